@@ -0,0 +1,300 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/kubectl"
+	"github.com/gemalto/gokube/pkg/virtualbox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const forwardRulePrefix = "gokube-"
+
+// forward is a localhost port forwarded to a minikube VM node port, given as a number or as a [namespace/]service[:port] target
+type forward struct {
+	HostPort int    `mapstructure:"host-port"`
+	Target   string `mapstructure:"target"`
+}
+
+// forwardCmd represents the forward command
+var forwardCmd = &cobra.Command{
+	Use:   "forward",
+	Short: "Manages localhost port-forwards. This command forwards localhost ports to minikube VM node ports",
+	Long:  "Manages localhost port-forwards. This command forwards localhost ports to minikube VM node ports through VirtualBox NAT, forwards are re-applied on start",
+}
+
+var forwardAddCmd = &cobra.Command{
+	Use:          "add <host-port> <node-port|[namespace/]service[:port]>",
+	Short:        "Forwards a localhost port to a node port, or to the node port of a service",
+	Long:         "Forwards a localhost port to a node port, or to the node port of a service (the port name or number is needed for services exposing several ports)",
+	RunE:         forwardAddRun,
+	SilenceUsage: true,
+}
+
+var forwardListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "Lists localhost port-forwards",
+	Long:         "Lists localhost port-forwards",
+	RunE:         forwardListRun,
+	SilenceUsage: true,
+}
+
+var forwardRemoveCmd = &cobra.Command{
+	Use:          "remove <host-port>",
+	Short:        "Removes a localhost port-forward",
+	Long:         "Removes a localhost port-forward",
+	RunE:         forwardRemoveRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	forwardCmd.AddCommand(forwardAddCmd)
+	forwardCmd.AddCommand(forwardListCmd)
+	forwardCmd.AddCommand(forwardRemoveCmd)
+	rootCmd.AddCommand(forwardCmd)
+}
+
+// configuredForwards returns the persisted port-forwards
+func configuredForwards() ([]forward, error) {
+	var forwards []forward
+	err := viper.UnmarshalKey("forwards", &forwards)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read port-forwards from gokube configuration: %w", err)
+	}
+	return forwards, nil
+}
+
+func saveForwards(forwards []forward) error {
+	values := make([]map[string]interface{}, 0, len(forwards))
+	for _, f := range forwards {
+		values = append(values, map[string]interface{}{"host-port": f.HostPort, "target": f.Target})
+	}
+	err := gokube.UpdateConfig(map[string]interface{}{"forwards": values})
+	if err != nil {
+		return fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	return nil
+}
+
+// resolveNodePort returns the node port of the given target, which is either a node port or a [namespace/]service[:port]
+func resolveNodePort(target string) (int, error) {
+	if nodePort, err := strconv.Atoi(target); err == nil {
+		return nodePort, nil
+	}
+	namespace := "default"
+	name := target
+	port := ""
+	if i := strings.Index(name, "/"); i >= 0 {
+		namespace, name = name[:i], name[i+1:]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name, port = name[:i], name[i+1:]
+	}
+	client, err := kubectl.NewClient("minikube")
+	if err != nil {
+		return 0, err
+	}
+	service, err := client.GetService(namespace, name)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get K8S service %s/%s: %w", namespace, name, err)
+	}
+	var nodePorts []int
+	for _, p := range service.Spec.Ports {
+		if p.NodePort == 0 {
+			continue
+		}
+		if len(port) == 0 || port == p.Name || port == strconv.Itoa(int(p.Port)) {
+			nodePorts = append(nodePorts, int(p.NodePort))
+		}
+	}
+	switch {
+	case len(nodePorts) == 0 && len(port) > 0:
+		return 0, fmt.Errorf("K8S service %s/%s does not expose port %s on a node port", namespace, name, port)
+	case len(nodePorts) == 0:
+		return 0, fmt.Errorf("K8S service %s/%s is not exposed on a node port", namespace, name)
+	case len(nodePorts) > 1:
+		return 0, fmt.Errorf("K8S service %s/%s exposes several node ports, please specify the service port", namespace, name)
+	}
+	return nodePorts[0], nil
+}
+
+// applyForward (re)creates the NAT port-forwarding rule of the given forward
+func applyForward(f forward, rules []*virtualbox.NATPortForward) error {
+	nodePort, err := resolveNodePort(f.Target)
+	if err != nil {
+		return err
+	}
+	rule := &virtualbox.NATPortForward{
+		Name:      forwardRulePrefix + strconv.Itoa(f.HostPort),
+		Protocol:  "tcp",
+		HostIP:    "127.0.0.1",
+		HostPort:  f.HostPort,
+		GuestPort: nodePort,
+	}
+	for _, r := range rules {
+		if r.Name == rule.Name {
+			if r.String() == rule.String() {
+				return nil
+			}
+			err = virtualbox.RemoveNATPortForward(r.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return virtualbox.AddNATPortForward(rule)
+}
+
+// applyForwards (re)creates the NAT port-forwarding rules of persisted forwards, as services node ports may have changed
+func applyForwards() error {
+	forwards, err := configuredForwards()
+	if err != nil || len(forwards) == 0 {
+		return err
+	}
+	rules, err := virtualbox.ListNATPortForwards()
+	if err != nil {
+		return err
+	}
+	for _, f := range forwards {
+		fmt.Printf("Forwarding localhost:%d to %s...\n", f.HostPort, f.Target)
+		err = applyForward(f, rules)
+		if err != nil {
+			fmt.Printf("Warning: cannot forward localhost:%d to %s: %s\n", f.HostPort, f.Target, err)
+		}
+	}
+	return nil
+}
+
+func forwardAddRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return cmd.Usage()
+	}
+	hostPort, err := strconv.Atoi(args[0])
+	if err != nil || hostPort <= 0 || hostPort > 65535 {
+		return fmt.Errorf("invalid host port %q", args[0])
+	}
+	target := args[1]
+	err = gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	forwards, err := configuredForwards()
+	if err != nil {
+		return err
+	}
+	for _, f := range forwards {
+		if f.HostPort == hostPort {
+			return fmt.Errorf("localhost:%d is already forwarded to %s", hostPort, f.Target)
+		}
+	}
+	f := forward{HostPort: hostPort, Target: target}
+	running, err := virtualbox.IsRunning()
+	if err != nil {
+		return fmt.Errorf("cannot check if minikube VM is running: %w", err)
+	}
+	if _, convErr := strconv.Atoi(target); running || convErr == nil {
+		rules, err := virtualbox.ListNATPortForwards()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Forwarding localhost:%d to %s...\n", hostPort, target)
+		err = applyForward(f, rules)
+		if err != nil {
+			return fmt.Errorf("cannot forward localhost:%d to %s: %w", hostPort, target, err)
+		}
+	} else {
+		fmt.Printf("minikube VM is not running, localhost:%d will be forwarded to %s on next start\n", hostPort, target)
+	}
+	return saveForwards(append(forwards, f))
+}
+
+func forwardListRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	forwards, err := configuredForwards()
+	if err != nil {
+		return err
+	}
+	rules, err := virtualbox.ListNATPortForwards()
+	if err != nil {
+		return err
+	}
+	if len(forwards) == 0 {
+		fmt.Println("No port-forward")
+		return nil
+	}
+	for _, f := range forwards {
+		status := "not applied"
+		for _, r := range rules {
+			if r.Name == forwardRulePrefix+strconv.Itoa(f.HostPort) {
+				status = fmt.Sprintf("node port %d", r.GuestPort)
+			}
+		}
+		fmt.Printf("localhost:%d -> %s (%s)\n", f.HostPort, f.Target, status)
+	}
+	return nil
+}
+
+func forwardRemoveRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Usage()
+	}
+	hostPort, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid host port %q", args[0])
+	}
+	err = gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	forwards, err := configuredForwards()
+	if err != nil {
+		return err
+	}
+	var kept []forward
+	for _, f := range forwards {
+		if f.HostPort != hostPort {
+			kept = append(kept, f)
+		}
+	}
+	if len(kept) == len(forwards) {
+		return fmt.Errorf("localhost:%d is not forwarded", hostPort)
+	}
+	rules, err := virtualbox.ListNATPortForwards()
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		if r.Name == forwardRulePrefix+strconv.Itoa(hostPort) {
+			fmt.Printf("Removing forward of localhost:%d...\n", hostPort)
+			err = virtualbox.RemoveNATPortForward(r.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return saveForwards(kept)
+}
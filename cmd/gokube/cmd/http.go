@@ -0,0 +1,47 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gemalto/gokube/pkg/httpclient"
+	"github.com/spf13/viper"
+)
+
+// loadHTTPConfig applies the HTTP configuration of gokube configuration to every gokube download and request:
+//
+//	http:
+//	  timeout: 30m
+//	  netrc: true
+//	  hosts:
+//	  - url: https://artifactory.example.com/
+//	    username: ${ARTIFACTORY_USER}
+//	    password: ${ARTIFACTORY_PASSWORD}
+func loadHTTPConfig() error {
+	config := &httpclient.Config{}
+	err := viper.UnmarshalKey("http", config)
+	if err != nil {
+		return fmt.Errorf("invalid http configuration: %w", err)
+	}
+	if len(config.UserAgent) == 0 {
+		config.UserAgent = "gokube/" + GOKUBE_VERSION
+	}
+	err = httpclient.SetConfig(config)
+	if err != nil {
+		return fmt.Errorf("invalid http configuration: %w", err)
+	}
+	return nil
+}
@@ -17,33 +17,36 @@ package cmd
 // TODO manage vbox time sync (VBoxManage guestproperty set default "/VirtualBox/GuestAdd/VBoxService/--timesync-set-threshold" 1000)
 
 import (
+	"errors"
 	"fmt"
-	"github.com/gemalto/gokube/internal/util"
-	"github.com/gemalto/gokube/pkg/docker"
-	"github.com/gemalto/gokube/pkg/utils"
-	"github.com/gemalto/gokube/pkg/virtualbox"
-	"github.com/spf13/viper"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
-	"net/http"
 
 	"github.com/coreos/go-semver/semver"
+	"github.com/gemalto/gokube/internal/util"
+	"github.com/gemalto/gokube/pkg/certs"
+	"github.com/gemalto/gokube/pkg/docker"
 	"github.com/gemalto/gokube/pkg/gokube"
 	"github.com/gemalto/gokube/pkg/helm"
+	"github.com/gemalto/gokube/pkg/httpclient"
 	"github.com/gemalto/gokube/pkg/kubectl"
 	"github.com/gemalto/gokube/pkg/minikube"
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/utils"
+	"github.com/gemalto/gokube/pkg/virtualbox"
 	"github.com/spf13/cobra"
-	"os/exec"
+	"github.com/spf13/viper"
 )
 
 var memory int16
 var cpus int16
 var swap int16
-var enableSwap bool
 var disk string
 var checkIP string
+var hostOnlyCIDR string
 var ipCheckNeeded bool
 var insecureRegistry string
 var httpProxy string
@@ -55,6 +58,8 @@ var dnsProxy bool
 var hostDNSResolver bool
 var keepVM bool
 var dnsDomain string
+var caCerts []string
+var locked bool
 
 // initCmd represents the init command
 var initCmd = &cobra.Command{
@@ -69,86 +74,67 @@ func init() {
 	defaultVMMemory, _ := strconv.Atoi(utils.GetValueFromEnv("MINIKUBE_MEMORY", strconv.Itoa(DEFAULT_MINIKUBE_MEMORY)))
 	defaultVMCPUs, _ := strconv.Atoi(utils.GetValueFromEnv("MINIKUBE_CPUS", strconv.Itoa(DEFAULT_MINIKUBE_CPUS)))
 	defaultVMSwap, _ := strconv.Atoi(utils.GetValueFromEnv("MINIKUBE_SWAP", strconv.Itoa(DEFAULT_MINIKUBE_SWAP)))
-	enableSwap = false
-	if defaultVMSwap != 0 {
-		enableSwap = true
-	}
 	defaultGokubeQuiet := false
 	if len(utils.GetValueFromEnv("GOKUBE_QUIET", "")) > 0 {
 		defaultGokubeQuiet = true
 	}
-	loadURLVersionsFromEnv()
 	initCmd.Flags().StringVarP(&kubernetesVersion, "kubernetes-version", "", utils.GetValueFromEnv("KUBERNETES_VERSION", DEFAULT_KUBERNETES_VERSION), "The kubernetes version")
 	initCmd.Flags().StringVarP(&containerRuntime, "container-runtime", "", utils.GetValueFromEnv("MINIKUBE_CONTAINER_RUNTIME", DEFAULT_MINIKUBE_CONTAINER_RUNTIME), "Minikube container runtime (docker, cri-o, containerd)")
 	initCmd.Flags().BoolVarP(&askForUpgrade, "upgrade", "u", false, "Upgrade gokube (download and setup docker, minikube, kubectl and helm)")
-	initCmd.Flags().BoolVarP(&askForClean, "clean", "c", false, "Clean gokube (remove docker, minikube, kubectl and helm working directories)")
+	initCmd.Flags().BoolVarP(&askForClean, "clean", "c", false, "Clean gokube (remove minikube and helm working directories, and minikube entries from kubectl and docker configuration)")
 	initCmd.Flags().Int16VarP(&memory, "memory", "", int16(defaultVMMemory), "Amount of RAM allocated to the minikube VM in MB")
 	initCmd.Flags().Int16VarP(&cpus, "cpus", "", int16(defaultVMCPUs), "Number of CPUs allocated to the minikube VM")
 	initCmd.Flags().Int16VarP(&swap, "swap", "", int16(defaultVMSwap), "Amount of SWAP allocated to the minikube VM in MB")
 	initCmd.Flags().StringVarP(&disk, "disk", "", utils.GetValueFromEnv("MINIKUBE_DISK", DEFAULT_MINIKUBE_DISK), "Disk size allocated to the minikube VM. Format: <number>[<unit>], where unit = b, k, m or g")
-	initCmd.Flags().StringVarP(&checkIP, "check-ip", "", utils.GetValueFromEnv("GOKUBE_CHECK_IP", DEFAULT_GOKUBE_CHECK_IP), "Checks if minikube VM allocated IP matches the provided one (0.0.0.0 means no check)")
+	initCmd.Flags().StringVarP(&hostOnlyCIDR, "host-only-cidr", "", utils.GetValueFromEnv("GOKUBE_CIDR", DEFAULT_GOKUBE_CIDR), "The CIDR to be used for the minikube VM host-only network (prefix length must be 24 or lower)")
+	initCmd.Flags().StringVarP(&checkIP, "check-ip", "", utils.GetValueFromEnv("GOKUBE_CHECK_IP", ""), "Checks if minikube VM allocated IP matches the provided one (derived from host-only CIDR if not provided, 0.0.0.0 means no check)")
 	initCmd.Flags().StringVarP(&insecureRegistry, "insecure-registry", "", os.Getenv("INSECURE_REGISTRY"), "Insecure Docker registries to pass to the Docker daemon. The default service CIDR range will automatically be added.")
-	initCmd.Flags().StringVarP(&httpProxy, "http-proxy", "", os.Getenv("HTTP_PROXY"), "HTTP proxy variable for docker engine in minikube VM")
-	initCmd.Flags().StringVarP(&httpsProxy, "https-proxy", "", os.Getenv("HTTPS_PROXY"), "HTTPS proxy variable for docker engine in minikube VM")
-	initCmd.Flags().StringVarP(&noProxy, "no-proxy", "", os.Getenv("NO_PROXY"), "No proxy variable for docker engine in minikube VM")
+	initCmd.Flags().StringVarP(&httpProxy, "http-proxy", "", "", "HTTP proxy for gokube, its dependencies and minikube VM container runtime (detected from environment, PAC file or Windows internet settings if not provided)")
+	initCmd.Flags().StringVarP(&httpsProxy, "https-proxy", "", "", "HTTPS proxy for gokube, its dependencies and minikube VM container runtime (detected from environment, PAC file or Windows internet settings if not provided)")
+	initCmd.Flags().StringVarP(&noProxy, "no-proxy", "", "", "Hosts which must not be reached through proxy (minikube VM IP, host-only network, service & pod CIDRs and cluster domain are added automatically)")
+	initCmd.Flags().StringVarP(&proxyPAC, "proxy-pac", "", os.Getenv("GOKUBE_PROXY_PAC"), "URL of a PAC file from which the proxy is detected")
+	initCmd.Flags().StringArrayVarP(&caCerts, "ca-cert", "", nil, "PEM file of a CA certificate to trust in minikube VM, downloads and helm repositories, can be repeated (current ones kept if not provided, --ca-cert= removes them)")
 	initCmd.Flags().StringVarP(&dnsDomain, "dns-domain", "", utils.GetValueFromEnv("MINIKUBE_DNS_DOMAIN", DEFAULT_MINIKUBE_DNS_DOMAIN), "Minikube cluster DNS domain name")
 	initCmd.Flags().BoolVarP(&dnsProxy, "dns-proxy", "", false, "Use Virtualbox NAT DNS proxy (could be unstable)")
 	initCmd.Flags().BoolVarP(&hostDNSResolver, "host-dns-resolver", "", false, "Use Virtualbox NAT DNS host resolver (could be unstable)")
 	initCmd.Flags().BoolVarP(&quiet, "quiet", "q", defaultGokubeQuiet, "Don't display warning message before initializing")
+	_ = initCmd.Flags().MarkDeprecated("quiet", "there is no more warning message before initializing")
 	initCmd.Flags().BoolVar(&keepVM, "keep-vm", false, "Keep minikube VM as it is (don't delete/recreate)")
 	initCmd.Flags().BoolVar(&force, "force", false, "Force minikube to perform possibly dangerous operations")
+	initCmd.Flags().BoolVar(&locked, "locked", false, "Refuse to install dependencies which don't match the lock file (written by lock command), upgrading the ones which differ")
+	initCmd.Flags().StringVarP(&lockFile, "lock-file", "", utils.GetValueFromEnv("GOKUBE_LOCK_FILE", gokube.DEFAULT_LOCK_FILE), "The lock file used with --locked")
 	rootCmd.AddCommand(initCmd)
 }
 
-func resetVBLease(hostOnlyCIDR string) error {
-	// VB6 persists DHCP leases which prevent minikube to get the expected 192.168.99.100 IP address
-	// Wait 5 seconds to make sure DHCP leases files are unlocked following VM deletion
-	// TODO add manifest to ask for admin rights (when we will need to remove host-only network)
-	fmt.Print("Resetting host-only network used by minikube...")
-	var err error
-	for n := 1; n < 3; n++ {
-		time.Sleep(5 * time.Second)
-		err = virtualbox.ResetHostOnlyNetworkLeases(hostOnlyCIDR, verbose)
-		if err == nil {
-			break
-		} else {
-			fmt.Print(".")
-		}
-	}
-	if err != nil {
-		return fmt.Errorf("cannot reset host-only network: %w", err)
-	} else {
-		fmt.Println()
-		return nil
-	}
-}
-
-func setupMiniappsHelmRepository() error {
-	err := helm.RepoAdd("miniapps", miniappsRepo)
+func setupMiniappsHelmRepository(helmClient *helm.Client) error {
+	err := helmClient.RepoAdd("miniapps", miniappsRepo)
 	if err != nil {
 		return fmt.Errorf("cannot add miniapps repo: %w", err)
 	}
-	err = helm.RepoUpdate()
+	err = helmClient.RepoUpdate()
 	if err != nil {
 		return fmt.Errorf("cannot update helm repositories: %w", err)
 	}
 	return nil
 }
 
-func installChartMuseum(localRepoIp string) error {
-	err := helm.RepoAdd("chartmuseum", "https://chartmuseum.github.io/charts")
+func installChartMuseum(helmClient *helm.Client, localRepoIp string) error {
+	err := helmClient.RepoAdd("chartmuseum", "https://chartmuseum.github.io/charts")
 	if err != nil {
 		return fmt.Errorf("cannot add chartmuseum repo: %w", err)
 	}
-	err = helm.RepoUpdate()
+	err = helmClient.RepoUpdate()
 	if err != nil {
 		return fmt.Errorf("cannot update helm repositories: %w", err)
 	}
 
-	// Install or Upgrade the ChartMuseum
-	err = helm.Upgrade("chartmuseum/chartmuseum", "", "chartmuseum", "kube-system",
-		"env.open.DISABLE_API=false,env.open.ALLOW_OVERWRITE=true,service.type=NodePort,service.nodePort=32767", "")
+	// Install or Upgrade the ChartMuseum and wait for its deployment to be ready
+	rel, err := helmClient.Upgrade("chartmuseum/chartmuseum", "", "chartmuseum", "kube-system",
+		"env.open.DISABLE_API=false,env.open.ALLOW_OVERWRITE=true,service.type=NodePort,service.nodePort=32767", "", 5*time.Minute)
 	if err != nil {
+		if rel != nil && rel.Info != nil {
+			return fmt.Errorf("cannot install chartmuseum (release status is %s): %w", rel.Info.Status, err)
+		}
 		return fmt.Errorf("cannot install chartmuseum: %w", err)
 	}
 
@@ -157,7 +143,7 @@ func installChartMuseum(localRepoIp string) error {
 	retries := 50
 	waitBeforeRetry := 5
 
-	for n := 1; n <= retries; n++ {
+	for n := 1; n <= retries && !runner.IsDryRun(); n++ {
 		// Check if ChartMuseum service is ready
 		ready, err := isChartMuseumReady(localRepoIp, 32767)
 		if err == nil && ready {
@@ -173,11 +159,11 @@ func installChartMuseum(localRepoIp string) error {
 		}
 	}
 
-	err = helm.RepoAdd("minikube", "http://"+localRepoIp+":32767")
+	err = helmClient.RepoAdd("minikube", "http://"+localRepoIp+":32767")
 	if err != nil {
 		fmt.Printf("Warning: cannot add minikube repo: %s\n", err)
 	}
-	err = helm.RepoUpdate()
+	err = helmClient.RepoUpdate()
 	if err != nil {
 		return fmt.Errorf("cannot update helm repositories: %w", err)
 	}
@@ -187,7 +173,7 @@ func installChartMuseum(localRepoIp string) error {
 // Function to check if ChartMuseum is ready
 func isChartMuseumReady(ip string, port int) (bool, error) {
 	url := fmt.Sprintf("http://%s:%d/index.yaml", ip, port)
-	resp, err := http.Get(url)
+	resp, err := httpclient.New().Get(url)
 	if err != nil {
 		return false, err
 	}
@@ -197,26 +183,34 @@ func isChartMuseumReady(ip string, port int) (bool, error) {
 }
 
 func exposeDashboard(port int) error {
+	if runner.DryRun("patch service kubernetes-dashboard/kubernetes-dashboard to expose it on nodePort %d", port) {
+		return nil
+	}
+	client, err := kubectl.NewClient("minikube")
+	if err != nil {
+		return err
+	}
 	for n := 1; n <= 12; n++ {
-		dashboardService, err := kubectl.Get("kubernetes-dashboard", "svc", "kubernetes-dashboard", "")
-		if err != nil {
-			return fmt.Errorf("cannot get K8S kubernetes-dashboard service: %w", err)
-		}
-		if len(dashboardService) > 0 {
+		_, err := client.GetService("kubernetes-dashboard", "kubernetes-dashboard")
+		if err == nil {
 			fmt.Println()
 			patchPayload := fmt.Sprintf("{\"spec\":{\"type\":\"NodePort\",\"ports\":[{\"port\":80,\"protocol\":\"TCP\",\"targetPort\":9090,\"nodePort\":%d}]}}", port)
-			err = kubectl.Patch("kubernetes-dashboard", "svc", "kubernetes-dashboard", patchPayload)
+			err = client.PatchService("kubernetes-dashboard", "kubernetes-dashboard", patchPayload)
 			if err != nil {
 				return fmt.Errorf("cannot patch K8S kubernetes-dashboard service: %w", err)
 			}
+			fmt.Println("service/kubernetes-dashboard patched")
 			break
+		}
+		// Dashboard service may not be created yet, or API server may still be starting
+		if !errors.Is(err, kubectl.ErrNotFound) && !errors.Is(err, kubectl.ErrConnectionRefused) {
+			return fmt.Errorf("cannot get K8S kubernetes-dashboard service: %w", err)
+		}
+		fmt.Print(".")
+		if n == 12 {
+			fmt.Printf("\nWarning: kubernetes-dashboard is not present after 60s, which probably means its installation failed\n")
 		} else {
-			fmt.Print(".")
-			if n == 12 {
-				fmt.Printf("\nWarning: kubernetes-dashboard is not present after 60s, which probably means its installation failed\n")
-			} else {
-				time.Sleep(5 * time.Second)
-			}
+			time.Sleep(5 * time.Second)
 		}
 	}
 	return nil
@@ -227,29 +221,83 @@ func initRun(cmd *cobra.Command, args []string) error {
 		return cmd.Usage()
 	}
 
-	checkLatestVersion()
-
 	err := gokube.ReadConfig(verbose)
 	if err != nil {
 		return fmt.Errorf("cannot read gokube configuration file: %w", err)
 	}
+
+	// VM resources changed by resize are kept, unless given
+	if !cmd.Flags().Changed("memory") && viper.IsSet("memory") {
+		memory = int16(viper.GetInt("memory"))
+	}
+	if !cmd.Flags().Changed("cpus") && viper.IsSet("cpus") {
+		cpus = int16(viper.GetInt("cpus"))
+	}
+	if !cmd.Flags().Changed("disk") && viper.IsSet("disk") {
+		disk = viper.GetString("disk")
+	}
+
+	// Proxy must be applied before reaching the network
+	proxyConfig, err := resolveProxy(cmd)
+	if err != nil {
+		return err
+	}
+	proxyConfig, err = applyProxy(proxyConfig, hostOnlyCIDR, dnsDomain)
+	if err != nil {
+		return err
+	}
+
+	checkLatestVersion()
 	gokubeVersion = viper.GetString("gokube-version")
 	if len(gokubeVersion) == 0 {
 		gokubeVersion = "0.0.0"
 	}
 
-	// Force clean & upgrade if persisted gokube-version is lower than the current one
-	if semver.New(gokubeVersion).Compare(*semver.New(GOKUBE_VERSION)) < 0 {
+	// Force clean & upgrade if persisted gokube-version is lower than the current one (or invalid)
+	persistedVersion, err := semver.NewVersion(gokubeVersion)
+	if err != nil || persistedVersion.LessThan(*semver.New(GOKUBE_VERSION)) {
 		fmt.Println("Warning: this version of gokube is launched for the first time, forcing clean & upgrade...")
 		gokubeVersion = GOKUBE_VERSION
 		askForClean = true
 		askForUpgrade = true
 	}
 
-	if !askForUpgrade {
-		checkMinimumRequirements()
+	// CA certificates are kept in the gokube CA bundle so that next commands (e.g. start --upgrade) trust them too,
+	// the bundle being only replaced when --ca-cert is provided
+	if cmd.Flags().Changed("ca-cert") {
+		var files []string
+		for _, file := range caCerts {
+			if len(file) > 0 {
+				files = append(files, file)
+			}
+		}
+		err = certs.Setup(files)
+		if err != nil {
+			return fmt.Errorf("cannot setup CA certificates: %w", err)
+		}
+	}
+
+	var isoURL string
+	if locked {
+		isoURL, err = applyLock(kubernetesVersion)
+		if err != nil {
+			return err
+		}
+		askForUpgrade = true
+	}
+
+	err = checkCompatibility(kubernetesVersion)
+	if err != nil {
+		return err
 	}
 
+	expectedIP, err := virtualbox.ExpectedVMIP(hostOnlyCIDR)
+	if err != nil {
+		return fmt.Errorf("invalid host-only CIDR %s: %w", hostOnlyCIDR, err)
+	}
+	if len(checkIP) == 0 {
+		checkIP = expectedIP
+	}
 	ipCheckNeeded = strings.Compare("0.0.0.0", checkIP) != 0
 
 	if askForClean && keepVM {
@@ -257,32 +305,45 @@ func initRun(cmd *cobra.Command, args []string) error {
 		os.Exit(1)
 	}
 
-	// Warn user with pre-requisites
-	if ipCheckNeeded && !quiet && !keepVM {
-		gokube.ConfirmInitCommandExecution()
-	}
-
 	startTime := time.Now()
 
 	if !keepVM {
+		fmt.Printf("Checking host-only network %s does not overlap with host networks...\n", hostOnlyCIDR)
+		err = virtualbox.ValidateHostOnlyCIDR(hostOnlyCIDR)
+		if err != nil {
+			return fmt.Errorf("invalid host-only CIDR %s: %w", hostOnlyCIDR, err)
+		}
 		fmt.Println("Deleting previous minikube VM...")
 		err := minikube.Delete()
 		if err != nil {
 			fmt.Printf("Warning: cannot delete previous minikube VM: %s\n", err)
 		}
-		if ipCheckNeeded {
-			err = resetVBLease(DEFAULT_GOKUBE_CIDR)
-			if err != nil {
-				return fmt.Errorf("cannot delete previous minikube VM: %w", err)
+		reservedMAC := viper.GetString("dhcp-reserved-mac")
+		if len(reservedMAC) > 0 {
+			err = virtualbox.ReleaseVMIP(viper.GetString("host-only-cidr"), reservedMAC)
+			if err != nil && verbose {
+				fmt.Printf("Warning: cannot release IP reserved for previous minikube VM: %s\n", err)
 			}
 		}
 	}
 
 	if askForClean {
+		fmt.Println("Backing up kubectl and docker configuration...")
+		backup, err := gokube.BackupConfigDirectories()
+		if err != nil {
+			return fmt.Errorf("cannot backup kubectl and docker configuration: %w", err)
+		}
+		fmt.Printf("Configuration saved to backup '%s' (use 'gokube restore-config' to restore it)\n", backup)
 		fmt.Println("Deleting gokube dependencies working directory...")
 		_ = minikube.DeleteWorkingDirectory()
-		_ = kubectl.DeleteWorkingDirectory()
-		_ = docker.DeleteWorkingDirectory()
+		err = kubectl.DeleteMinikubeConfig()
+		if err != nil {
+			fmt.Printf("Warning: cannot remove minikube entries from kubeconfig: %s\n", err)
+		}
+		err = docker.DeleteMinikubeContexts()
+		if err != nil {
+			fmt.Printf("Warning: cannot remove minikube docker contexts: %s\n", err)
+		}
 		_ = docker.InitWorkingDirectory()
 		_ = helm.DeleteWorkingDirectory()
 	} else if !keepVM {
@@ -301,22 +362,34 @@ func initRun(cmd *cobra.Command, args []string) error {
 		// Disable notification for updates
 		_ = minikube.ConfigSet("WantUpdateNotification", "false")
 
+		// Let minikube install CA certificates into the VM
+		err = certs.InstallInMinikube()
+		if err != nil {
+			return fmt.Errorf("cannot copy CA certificates into minikube certificates directory: %w", err)
+		}
+
 		// Create virtual machine (minikube)
 		fmt.Printf("Creating minikube VM with kubernetes %s...\n", kubernetesVersion)
-		err := minikube.Start(memory, cpus, disk, httpProxy, httpsProxy, noProxy, insecureRegistry, kubernetesVersion, true, dnsProxy, hostDNSResolver, dnsDomain, containerRuntime, force, verbose)
+		err := minikube.Start(memory, cpus, disk, hostOnlyCIDR, proxyConfig.HTTPProxy, proxyConfig.HTTPSProxy, proxyConfig.NoProxy, insecureRegistry, kubernetesVersion, isoURL, true, dnsProxy, hostDNSResolver, dnsDomain, containerRuntime, force, verbose)
 		if err != nil {
 			return fmt.Errorf("cannot start minikube VM: %w", err)
 		}
 
-        // Create & attach swap drive to minikube
-        if enableSwap {
-            fmt.Println("Creating & attaching swap drive to minikube VM...")
-            vboxManager := virtualbox.NewVBoxManager()
-            err = vboxManager.AddSwapDisk(swap)
-            if err != nil {
-                fmt.Printf("Warning: cannot create & attach swap drive to minikube VM: %s\n", err)
-            }
-        }
+		// Docker daemon proxy is configured by minikube, other container runtimes need it too
+		err = minikube.ConfigureRuntimeProxy(containerRuntime, proxyConfig.Env())
+		if err != nil {
+			fmt.Printf("Warning: cannot configure proxy of %s container runtime: %s\n", containerRuntime, err)
+		}
+
+		// Create & attach swap drive to minikube
+		if swap > 0 {
+			fmt.Println("Creating & attaching swap drive to minikube VM...")
+			vboxManager := virtualbox.NewVBoxManager()
+			err = vboxManager.AddSwapDisk(swap)
+			if err != nil {
+				fmt.Printf("Warning: cannot create & attach swap drive to minikube VM: %s\n", err)
+			}
+		}
 
 		// Enable dashboard
 		err = minikube.AddonsEnable("dashboard")
@@ -328,11 +401,34 @@ func initRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("cannot get minikube VM IP address: %w", err)
 		}
-		// Checks minikube IP
+		// Reserves expected IP for minikube VM in host-only network DHCP server
 		if ipCheckNeeded {
-			if strings.Compare(checkIP, minikubeIP) != 0 {
-				fmt.Printf("\nError: minikube IP (%s) does not match expected IP (%s), VM post-installation process aborted\n", minikubeIP, checkIP)
-				os.Exit(1)
+			fmt.Printf("Reserving IP %s for minikube VM...\n", checkIP)
+			reservedMAC, err := virtualbox.ReserveVMIP(hostOnlyCIDR, checkIP)
+			if err != nil {
+				return fmt.Errorf("cannot reserve IP %s for minikube VM: %w", checkIP, err)
+			}
+			err = gokube.UpdateConfig(map[string]interface{}{"dhcp-reserved-mac": reservedMAC})
+			if err != nil {
+				return fmt.Errorf("cannot write gokube configuration: %w", err)
+			}
+			if strings.Compare(checkIP, minikubeIP) != 0 && !dryRun {
+				fmt.Printf("minikube IP (%s) does not match expected IP (%s), restarting minikube VM...\n", minikubeIP, checkIP)
+				err = minikube.Stop()
+				if err != nil {
+					return fmt.Errorf("cannot stop minikube VM: %w", err)
+				}
+				err = minikube.Restart(kubernetesVersion, containerRuntime, force, verbose)
+				if err != nil {
+					return fmt.Errorf("cannot restart minikube VM: %w", err)
+				}
+				minikubeIP, err = minikube.Ip()
+				if err != nil {
+					return fmt.Errorf("cannot get minikube VM IP address: %w", err)
+				}
+				if strings.Compare(checkIP, minikubeIP) != 0 {
+					return fmt.Errorf("minikube IP (%s) still does not match expected IP (%s) after restart", minikubeIP, checkIP)
+				}
 			}
 		}
 
@@ -342,14 +438,16 @@ func initRun(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("cannot switch K8S context to minikube: %w", err)
 		}
 
+		helmClient := helm.NewClient("minikube")
+
 		fmt.Println("Installing ChartMuseum...")
-		err = installChartMuseum(minikubeIP)
+		err = installChartMuseum(helmClient, minikubeIP)
 		if err != nil {
 			return err
 		}
 
 		fmt.Println("Configuring miniapps repository...")
-		err = setupMiniappsHelmRepository()
+		err = setupMiniappsHelmRepository(helmClient)
 		if err != nil {
 			return err
 		}
@@ -376,37 +474,30 @@ func initRun(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("cannot write gokube configuration: %w", err)
 	}
-
-    // Format & enable swap drive in minikube VM
-    if enableSwap {
-        fmt.Println("Formatting & enabling swap drive in minikube VM...")
-	    err = addSwapToMinikube()
-	    if err != nil {
-		    fmt.Printf("Warning: cannot format/enable swap drive in minikube VM: %s\n", err)
-	    }
-    }
-
-	fmt.Printf("\ngokube init completed in %s\n", util.Duration(time.Since(startTime)))
-	return nil
-}
-
-func addSwapToMinikube() error {
-
-	// Add swap file commands
-	swapCmds := []string{
-		"sudo mkswap /dev/sdb",
-		"sudo swapon /dev/sdb",
-		"echo '/dev/sdb none swap defaults 0 0' | sudo tee -a /etc/fstab",
+	if !keepVM {
+		// Keep VM settings for next commands, resize updating memory, CPUs and disk size
+		err = gokube.UpdateConfig(map[string]interface{}{
+			"memory":         memory,
+			"cpus":           cpus,
+			"disk":           disk,
+			"swap":           swap,
+			"host-only-cidr": hostOnlyCIDR,
+			"dns-domain":     dnsDomain,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot write gokube configuration: %w", err)
+		}
 	}
 
-	// Execute each command
-	for _, cmd := range swapCmds {
-		sshCmd := exec.Command("minikube", "ssh", cmd)
-		err := sshCmd.Run()
+	// Format & enable swap drive in minikube VM
+	if swap > 0 && !keepVM {
+		fmt.Println("Formatting & enabling swap drive in minikube VM...")
+		err = minikube.EnableSwap()
 		if err != nil {
-			return fmt.Errorf("error running command '%s': %w", cmd, err)
+			fmt.Printf("Warning: cannot format/enable swap drive in minikube VM: %s\n", err)
 		}
 	}
 
+	fmt.Printf("\ngokube init completed in %s\n", util.Duration(time.Since(startTime)))
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,101 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/minikube"
+	"github.com/gemalto/gokube/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var lockFile string
+var lockKubernetesVersion string
+
+// lockCmd represents the lock command
+var lockCmd = &cobra.Command{
+	Use:          "lock",
+	Short:        "Locks gokube dependencies. This command writes the versions, URLs and checksums of gokube dependencies to a lock file used by init --locked",
+	Long:         "Locks gokube dependencies. This command writes the versions, URLs and checksums of gokube dependencies to a lock file used by init --locked",
+	RunE:         lockRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	lockCmd.Flags().StringVarP(&lockFile, "lock-file", "", utils.GetValueFromEnv("GOKUBE_LOCK_FILE", gokube.DEFAULT_LOCK_FILE), "The lock file")
+	lockCmd.Flags().StringVarP(&lockKubernetesVersion, "kubernetes-version", "", "", "The kubernetes version to lock (configured one if not provided)")
+	rootCmd.AddCommand(lockCmd)
+}
+
+// applyLock checks gokube configuration against the lock file and restricts downloads to the locked artifacts.
+// It returns the locked minikube ISO URL, if any.
+func applyLock(kubernetesVersion string) (string, error) {
+	lock, err := gokube.ReadLock(lockFile)
+	if err != nil {
+		return "", fmt.Errorf("cannot read lock file: %w", err)
+	}
+	err = lock.Verify(kubernetesVersion, dependencies(), helmPlugins())
+	if err != nil {
+		return "", err
+	}
+	err = lock.VerifyMinikubeISO()
+	if err != nil {
+		return "", err
+	}
+	download.SetLockedChecksums(lock.Checksums())
+	if lock.MinikubeISO == nil {
+		return "", nil
+	}
+	return lock.MinikubeISO.URL, nil
+}
+
+func lockRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	if len(lockKubernetesVersion) == 0 {
+		lockKubernetesVersion = configuredKubernetesVersion()
+	}
+	err = checkCompatibility(lockKubernetesVersion)
+	if err != nil {
+		return err
+	}
+
+	isoURL, err := minikube.ISOURL()
+	if err != nil {
+		return err
+	}
+	if len(isoURL) == 0 {
+		fmt.Println("Warning: minikube VM not found, minikube ISO will not be locked")
+	}
+	lock, err := gokube.NewLock(lockKubernetesVersion, isoURL, dependencies(), helmPlugins())
+	if err != nil {
+		return err
+	}
+	err = lock.Write(lockFile)
+	if err != nil {
+		return fmt.Errorf("cannot write lock file: %w", err)
+	}
+	fmt.Printf("Lock file %s written\n", lockFile)
+	return nil
+}
@@ -0,0 +1,299 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/minikube"
+	"github.com/gemalto/gokube/pkg/virtualbox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	mountName string
+
+	reInvalidMountNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+	reMountName             = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// mount is a host directory shared with minikube VM and mounted on a guest path
+type mount struct {
+	Name      string `mapstructure:"name"`
+	HostPath  string `mapstructure:"host-path"`
+	GuestPath string `mapstructure:"guest-path"`
+}
+
+// mountCmd represents the mount command
+var mountCmd = &cobra.Command{
+	Use:   "mount",
+	Short: "Manages host directory mounts. This command shares host directories with the minikube VM",
+	Long:  "Manages host directory mounts. This command shares host directories with the minikube VM through VirtualBox shared folders, mounts are restored on start",
+}
+
+var mountAddCmd = &cobra.Command{
+	Use:          "add <host-path> <guest-path>",
+	Short:        "Shares a host directory with the minikube VM and mounts it on the guest path",
+	Long:         "Shares a host directory with the minikube VM and mounts it on the guest path (which can then be used by hostPath volumes)",
+	RunE:         mountAddRun,
+	SilenceUsage: true,
+}
+
+var mountListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "Lists host directory mounts",
+	Long:         "Lists host directory mounts",
+	RunE:         mountListRun,
+	SilenceUsage: true,
+}
+
+var mountRemoveCmd = &cobra.Command{
+	Use:          "remove <name|guest-path>",
+	Short:        "Unmounts and stops sharing a host directory with the minikube VM",
+	Long:         "Unmounts and stops sharing a host directory with the minikube VM",
+	RunE:         mountRemoveRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	mountAddCmd.Flags().StringVarP(&mountName, "name", "", "", "Name of the VirtualBox shared folder (derived from guest path if not provided)")
+	mountCmd.AddCommand(mountAddCmd)
+	mountCmd.AddCommand(mountListCmd)
+	mountCmd.AddCommand(mountRemoveCmd)
+	rootCmd.AddCommand(mountCmd)
+}
+
+// configuredMounts returns the persisted host directory mounts
+func configuredMounts() ([]mount, error) {
+	var mounts []mount
+	err := viper.UnmarshalKey("mounts", &mounts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read mounts from gokube configuration: %w", err)
+	}
+	return mounts, nil
+}
+
+func saveMounts(mounts []mount) error {
+	values := make([]map[string]interface{}, 0, len(mounts))
+	for _, m := range mounts {
+		values = append(values, map[string]interface{}{"name": m.Name, "host-path": m.HostPath, "guest-path": m.GuestPath})
+	}
+	err := gokube.UpdateConfig(map[string]interface{}{"mounts": values})
+	if err != nil {
+		return fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	return nil
+}
+
+// shareMounts adds missing shared folders of persisted mounts to minikube VM
+func shareMounts() error {
+	mounts, err := configuredMounts()
+	if err != nil || len(mounts) == 0 {
+		return err
+	}
+	folders, err := virtualbox.ListSharedFolders()
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		if _, present := folders[m.Name]; present {
+			continue
+		}
+		err = virtualbox.AddSharedFolder(m.Name, m.HostPath, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountMounts mounts persisted mounts inside running minikube VM
+func mountMounts() error {
+	mounts, err := configuredMounts()
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		fmt.Printf("Mounting %s on %s...\n", m.HostPath, m.GuestPath)
+		err = minikube.MountSharedFolder(m.Name, m.GuestPath)
+		if err != nil {
+			fmt.Printf("Warning: cannot mount %s on %s: %s\n", m.HostPath, m.GuestPath, err)
+		}
+	}
+	return nil
+}
+
+func mountAddRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return cmd.Usage()
+	}
+	hostPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid host path %q: %w", args[0], err)
+	}
+	if info, err := os.Stat(hostPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("host path %s is not an existing directory", hostPath)
+	}
+	guestPath := path.Clean(args[1])
+	if !path.IsAbs(guestPath) || guestPath == "/" {
+		return fmt.Errorf("invalid guest path %q, an absolute path is expected", args[1])
+	}
+	name := mountName
+	if len(name) == 0 {
+		name = "gokube" + reInvalidMountNameChars.ReplaceAllString(strings.ReplaceAll(guestPath, "/", "_"), "")
+	} else if !reMountName.MatchString(name) {
+		return fmt.Errorf("invalid mount name %q, only letters, digits, '_', '.' and '-' are allowed", name)
+	}
+	err = gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	mounts, err := configuredMounts()
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		if m.Name == name || m.GuestPath == guestPath {
+			return fmt.Errorf("%s is already mounted on %s", m.HostPath, m.GuestPath)
+		}
+	}
+	m := mount{Name: name, HostPath: hostPath, GuestPath: guestPath}
+	running, err := virtualbox.IsRunning()
+	if err != nil {
+		return fmt.Errorf("cannot check if minikube VM is running: %w", err)
+	}
+	fmt.Printf("Sharing %s with minikube VM...\n", hostPath)
+	// A running VM only accepts transient shared folders, the permanent one being added on next start
+	err = virtualbox.AddSharedFolder(m.Name, m.HostPath, running)
+	if err != nil {
+		return err
+	}
+	if running {
+		fmt.Printf("Mounting %s on %s...\n", hostPath, guestPath)
+		err = minikube.MountSharedFolder(m.Name, m.GuestPath)
+		if err != nil {
+			if removeErr := virtualbox.RemoveSharedFolder(m.Name, true); removeErr != nil {
+				fmt.Printf("Warning: %s\n", removeErr)
+			}
+			return err
+		}
+	} else {
+		fmt.Printf("minikube VM is not running, %s will be mounted on %s on next start\n", hostPath, guestPath)
+	}
+	return saveMounts(append(mounts, m))
+}
+
+func mountListRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	mounts, err := configuredMounts()
+	if err != nil {
+		return err
+	}
+	if len(mounts) == 0 {
+		fmt.Println("No mount")
+		return nil
+	}
+	folders, err := virtualbox.ListSharedFolders()
+	if err != nil {
+		return err
+	}
+	transientFolders, err := virtualbox.ListTransientSharedFolders()
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		status := "shared"
+		if _, present := transientFolders[m.Name]; present {
+			status = "shared until next stop"
+		} else if _, present := folders[m.Name]; !present {
+			status = "not shared"
+		}
+		fmt.Printf("%s: %s -> %s (%s)\n", m.Name, m.HostPath, m.GuestPath, status)
+	}
+	return nil
+}
+
+func mountRemoveRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	mounts, err := configuredMounts()
+	if err != nil {
+		return err
+	}
+	var removed *mount
+	var kept []mount
+	for i, m := range mounts {
+		if m.Name == args[0] || m.GuestPath == path.Clean(args[0]) {
+			removed = &mounts[i]
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	if removed == nil {
+		return fmt.Errorf("no mount named or mounted on %s", args[0])
+	}
+	running, err := virtualbox.IsRunning()
+	if err != nil {
+		return fmt.Errorf("cannot check if minikube VM is running: %w", err)
+	}
+	if running {
+		fmt.Printf("Unmounting %s...\n", removed.GuestPath)
+		err = minikube.UnmountSharedFolder(removed.GuestPath)
+		if err != nil {
+			return err
+		}
+	}
+	transientFolders, err := virtualbox.ListTransientSharedFolders()
+	if err != nil {
+		return err
+	}
+	if _, present := transientFolders[removed.Name]; present {
+		fmt.Printf("Stop sharing %s with minikube VM...\n", removed.HostPath)
+		err = virtualbox.RemoveSharedFolder(removed.Name, true)
+		if err != nil {
+			return err
+		}
+	}
+	folders, err := virtualbox.ListSharedFolders()
+	if err != nil {
+		return err
+	}
+	if _, present := folders[removed.Name]; present {
+		fmt.Printf("Stop sharing %s with minikube VM...\n", removed.HostPath)
+		err = virtualbox.RemoveSharedFolder(removed.Name, false)
+		if err != nil {
+			return err
+		}
+	}
+	return saveMounts(kept)
+}
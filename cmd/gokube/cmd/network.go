@@ -0,0 +1,210 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/minikube"
+	"github.com/gemalto/gokube/pkg/virtualbox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var networkCIDR string
+
+// networkCmd represents the network command
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Manages VirtualBox host-only networks. This command lists, prunes or recreates the host-only networks used by the minikube VM",
+	Long:  "Manages VirtualBox host-only networks. This command lists, prunes or recreates the host-only networks used by the minikube VM",
+}
+
+var networkListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "Lists VirtualBox host-only networks, their DHCP servers and the VMs using them",
+	Long:         "Lists VirtualBox host-only networks, their DHCP servers and the VMs using them",
+	RunE:         networkListRun,
+	SilenceUsage: true,
+}
+
+var networkPruneCmd = &cobra.Command{
+	Use:          "prune",
+	Short:        "Removes VirtualBox host-only networks and DHCP servers which are not used by any VM",
+	Long:         "Removes VirtualBox host-only networks and DHCP servers which are not used by any VM (the one matching the configured host-only CIDR is kept)",
+	RunE:         networkPruneRun,
+	SilenceUsage: true,
+}
+
+var networkRecreateCmd = &cobra.Command{
+	Use:          "recreate",
+	Short:        "Recreates the host-only network of the minikube VM with the configured CIDR",
+	Long:         "Recreates the host-only network of the minikube VM with the configured CIDR. The minikube VM is stopped during the operation and restarted afterwards if it was running",
+	RunE:         networkRecreateRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	networkRecreateCmd.Flags().StringVarP(&networkCIDR, "host-only-cidr", "", "", "The CIDR to be used for the minikube VM host-only network (configured one if not provided)")
+	networkCmd.AddCommand(networkListCmd)
+	networkCmd.AddCommand(networkPruneCmd)
+	networkCmd.AddCommand(networkRecreateCmd)
+	rootCmd.AddCommand(networkCmd)
+}
+
+// configuredHostOnlyCIDR returns the persisted host-only CIDR, falling back on default one for configurations written by older gokube versions
+func configuredHostOnlyCIDR() string {
+	if viper.IsSet("host-only-cidr") {
+		return viper.GetString("host-only-cidr")
+	}
+	return DEFAULT_GOKUBE_CIDR
+}
+
+func networkListRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	hostOnlyCIDR := configuredHostOnlyCIDR()
+	networks, dhcpServers, err := virtualbox.ListHostOnlyNetworks()
+	if err != nil {
+		return fmt.Errorf("cannot list host-only networks: %w", err)
+	}
+	fmt.Printf("Configured host-only CIDR: %s\n", hostOnlyCIDR)
+	for _, n := range networks {
+		var status []string
+		if n.IsOwned(hostOnlyCIDR) {
+			status = append(status, "owned by gokube")
+		}
+		if len(n.VMs) == 0 {
+			status = append(status, "unused")
+		}
+		fmt.Printf("%s (%s)", n.Name, n.IPv4.String())
+		if len(status) > 0 {
+			fmt.Printf(" [%s]", strings.Join(status, ", "))
+		}
+		fmt.Println()
+		if n.DHCPServer != nil {
+			fmt.Printf("  DHCP server: %s (%s - %s, enabled: %t)\n", n.DHCPServer.IP, n.DHCPServer.LowerIP, n.DHCPServer.UpperIP, n.DHCPServer.Enabled)
+		} else {
+			fmt.Println("  DHCP server: none")
+		}
+		if len(n.VMs) > 0 {
+			fmt.Printf("  VMs: %s\n", strings.Join(n.VMs, ", "))
+		}
+	}
+	for _, dhcp := range dhcpServers {
+		fmt.Printf("%s [orphan DHCP server]\n", dhcp.NetworkName)
+	}
+	return nil
+}
+
+func networkPruneRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	hostOnlyCIDR := configuredHostOnlyCIDR()
+	networks, dhcpServers, err := virtualbox.ListHostOnlyNetworks()
+	if err != nil {
+		return fmt.Errorf("cannot list host-only networks: %w", err)
+	}
+	pruned := 0
+	for _, n := range networks {
+		if len(n.VMs) > 0 || n.IsOwned(hostOnlyCIDR) {
+			continue
+		}
+		fmt.Printf("Removing unused host-only network %s (%s)...\n", n.Name, n.IPv4.String())
+		err = virtualbox.RemoveHostOnlyNetwork(n)
+		if err != nil {
+			return err
+		}
+		pruned++
+	}
+	for _, dhcp := range dhcpServers {
+		fmt.Printf("Removing orphan DHCP server %s...\n", dhcp.NetworkName)
+		err = virtualbox.RemoveDHCPServer(dhcp)
+		if err != nil {
+			return err
+		}
+		pruned++
+	}
+	if pruned == 0 {
+		fmt.Println("Nothing to prune")
+	}
+	return nil
+}
+
+func networkRecreateRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	if len(networkCIDR) == 0 {
+		networkCIDR = configuredHostOnlyCIDR()
+	}
+	vmIP, err := virtualbox.ExpectedVMIP(networkCIDR)
+	if err != nil {
+		return fmt.Errorf("invalid host-only CIDR %s: %w", networkCIDR, err)
+	}
+	fmt.Println("Checking host-only network...")
+	err = virtualbox.ValidateHostOnlyCIDR(networkCIDR)
+	if err != nil {
+		return err
+	}
+	running, err := stopIfRunning()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Recreating host-only network %s...\n", networkCIDR)
+	name, err := virtualbox.RecreateHostOnlyNetwork(networkCIDR)
+	if err != nil {
+		return fmt.Errorf("cannot recreate host-only network: %w", err)
+	}
+	if len(name) > 0 {
+		fmt.Printf("minikube VM attached to host-only network %s\n", name)
+	}
+	err = minikube.SetHostOnlyCIDR(networkCIDR)
+	if err != nil {
+		return fmt.Errorf("cannot update minikube configuration: %w", err)
+	}
+	fmt.Printf("Reserving IP %s for minikube VM...\n", vmIP)
+	reservedMAC, err := virtualbox.ReserveVMIP(networkCIDR, vmIP)
+	if err != nil {
+		return fmt.Errorf("cannot reserve IP %s for minikube VM: %w", vmIP, err)
+	}
+	err = gokube.UpdateConfig(map[string]interface{}{
+		"host-only-cidr":    networkCIDR,
+		"dhcp-reserved-mac": reservedMAC,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	if running {
+		return start()
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/helm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var pluginSource string
+var pluginVersion string
+var pluginName string
+var pluginSHA256 string
+
+// pluginsCmd represents the plugins command
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Manages helm plugins. This command installs, upgrades or removes the helm plugins of gokube configuration",
+	Long:  "Manages helm plugins. This command installs, upgrades or removes the helm plugins of gokube configuration (built-in ones and user-defined ones) with helm plugin install",
+}
+
+var pluginsListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "Lists helm plugins, comparing installed versions with configured ones",
+	Long:         "Lists helm plugins, comparing installed versions with configured ones (installed plugins which are not configured are reported as unmanaged)",
+	RunE:         pluginsListRun,
+	SilenceUsage: true,
+}
+
+var pluginsInstallCmd = &cobra.Command{
+	Use:          "install [name...]",
+	Short:        "Installs or upgrades configured helm plugins, or adds a plugin to gokube configuration with --source",
+	Long:         "Installs or upgrades configured helm plugins whose installed version differs from the configured one (all of them if no name is provided), or adds a plugin to gokube configuration with --source and installs it",
+	RunE:         pluginsInstallRun,
+	SilenceUsage: true,
+}
+
+var pluginsRemoveCmd = &cobra.Command{
+	Use:          "remove <name>",
+	Short:        "Uninstalls a helm plugin and removes it from gokube configuration",
+	Long:         "Uninstalls a helm plugin and removes it from gokube configuration (built-in plugins are installed again by next upgrade)",
+	RunE:         pluginsRemoveRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	pluginsInstallCmd.Flags().StringVarP(&pluginSource, "source", "", "", "Plugin archive URL (each %s being replaced by the version) or VCS repository URL")
+	pluginsInstallCmd.Flags().StringVarP(&pluginVersion, "version", "", "", "Plugin version (required with --source)")
+	pluginsInstallCmd.Flags().StringVarP(&pluginName, "plugin-name", "", "", "Name declared by the plugin in its plugin.yaml, if it differs from the given name")
+	pluginsInstallCmd.Flags().StringVarP(&pluginSHA256, "sha256", "", "", "Expected SHA-256 of the plugin archive")
+	pluginsCmd.AddCommand(pluginsListCmd)
+	pluginsCmd.AddCommand(pluginsInstallCmd)
+	pluginsCmd.AddCommand(pluginsRemoveCmd)
+	rootCmd.AddCommand(pluginsCmd)
+}
+
+// readHelmPlugins returns the user-defined helm plugins persisted in gokube configuration
+func readHelmPlugins() ([]*helm.Plugin, error) {
+	var plugins []*helm.Plugin
+	err := viper.UnmarshalKey("helm-plugins", &plugins)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read helm plugins from gokube configuration: %w", err)
+	}
+	return plugins, nil
+}
+
+// configuredHelmPlugins returns the valid user-defined helm plugins of gokube configuration:
+//
+//	helm-plugins:
+//	- name: helm-diff
+//	  plugin-name: diff
+//	  source: https://github.com/databus23/helm-diff/releases/download/v%s/helm-diff-windows-amd64.tgz
+//	  version: 3.9.11
+func configuredHelmPlugins() []*helm.Plugin {
+	plugins, err := readHelmPlugins()
+	if err != nil {
+		fmt.Printf("Warning: %s\n", err)
+		return nil
+	}
+	var valid []*helm.Plugin
+	names := map[string]bool{}
+	for _, p := range plugins {
+		err = p.Validate()
+		if err == nil && (helm.IsBuiltinPlugin(p.Name) || names[p.Name]) {
+			err = fmt.Errorf("plugin %s is already defined", p.Name)
+		}
+		if err != nil {
+			fmt.Printf("Warning: ignoring helm plugin %s from gokube configuration: %s\n", p.Name, err)
+			continue
+		}
+		names[p.Name] = true
+		valid = append(valid, p)
+	}
+	return valid
+}
+
+func saveHelmPlugins(plugins []*helm.Plugin) error {
+	values := make([]map[string]interface{}, 0, len(plugins))
+	for _, p := range plugins {
+		value := map[string]interface{}{"name": p.Name, "source": p.Source, "version": p.Version}
+		if len(p.PluginName) > 0 {
+			value["plugin-name"] = p.PluginName
+		}
+		if len(p.SHA256) > 0 {
+			value["sha256"] = p.SHA256
+		}
+		values = append(values, value)
+	}
+	err := gokube.UpdateConfig(map[string]interface{}{"helm-plugins": values})
+	if err != nil {
+		return fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	return nil
+}
+
+func pluginsListRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	configured := helmPlugins()
+	installed, err := helm.InstalledPlugins()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tINSTALLED\tCONFIGURED\tSTATUS")
+	for _, status := range gokube.CheckTools(configured.Tools()) {
+		installedVersion := status.Installed
+		if len(installedVersion) == 0 {
+			installedVersion = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Tool.Name, installedVersion, status.Tool.Version, status.Status())
+	}
+	managed := map[string]bool{}
+	for _, p := range configured.Plugins() {
+		managed[p.HelmName()] = true
+	}
+	var unmanaged []string
+	for name := range installed {
+		if !managed[name] {
+			unmanaged = append(unmanaged, name)
+		}
+	}
+	sort.Strings(unmanaged)
+	for _, name := range unmanaged {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, installed[name].Metadata.Version, "-", "unmanaged")
+	}
+	return w.Flush()
+}
+
+func pluginsInstallRun(cmd *cobra.Command, args []string) error {
+	if len(pluginSource) == 0 {
+		if len(pluginVersion) > 0 || len(pluginName) > 0 || len(pluginSHA256) > 0 {
+			return fmt.Errorf("--version, --plugin-name and --sha256 require --source")
+		}
+		tools, err := selectTools(helmPlugins().Tools(), args)
+		if err != nil {
+			return err
+		}
+		return gokube.UpgradeTools(tools)
+	}
+
+	if len(args) != 1 {
+		return cmd.Usage()
+	}
+	p := &helm.Plugin{Name: args[0], PluginName: pluginName, Source: pluginSource, Version: pluginVersion, SHA256: pluginSHA256}
+	err := p.Validate()
+	if err != nil {
+		return err
+	}
+	if helm.IsBuiltinPlugin(p.Name) {
+		return fmt.Errorf("%s is a built-in plugin, its version is set with environment variables", p.Name)
+	}
+	plugins, err := readHelmPlugins()
+	if err != nil {
+		return err
+	}
+	var updated []*helm.Plugin
+	for _, configured := range plugins {
+		if configured.Name != p.Name {
+			updated = append(updated, configured)
+		}
+	}
+	fmt.Printf("Installing helm plugin %s %s...\n", p.Name, p.Version)
+	err = gokube.UpgradeTools([]*gokube.Tool{gokube.PluginTool(p)})
+	if err != nil {
+		return err
+	}
+	return saveHelmPlugins(append(updated, p))
+}
+
+func pluginsRemoveRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Usage()
+	}
+	name := args[0]
+	helmName := name
+	for _, p := range helmPlugins().Plugins() {
+		if p.Name == name || p.HelmName() == name {
+			name = p.Name
+			helmName = p.HelmName()
+		}
+	}
+	installed, err := helm.InstalledPlugins()
+	if err != nil {
+		return err
+	}
+	if _, ok := installed[helmName]; ok {
+		err = helm.UninstallPlugin(helmName)
+		if err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Helm plugin %s is not installed\n", name)
+	}
+
+	if helm.IsBuiltinPlugin(name) {
+		fmt.Printf("Warning: %s is a built-in plugin, it will be installed again by next upgrade\n", name)
+		return nil
+	}
+	plugins, err := readHelmPlugins()
+	if err != nil {
+		return err
+	}
+	var updated []*helm.Plugin
+	for _, p := range plugins {
+		if p.Name != name {
+			updated = append(updated, p)
+		}
+	}
+	if len(updated) == len(plugins) {
+		return nil
+	}
+	return saveHelmPlugins(updated)
+}
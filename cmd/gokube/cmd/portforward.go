@@ -0,0 +1,301 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/kubectl"
+	"github.com/gemalto/gokube/pkg/portforward"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	portForwardNamespace string
+	followLogs           bool
+)
+
+// portForwardCmd represents the port-forward command
+var portForwardCmd = &cobra.Command{
+	Use:   "port-forward",
+	Short: "Manages background port-forwards. This command forwards localhost ports to pods, services or deployments",
+	Long:  "Manages background port-forwards. This command forwards localhost ports to pods, services or deployments from a background process which reconnects them automatically, port-forwards are restarted on start",
+}
+
+var portForwardAddCmd = &cobra.Command{
+	Use:          "add <[pod|svc|deploy/]name> <local-port>:<remote-port>",
+	Short:        "Adds a background port-forward",
+	Long:         "Adds a background port-forward, the remote port being a number or a port name",
+	RunE:         portForwardAddRun,
+	SilenceUsage: true,
+}
+
+var portForwardListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "Lists background port-forwards and their status",
+	Long:         "Lists background port-forwards and their status",
+	RunE:         portForwardListRun,
+	SilenceUsage: true,
+}
+
+var portForwardRemoveCmd = &cobra.Command{
+	Use:          "remove <local-port>",
+	Short:        "Removes a background port-forward",
+	Long:         "Removes a background port-forward",
+	RunE:         portForwardRemoveRun,
+	SilenceUsage: true,
+}
+
+var portForwardLogsCmd = &cobra.Command{
+	Use:          "logs",
+	Short:        "Shows background port-forwards logs",
+	Long:         "Shows background port-forwards logs",
+	RunE:         portForwardLogsRun,
+	SilenceUsage: true,
+}
+
+var portForwardDaemonCmd = &cobra.Command{
+	Use:          "daemon",
+	Short:        "Runs background port-forwards",
+	Long:         "Runs background port-forwards (started by gokube, not intended to be run directly)",
+	RunE:         portForwardDaemonRun,
+	SilenceUsage: true,
+	Hidden:       true,
+}
+
+func init() {
+	portForwardAddCmd.Flags().StringVarP(&portForwardNamespace, "namespace", "n", "default", "Namespace of the resource to forward to")
+	portForwardLogsCmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "Follow logs output")
+	portForwardCmd.AddCommand(portForwardAddCmd)
+	portForwardCmd.AddCommand(portForwardListCmd)
+	portForwardCmd.AddCommand(portForwardRemoveCmd)
+	portForwardCmd.AddCommand(portForwardLogsCmd)
+	portForwardCmd.AddCommand(portForwardDaemonCmd)
+	rootCmd.AddCommand(portForwardCmd)
+}
+
+// configuredPortForwards returns the persisted background port-forwards
+func configuredPortForwards() ([]portforward.Forward, error) {
+	var forwards []portforward.Forward
+	err := viper.UnmarshalKey("port-forwards", &forwards)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read port-forwards from gokube configuration: %w", err)
+	}
+	return forwards, nil
+}
+
+func savePortForwards(forwards []portforward.Forward) error {
+	values := make([]map[string]interface{}, 0, len(forwards))
+	for _, f := range forwards {
+		values = append(values, map[string]interface{}{
+			"namespace":   f.Namespace,
+			"resource":    f.Resource,
+			"local-port":  f.LocalPort,
+			"remote-port": f.RemotePort,
+		})
+	}
+	err := gokube.UpdateConfig(map[string]interface{}{"port-forwards": values})
+	if err != nil {
+		return fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	return nil
+}
+
+// restartPortForwardDaemon (re)starts the background process running persisted port-forwards, if any
+func restartPortForwardDaemon() error {
+	err := portforward.StopDaemon()
+	if err != nil {
+		return err
+	}
+	forwards, err := configuredPortForwards()
+	if err != nil || len(forwards) == 0 {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot find gokube executable: %w", err)
+	}
+	fmt.Printf("Starting %d background port-forward(s)...\n", len(forwards))
+	return portforward.StartDaemon(executable, "port-forward", "daemon")
+}
+
+func portForwardAddRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return cmd.Usage()
+	}
+	_, _, err := portforward.ParseResource(args[0])
+	if err != nil {
+		return err
+	}
+	ports := strings.SplitN(args[1], ":", 2)
+	if len(ports) != 2 || len(ports[1]) == 0 {
+		return fmt.Errorf("invalid ports %q, <local-port>:<remote-port> expected", args[1])
+	}
+	localPort, err := strconv.Atoi(ports[0])
+	if err != nil || localPort <= 0 || localPort > 65535 {
+		return fmt.Errorf("invalid local port %q", ports[0])
+	}
+	err = gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	forwards, err := configuredPortForwards()
+	if err != nil {
+		return err
+	}
+	for _, f := range forwards {
+		if f.LocalPort == localPort {
+			return fmt.Errorf("localhost:%d is already forwarded (%s)", localPort, f)
+		}
+	}
+	err = savePortForwards(append(forwards, portforward.Forward{
+		Namespace:  portForwardNamespace,
+		Resource:   args[0],
+		LocalPort:  localPort,
+		RemotePort: ports[1],
+	}))
+	if err != nil {
+		return err
+	}
+	return restartPortForwardDaemon()
+}
+
+func portForwardListRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	forwards, err := configuredPortForwards()
+	if err != nil {
+		return err
+	}
+	if len(forwards) == 0 {
+		fmt.Println("No port-forward")
+		return nil
+	}
+	pid := portforward.DaemonPID()
+	status := map[int]string{}
+	if pid != 0 {
+		fmt.Printf("Port-forward daemon running (PID %d)\n", pid)
+		status = portforward.ReadStatus()
+	} else {
+		fmt.Println("Port-forward daemon not running (use 'gokube start' to start it)")
+	}
+	for _, f := range forwards {
+		s, ok := status[f.LocalPort]
+		if !ok {
+			s = "not started"
+		}
+		fmt.Printf("%s (%s)\n", f, s)
+	}
+	return nil
+}
+
+func portForwardRemoveRun(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Usage()
+	}
+	localPort, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid local port %q", args[0])
+	}
+	err = gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	forwards, err := configuredPortForwards()
+	if err != nil {
+		return err
+	}
+	var kept []portforward.Forward
+	for _, f := range forwards {
+		if f.LocalPort != localPort {
+			kept = append(kept, f)
+		}
+	}
+	if len(kept) == len(forwards) {
+		return fmt.Errorf("localhost:%d is not forwarded", localPort)
+	}
+	err = savePortForwards(kept)
+	if err != nil {
+		return err
+	}
+	if portforward.DaemonPID() == 0 {
+		return nil
+	}
+	return restartPortForwardDaemon()
+}
+
+func portForwardLogsRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	logFile, err := os.Open(portforward.LogFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("No port-forward logs")
+			return nil
+		}
+		return fmt.Errorf("cannot open port-forward logs: %w", err)
+	}
+	defer logFile.Close()
+	for {
+		_, err = io.Copy(os.Stdout, logFile)
+		if err != nil || !followLogs {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func portForwardDaemonRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	forwards, err := configuredPortForwards()
+	if err != nil {
+		return err
+	}
+	config, err := kubectl.RestConfig("minikube")
+	if err != nil {
+		return err
+	}
+	manager, err := portforward.NewManager(config, os.Stderr, func(status map[int]string) {
+		_ = portforward.WriteStatus(status)
+	})
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	manager.Run(ctx, forwards)
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/proxy"
+	"github.com/gemalto/gokube/pkg/utils"
+	"github.com/gemalto/gokube/pkg/virtualbox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var proxyPAC string
+
+// configuredProxy returns the proxy configuration persisted by init, falling back on the environment one for configurations
+// written by older gokube versions (detection, which may download a PAC file, is only done by init)
+func configuredProxy() *proxy.Config {
+	if viper.IsSet("http-proxy") || viper.IsSet("https-proxy") || viper.IsSet("no-proxy") {
+		return &proxy.Config{
+			HTTPProxy:  viper.GetString("http-proxy"),
+			HTTPSProxy: viper.GetString("https-proxy"),
+			NoProxy:    viper.GetString("no-proxy"),
+		}
+	}
+	return proxy.Current()
+}
+
+// resolveProxy returns the proxy configuration given by init flags, or the detected one, and persists it for next commands
+func resolveProxy(cmd *cobra.Command) (*proxy.Config, error) {
+	config, err := flagsProxy(cmd)
+	if err != nil {
+		return nil, err
+	}
+	err = gokube.UpdateConfig(map[string]interface{}{
+		"http-proxy":  config.HTTPProxy,
+		"https-proxy": config.HTTPSProxy,
+		"no-proxy":    config.NoProxy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	return config, nil
+}
+
+// flagsProxy returns the detected proxy configuration, each field being overridden by its init flag if given
+func flagsProxy(cmd *cobra.Command) (*proxy.Config, error) {
+	flags := cmd.Flags()
+	config := &proxy.Config{}
+	if !flags.Changed("http-proxy") || !flags.Changed("https-proxy") || !flags.Changed("no-proxy") {
+		detected, err := proxy.Detect(proxyPAC)
+		if err != nil {
+			return nil, fmt.Errorf("cannot detect proxy configuration: %w", err)
+		}
+		config = detected
+	}
+	if flags.Changed("http-proxy") {
+		config.HTTPProxy = httpProxy
+	}
+	if flags.Changed("https-proxy") {
+		config.HTTPSProxy = httpsProxy
+	}
+	if flags.Changed("no-proxy") {
+		config.NoProxy = noProxy
+	}
+	return config, nil
+}
+
+// applyProxy completes NO_PROXY with the entries needed to reach minikube VM and cluster, and makes the configuration
+// the one used by gokube downloads and child processes
+func applyProxy(config *proxy.Config, hostOnlyCIDR string, dnsDomain string) (*proxy.Config, error) {
+	vmIP, _ := virtualbox.ExpectedVMIP(hostOnlyCIDR)
+	completed := config.Complete(vmIP, hostOnlyCIDR, dnsDomain)
+	if verbose && !completed.IsEmpty() {
+		fmt.Printf("Using proxy HTTP_PROXY=%s HTTPS_PROXY=%s NO_PROXY=%s\n", completed.HTTPProxy, completed.HTTPSProxy, completed.NoProxy)
+	}
+	err := proxy.Apply(completed)
+	if err != nil {
+		return nil, err
+	}
+	return completed, nil
+}
+
+// loadProxy applies the persisted proxy configuration, before any command reaches the network
+func loadProxy() error {
+	err := gokube.ReadConfig(false)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	_, err = applyProxy(configuredProxy(), configuredHostOnlyCIDR(), configuredDNSDomain())
+	return err
+}
+
+// configuredDNSDomain returns the persisted cluster DNS domain, falling back on MINIKUBE_DNS_DOMAIN for configurations written by older gokube versions
+func configuredDNSDomain() string {
+	if viper.IsSet("dns-domain") {
+		return viper.GetString("dns-domain")
+	}
+	return utils.GetValueFromEnv("MINIKUBE_DNS_DOMAIN", DEFAULT_MINIKUBE_DNS_DOMAIN)
+}
@@ -0,0 +1,74 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/gemalto/gokube/pkg/proxy"
+	"github.com/spf13/cobra"
+)
+
+func TestFlagsProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3129")
+	t.Setenv("NO_PROXY", "corp.example.com")
+	proxyPAC = ""
+	tests := []struct {
+		name  string
+		flags map[string]string
+		want  proxy.Config
+	}{
+		{
+			name: "environment",
+			want: proxy.Config{HTTPProxy: "http://env-proxy:3128", HTTPSProxy: "http://env-proxy:3129", NoProxy: "corp.example.com"},
+		},
+		{
+			name:  "no-proxy flag",
+			flags: map[string]string{"no-proxy": "intranet.example.com"},
+			want:  proxy.Config{HTTPProxy: "http://env-proxy:3128", HTTPSProxy: "http://env-proxy:3129", NoProxy: "intranet.example.com"},
+		},
+		{
+			name:  "https-proxy flag",
+			flags: map[string]string{"https-proxy": "http://flag-proxy:8080"},
+			want:  proxy.Config{HTTPProxy: "http://env-proxy:3128", HTTPSProxy: "http://flag-proxy:8080", NoProxy: "corp.example.com"},
+		},
+		{
+			name:  "all flags",
+			flags: map[string]string{"http-proxy": "http://flag-proxy:8080", "https-proxy": "", "no-proxy": ""},
+			want:  proxy.Config{HTTPProxy: "http://flag-proxy:8080"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().StringVarP(&httpProxy, "http-proxy", "", "", "")
+			cmd.Flags().StringVarP(&httpsProxy, "https-proxy", "", "", "")
+			cmd.Flags().StringVarP(&noProxy, "no-proxy", "", "", "")
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatalf("Set(%s) error = %v", name, err)
+				}
+			}
+			config, err := flagsProxy(cmd)
+			if err != nil {
+				t.Fatalf("flagsProxy() error = %v", err)
+			}
+			if *config != tt.want {
+				t.Errorf("flagsProxy() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/minikube"
+	"github.com/gemalto/gokube/pkg/virtualbox"
+	"github.com/spf13/cobra"
+)
+
+var newMemory int16
+var newCPUs int16
+var newDisk string
+
+// resizeCmd represents the resize command
+var resizeCmd = &cobra.Command{
+	Use:          "resize",
+	Short:        "Resizes gokube. This command changes minikube VM memory, CPUs or disk size without recreating it",
+	Long:         "Resizes gokube. This command changes minikube VM memory, CPUs or disk size without recreating it (disk can only grow)",
+	RunE:         resizeRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	resizeCmd.Flags().Int16VarP(&newMemory, "memory", "", 0, "Amount of RAM allocated to the minikube VM in MB")
+	resizeCmd.Flags().Int16VarP(&newCPUs, "cpus", "", 0, "Number of CPUs allocated to the minikube VM")
+	resizeCmd.Flags().StringVarP(&newDisk, "disk", "", "", "Disk size allocated to the minikube VM. Format: <number>[<unit>], where unit = b, k, m or g")
+	rootCmd.AddCommand(resizeCmd)
+}
+
+// diskSizeInMB converts a minikube disk size (<number>[<unit>], where unit = b, k, m or g) to MB
+func diskSizeInMB(size string) (int, error) {
+	size = strings.ToLower(strings.TrimSpace(size))
+	multipliers := map[string]float64{"b": 1.0 / (1024 * 1024), "k": 1.0 / 1024, "m": 1, "g": 1024}
+	// Like minikube, a size without unit is in MB
+	multiplier := 1.0
+	if len(size) > 0 {
+		if m, ok := multipliers[size[len(size)-1:]]; ok {
+			multiplier = m
+			size = size[:len(size)-1]
+		}
+	}
+	value, err := strconv.ParseFloat(size, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid disk size %q", size)
+	}
+	return int(value * multiplier), nil
+}
+
+func resizeRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	if newMemory <= 0 && newCPUs <= 0 && len(newDisk) == 0 {
+		return fmt.Errorf("please provide at least one of --memory, --cpus or --disk")
+	}
+
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+
+	vboxManager := virtualbox.NewVBoxManager()
+	newDiskInMB := 0
+	if len(newDisk) > 0 {
+		newDiskInMB, err = diskSizeInMB(newDisk)
+		if err != nil {
+			return err
+		}
+		systemDisk, err := vboxManager.GetSystemDisk()
+		if err != nil {
+			return fmt.Errorf("cannot get minikube VM system disk: %w", err)
+		}
+		capacity, err := vboxManager.GetDiskCapacity(systemDisk)
+		if err != nil {
+			return fmt.Errorf("cannot get minikube VM system disk capacity: %w", err)
+		}
+		if newDiskInMB <= capacity {
+			return fmt.Errorf("disk can only grow (current size is %dMB)", capacity)
+		}
+	}
+
+	running, err := stopIfRunning()
+	if err != nil {
+		return err
+	}
+
+	settings := map[string]interface{}{}
+	var modifyArgs []string
+	if newMemory > 0 {
+		modifyArgs = append(modifyArgs, "--memory", strconv.Itoa(int(newMemory)))
+		settings["memory"] = newMemory
+	}
+	if newCPUs > 0 {
+		modifyArgs = append(modifyArgs, "--cpus", strconv.Itoa(int(newCPUs)))
+		settings["cpus"] = newCPUs
+	}
+	if len(modifyArgs) > 0 {
+		fmt.Println("Updating minikube VM memory/CPUs...")
+		err = virtualbox.Update(modifyArgs...)
+		if err != nil {
+			return err
+		}
+	}
+	if newDiskInMB > 0 {
+		fmt.Printf("Resizing minikube VM disk to %dMB...\n", newDiskInMB)
+		err = vboxManager.ResizeSystemDisk(newDiskInMB)
+		if err != nil {
+			return err
+		}
+		settings["disk"] = fmt.Sprintf("%dm", newDiskInMB)
+		// Filesystem is grown inside the VM during next start
+		settings["grow-filesystem"] = true
+	}
+	// New resources are kept both by gokube, for next inits, and by minikube, for next starts
+	err = gokube.UpdateConfig(settings)
+	if err != nil {
+		return fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	err = minikube.SetResources(int(newMemory), int(newCPUs), newDiskInMB)
+	if err != nil {
+		return fmt.Errorf("cannot update minikube configuration: %w", err)
+	}
+	fmt.Println("Minikube VM has successfully been resized")
+
+	if running {
+		return start()
+	}
+	return nil
+}
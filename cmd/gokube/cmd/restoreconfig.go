@@ -0,0 +1,77 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/spf13/cobra"
+)
+
+var backupName string
+var listBackups bool
+
+// restoreConfigCmd represents the restore-config command
+var restoreConfigCmd = &cobra.Command{
+	Use:          "restore-config",
+	Short:        "Restores kubectl and docker configuration. This command restores ~/.kube and ~/.docker from a backup taken by init --clean",
+	Long:         "Restores kubectl and docker configuration. This command restores ~/.kube and ~/.docker from a backup taken by init --clean",
+	RunE:         restoreConfigRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	restoreConfigCmd.Flags().StringVarP(&backupName, "name", "n", "", "The backup name (latest one if not provided)")
+	restoreConfigCmd.Flags().BoolVarP(&listBackups, "list", "l", false, "List available backups")
+	rootCmd.AddCommand(restoreConfigCmd)
+}
+
+func restoreConfigRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+
+	backups, err := gokube.ListConfigBackups()
+	if err != nil {
+		return fmt.Errorf("cannot list configuration backups: %w", err)
+	}
+	if listBackups {
+		for _, name := range backups {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	if len(backupName) == 0 {
+		if len(backups) == 0 {
+			return fmt.Errorf("no configuration backup found")
+		}
+		backupName = backups[len(backups)-1]
+	}
+
+	// Current configuration is saved first so that restore can be undone, the restored backup being kept from pruning
+	fmt.Println("Backing up current kubectl and docker configuration...")
+	currentBackup, err := gokube.BackupConfigDirectories(backupName)
+	if err != nil {
+		return fmt.Errorf("cannot backup current configuration: %w", err)
+	}
+	fmt.Printf("Current configuration saved to backup '%s'\n", currentBackup)
+	fmt.Printf("Restoring kubectl and docker configuration from backup '%s'...\n", backupName)
+	err = gokube.RestoreConfigDirectories(backupName)
+	if err != nil {
+		return fmt.Errorf("cannot restore configuration from backup %s: %w", backupName, err)
+	}
+	fmt.Printf("Configuration has successfully been restored from backup '%s'\n", backupName)
+	return nil
+}
@@ -18,17 +18,12 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/coreos/go-semver/semver"
-	"github.com/gemalto/gokube/pkg/docker"
+	"github.com/gemalto/gokube/pkg/compat"
 	"github.com/gemalto/gokube/pkg/gokube"
 	"github.com/gemalto/gokube/pkg/helm"
-	"github.com/gemalto/gokube/pkg/helmimage"
-	"github.com/gemalto/gokube/pkg/helmpush"
-	"github.com/gemalto/gokube/pkg/helmspray"
-	"github.com/gemalto/gokube/pkg/kubectl"
 	"github.com/gemalto/gokube/pkg/minikube"
-	"github.com/gemalto/gokube/pkg/stern"
-	"github.com/gemalto/gokube/pkg/k9s"
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/tool"
 	"github.com/gemalto/gokube/pkg/utils"
 	"github.com/spf13/cobra"
 	"os"
@@ -52,7 +47,6 @@ const (
 	DEFAULT_STERN_VERSION              = "1.33.1"
 	DEFAULT_K9S_VERSION                = "0.50.18"
 	DEFAULT_MINIAPPS_REPO              = "https://thalesgroup.github.io/miniapps"
-	DEFAULT_GOKUBE_CHECK_IP            = "192.168.99.100"
 	DEFAULT_GOKUBE_CIDR                = "192.168.99.1/24"
 )
 
@@ -81,65 +75,88 @@ var snapshotName string
 var verbose bool
 var quiet bool
 var force bool
+var dryRun bool
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "gokube",
 	Short: `gokube is a nice installer to provide an environment for developing day-to-day with kubernetes & helm on your laptop.`,
 	Long:  `gokube is a nice installer to provide an environment for developing day-to-day with kubernetes & helm on your laptop.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		runner.SetDryRun(dryRun)
+		err := loadProxy()
+		if err != nil {
+			return err
+		}
+		err = loadHTTPConfig()
+		if err != nil {
+			return err
+		}
+		registerConfiguredTools()
+		return loadSignatures()
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		printLatestVersion(0)
+	},
 }
 
 func init() {
+	loadURLVersionsFromEnv()
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Activate verbose logging")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Only display the actions which would be performed, without executing them")
+	rootCmd.PersistentFlags().StringVar(&signaturePolicy, "signature-policy", utils.GetValueFromEnv("GOKUBE_SIGNATURE_POLICY", ""), "Signature verification policy of downloaded releases (require, warn or off), overriding gokube configuration")
 }
 
-func checkMinimumRequirements() {
-	if semver.New(kubernetesVersion[1:]).Compare(*semver.New("1.20.0")) < 0 {
-		fmt.Println("Error: This gokube version is only compatible with kubernetes version >= 1.20.0")
-		os.Exit(1)
-	}
-	if semver.New(minikubeVersion[1:]).Compare(*semver.New("1.25.0")) < 0 {
-		fmt.Println("Error: This gokube version is only compatible with minikube version >= 1.25.0")
-		os.Exit(1)
-	}
-	if semver.New(helmVersion[1:]).Compare(*semver.New("3.0.0-0")) < 0 {
-		fmt.Println("Error: This gokube version is only compatible with helm version >= 3.0.0-0")
-		os.Exit(1)
+// configuredVersions returns the versions of gokube dependencies to be used with the given kubernetes version
+func configuredVersions(kubernetesVersion string) compat.Versions {
+	return compat.Versions{
+		Kubernetes: kubernetesVersion,
+		Minikube:   minikubeVersion,
+		Kubectl:    kubectlVersion,
+		Helm:       helmVersion,
+		HelmPlugins: map[string]string{
+			"helm-spray": helmSprayVersion,
+			"helm-push":  helmPushVersion,
+			"helm-image": helmImageVersion,
+		},
 	}
-	if semver.New(helmSprayVersion[1:]).Compare(*semver.New("4.0.0-0")) < 0 {
-		fmt.Println("Error: This gokube version is only compatible with helm-spray version >= 4.0.0-0")
-		os.Exit(1)
-	}
-	if semver.New(helmPushVersion).Compare(*semver.New("0.10.0")) <= 0 {
-		fmt.Println("Error: This gokube version is only compatible with helm-push version >= 0.10.0")
-		os.Exit(1)
+}
+
+// checkCompatibility validates the versions of gokube dependencies against the compatibility matrix, displaying warnings
+func checkCompatibility(kubernetesVersion string) error {
+	warnings, err := compat.Validate(configuredVersions(kubernetesVersion))
+	for _, w := range warnings {
+		fmt.Printf("Warning: %s\n", w)
 	}
+	return err
 }
 
+// loadURLVersionsFromEnv loads the URL and version of gokube dependencies, which can be overridden by environment variables
 func loadURLVersionsFromEnv() {
-	kubectlURL = utils.GetValueFromEnv("KUBECTL_URL", kubectl.DEFAULT_URL)
+	kubectlURL = utils.GetValueFromEnv("KUBECTL_URL", tool.DEFAULT_KUBECTL_URL)
 	kubectlVersion = utils.GetValueFromEnv("KUBECTL_VERSION", DEFAULT_KUBECTL_VERSION)
 	miniappsRepo = utils.GetValueFromEnv("MINIAPPS_URL", DEFAULT_MINIAPPS_REPO)
 	minikubeURL = utils.GetValueFromEnv("MINIKUBE_URL", minikube.DEFAULT_URL)
 	minikubeVersion = utils.GetValueFromEnv("MINIKUBE_VERSION", DEFAULT_MINIKUBE_VERSION)
-	dockerURL = utils.GetValueFromEnv("DOCKER_URL", docker.DEFAULT_URL)
+	dockerURL = utils.GetValueFromEnv("DOCKER_URL", tool.DEFAULT_DOCKER_URL)
 	dockerVersion = utils.GetValueFromEnv("DOCKER_VERSION", DEFAULT_DOCKER_VERSION)
-	helmURL = utils.GetValueFromEnv("HELM_URL", helm.DEFAULT_URL)
+	helmURL = utils.GetValueFromEnv("HELM_URL", tool.DEFAULT_HELM_URL)
 	helmVersion = utils.GetValueFromEnv("HELM_VERSION", DEFAULT_HELM_VERSION)
-	helmSprayURL = utils.GetValueFromEnv("HELM_SPRAY_URL", helmspray.DEFAULT_URL)
+	helmSprayURL = utils.GetValueFromEnv("HELM_SPRAY_URL", helm.DEFAULT_SPRAY_URL)
 	helmSprayVersion = utils.GetValueFromEnv("HELM_SPRAY_VERSION", DEFAULT_HELM_SPRAY_VERSION)
-	helmImageURL = utils.GetValueFromEnv("HELM_IMAGE_URL", helmimage.DEFAULT_URL)
+	helmImageURL = utils.GetValueFromEnv("HELM_IMAGE_URL", helm.DEFAULT_IMAGE_URL)
 	helmImageVersion = utils.GetValueFromEnv("HELM_IMAGE_VERSION", DEFAULT_HELM_IMAGE_VERSION)
-	helmPushURL = utils.GetValueFromEnv("HELM_PUSH_URL", helmpush.DEFAULT_URL)
+	helmPushURL = utils.GetValueFromEnv("HELM_PUSH_URL", helm.DEFAULT_PUSH_URL)
 	helmPushVersion = utils.GetValueFromEnv("HELM_PUSH_VERSION", DEFAULT_HELM_PUSH_VERSION)
-	sternURL = utils.GetValueFromEnv("STERN_URL", stern.DEFAULT_URL)
+	sternURL = utils.GetValueFromEnv("STERN_URL", tool.DEFAULT_STERN_URL)
 	sternVersion = utils.GetValueFromEnv("STERN_VERSION", DEFAULT_STERN_VERSION)
-	k9sURL = utils.GetValueFromEnv("K9S_URL", k9s.DEFAULT_URL)
+	k9sURL = utils.GetValueFromEnv("K9S_URL", tool.DEFAULT_K9S_URL)
 	k9sVersion = utils.GetValueFromEnv("K9S_VERSION", DEFAULT_K9S_VERSION)
 }
 
-func upgradeDependencies() error {
-	return gokube.UpgradeDependencies(&gokube.Dependencies{
+// dependencies returns gokube dependencies with their configured URL and version
+func dependencies() *gokube.Dependencies {
+	return &gokube.Dependencies{
 		MinikubeURL:     minikubeURL,
 		MinikubeVersion: minikubeVersion,
 		HelmURL:         helmURL,
@@ -152,18 +169,29 @@ func upgradeDependencies() error {
 		SternVersion:    sternVersion,
 		K9sURL:          k9sURL,
 		K9sVersion:      k9sVersion,
-	})
+		Extra:           tool.Registered(),
+	}
 }
 
-func upgradeHelmPlugins() error {
-	return gokube.UpgradeHelmPlugins(&gokube.HelmPlugins{
+// helmPlugins returns helm plugins with their configured URL and version
+func helmPlugins() *gokube.HelmPlugins {
+	return &gokube.HelmPlugins{
 		SprayURL:     helmSprayURL,
 		SprayVersion: helmSprayVersion,
 		ImageURL:     helmImageURL,
 		ImageVersion: helmImageVersion,
 		PushURL:      helmPushURL,
 		PushVersion:  helmPushVersion,
-	})
+		Extra:        configuredHelmPlugins(),
+	}
+}
+
+func upgradeDependencies() error {
+	return gokube.UpgradeDependencies(dependencies())
+}
+
+func upgradeHelmPlugins() error {
+	return gokube.UpgradeHelmPlugins(helmPlugins())
 }
 
 // Execute adds all child commands to the root command sets flags appropriately.
@@ -39,7 +39,7 @@ func saveRun(cmd *cobra.Command, args []string) error {
 	checkLatestVersion()
 
 	running := false
-	if live && !quiet {
+	if live && !quiet && !dryRun {
 		gokube.ConfirmSnapshotCommandExecution()
 	} else if !live {
 		var err error
@@ -0,0 +1,87 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gemalto/gokube/pkg/runner"
+)
+
+// recordFlow runs the given flow with a Recorder replaying the given responses, and returns the recorded commands,
+// executables being reduced to their base name without extension
+func recordFlow(t *testing.T, responses map[string]*runner.Response, flow func() error) ([]string, error) {
+	t.Helper()
+	t.Setenv("GOKUBE_NO_UPDATE_CHECK", "true")
+	recorder := &runner.Recorder{Responses: responses}
+	previous := runner.Default()
+	runner.SetDefault(recorder)
+	defer runner.SetDefault(previous)
+	err := flow()
+	var commands []string
+	for _, record := range recorder.Records {
+		name := filepath.Base(strings.ReplaceAll(record.Command.Name, `\`, "/"))
+		c := &runner.Command{Name: strings.TrimSuffix(name, filepath.Ext(name)), Args: record.Command.Args}
+		commands = append(commands, c.String())
+	}
+	return commands, err
+}
+
+func TestSaveStoppedVM(t *testing.T) {
+	live, snapshotName = false, "gokube"
+	commands, err := recordFlow(t, map[string]*runner.Response{
+		"VBoxManage showvminfo minikube": {Result: &runner.Result{Stdout: "State:           powered off (since 2024-01-01)\n"}},
+		"VBoxManage snapshot minikube delete gokube": {
+			Result: &runner.Result{Stderr: "This machine does not have any snapshots"},
+			Err:    errors.New("exit status 1"),
+		},
+		"VBoxManage snapshot minikube take gokube": {Result: &runner.Result{}},
+	}, func() error {
+		return saveRun(saveCmd, nil)
+	})
+	if err != nil {
+		t.Fatalf("saveRun() error = %v", err)
+	}
+	want := []string{
+		"VBoxManage showvminfo minikube",
+		"VBoxManage snapshot minikube delete gokube",
+		"VBoxManage snapshot minikube take gokube",
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("saveRun() commands = %v, want %v", commands, want)
+	}
+}
+
+func TestSaveRunningVMFailingSnapshot(t *testing.T) {
+	live, snapshotName = false, "gokube"
+	commands, err := recordFlow(t, map[string]*runner.Response{
+		"VBoxManage showvminfo minikube":             {Result: &runner.Result{Stdout: "State:           running (since 2024-01-01)\n"}},
+		"minikube stop":                              {Result: &runner.Result{}},
+		"VBoxManage snapshot minikube delete gokube": {Result: &runner.Result{}},
+		"VBoxManage snapshot minikube take gokube":   {Err: errors.New("exit status 1")},
+	}, func() error {
+		return saveRun(saveCmd, nil)
+	})
+	if err == nil {
+		t.Fatal("saveRun() error = nil, want snapshot error")
+	}
+	if len(commands) != 4 || commands[1] != "minikube stop" {
+		t.Errorf("saveRun() commands = %v, want VM to be stopped before snapshot", commands)
+	}
+}
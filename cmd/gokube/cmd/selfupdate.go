@@ -0,0 +1,113 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/selfupdate"
+	"github.com/gemalto/gokube/pkg/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var selfUpdateVersion string
+
+// selfUpdateCmd represents the self-update command
+var selfUpdateCmd = &cobra.Command{
+	Use:          "self-update",
+	Short:        "Updates gokube. This command downloads a gokube release and replaces the current executable",
+	Long:         "Updates gokube. This command downloads a gokube release (latest one by default), verifies its checksum and replaces the current executable",
+	RunE:         selfUpdateRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	selfUpdateCmd.Flags().StringVarP(&selfUpdateVersion, "version", "", "", "The gokube version to install (latest one if not provided)")
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+func selfUpdateRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot find gokube executable: %w", err)
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return fmt.Errorf("cannot find gokube executable: %w", err)
+	}
+	selfupdate.CleanOldExecutable(executable)
+
+	var release *selfupdate.Release
+	if len(selfUpdateVersion) > 0 {
+		release, err = selfupdate.FindRelease(releasesAPIURL(), selfUpdateVersion)
+	} else {
+		release, err = selfupdate.LatestRelease(releasesAPIURL(), utils.GetValueFromEnv("GOKUBE_UPDATE_CHANNEL", viper.GetString("update-channel")))
+	}
+	if err != nil {
+		return fmt.Errorf("cannot find gokube release: %w", err)
+	}
+	newVersion, err := semver.NewVersion(release.Version())
+	if err != nil {
+		return fmt.Errorf("invalid gokube release version %q: %w", release.TagName, err)
+	}
+	currentVersion := semver.New(GOKUBE_VERSION)
+	if newVersion.Equal(*currentVersion) {
+		fmt.Printf("gokube %s is already installed\n", GOKUBE_VERSION)
+		return nil
+	}
+	if len(selfUpdateVersion) == 0 && newVersion.LessThan(*currentVersion) {
+		fmt.Printf("gokube %s is newer than latest release %s\n", GOKUBE_VERSION, newVersion)
+		return nil
+	}
+
+	fmt.Printf("Updating gokube %s to %s...\n", GOKUBE_VERSION, newVersion)
+	err = selfupdate.Update(release, executable)
+	if err != nil {
+		return fmt.Errorf("cannot update gokube: %w", err)
+	}
+
+	// Persisted gokube-version tells init which gokube version dependencies were set up with:
+	// keep the current one so that new version forces clean & upgrade on next init, and force it as well on downgrade
+	err = gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	persistedVersion := GOKUBE_VERSION
+	if newVersion.LessThan(*currentVersion) {
+		persistedVersion = "0.0.0"
+	} else if v := viper.GetString("gokube-version"); len(v) > 0 {
+		// An invalid persisted version is replaced by one forcing clean & upgrade as well
+		previousVersion, err := semver.NewVersion(v)
+		if err != nil {
+			persistedVersion = "0.0.0"
+		} else if previousVersion.LessThan(*currentVersion) {
+			persistedVersion = v
+		}
+	}
+	err = gokube.UpdateConfig(map[string]interface{}{"gokube-version": persistedVersion})
+	if err != nil {
+		return fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	fmt.Printf("gokube %s installed, please run 'gokube init' to set up its dependencies\n", newVersion)
+	return nil
+}
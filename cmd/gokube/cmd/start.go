@@ -18,11 +18,11 @@ import (
 	"fmt"
 	"github.com/gemalto/gokube/pkg/gokube"
 	"github.com/gemalto/gokube/pkg/minikube"
+	"github.com/gemalto/gokube/pkg/proxy"
 	"github.com/gemalto/gokube/pkg/utils"
 	"github.com/gemalto/gokube/pkg/virtualbox"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"os/exec"
 )
 
 // startCmd represents the start command
@@ -35,21 +35,26 @@ var startCmd = &cobra.Command{
 }
 
 func init() {
-	loadURLVersionsFromEnv()
 	startCmd.Flags().BoolVarP(&askForUpgrade, "upgrade", "u", false, "Upgrade gokube (download and setup docker, minikube, kubectl and helm)")
 	startCmd.Flags().BoolVar(&force, "force", false, "Force minikube to perform possibly dangerous operations")
 	rootCmd.AddCommand(startCmd)
 }
 
+// configuredKubernetesVersion returns the persisted kubernetes version, falling back on KUBERNETES_VERSION for configurations written by older gokube versions
+func configuredKubernetesVersion() string {
+	kubernetesVersion := viper.GetString("kubernetes-version")
+	if len(kubernetesVersion) == 0 {
+		kubernetesVersion = utils.GetValueFromEnv("KUBERNETES_VERSION", DEFAULT_KUBERNETES_VERSION)
+	}
+	return kubernetesVersion
+}
+
 func start() error {
 	err := gokube.ReadConfig(verbose)
 	if err != nil {
 		return fmt.Errorf("cannot read gokube configuration file: %w", err)
 	}
-	kubernetesVersionForStart := viper.GetString("kubernetes-version")
-	if len(kubernetesVersionForStart) == 0 {
-		kubernetesVersionForStart = utils.GetValueFromEnv("KUBERNETES_VERSION", DEFAULT_KUBERNETES_VERSION)
-	}
+	kubernetesVersionForStart := configuredKubernetesVersion()
 	containerRuntimeForStart := viper.GetString("container-runtime")
 	if len(containerRuntimeForStart) == 0 {
 		containerRuntimeForStart = utils.GetValueFromEnv("MINIKUBE_CONTAINER_RUNTIME", DEFAULT_MINIKUBE_CONTAINER_RUNTIME)
@@ -58,20 +63,62 @@ func start() error {
 	if len(vb7workaround) > 0 {
 		virtualbox.Update("--nat-localhostreachable1=on")
 	}
+	err = shareMounts()
+	if err != nil {
+		fmt.Printf("Warning: cannot share host directories with minikube VM: %s\n", err)
+	}
 	fmt.Printf("Starting minikube VM with kubernetes %s and container runtime %q...\n", kubernetesVersionForStart, containerRuntimeForStart)
 	err = minikube.Restart(kubernetesVersionForStart, containerRuntimeForStart, force, verbose)
 	if err != nil {
 		return fmt.Errorf("cannot restart minikube VM: %w", err)
 	}
 
+	// Docker daemon proxy is configured by minikube, other container runtimes need it too
+	err = minikube.ConfigureRuntimeProxy(containerRuntimeForStart, proxy.Current().Env())
+	if err != nil {
+		fmt.Printf("Warning: cannot configure proxy of %s container runtime: %s\n", containerRuntimeForStart, err)
+	}
+
+	// Grow filesystem after a disk resize
+	if viper.GetBool("grow-filesystem") {
+		fmt.Println("Growing minikube VM filesystem to the whole disk...")
+		err = minikube.GrowFilesystem()
+		if err != nil {
+			fmt.Printf("Warning: cannot grow minikube VM filesystem: %s\n", err)
+		} else {
+			err = gokube.UpdateConfig(map[string]interface{}{"grow-filesystem": false})
+			if err != nil {
+				return fmt.Errorf("cannot write gokube configuration: %w", err)
+			}
+		}
+	}
+
 	// Add swap to Minikube VM
-	if enableSwap {
-        fmt.Println("Enabling swap drive in minikube VM...")
-        err = addSwapToMinikubeDuringStart()
-        if err != nil {
-    	    fmt.Printf("Warning: cannot enable swap drive in minikube VM - start: %s\n", err)
-        }
-    }
+	if configuredSwap() > 0 {
+		fmt.Println("Enabling swap drive in minikube VM...")
+		err = minikube.EnableSwap()
+		if err != nil {
+			fmt.Printf("Warning: cannot enable swap drive in minikube VM - start: %s\n", err)
+		}
+	}
+
+	// Mount host directories
+	err = mountMounts()
+	if err != nil {
+		fmt.Printf("Warning: cannot mount host directories: %s\n", err)
+	}
+
+	// Re-apply localhost port-forwards
+	err = applyForwards()
+	if err != nil {
+		fmt.Printf("Warning: cannot apply localhost port-forwards: %s\n", err)
+	}
+
+	// Restart background port-forwards
+	err = restartPortForwardDaemon()
+	if err != nil {
+		fmt.Printf("Warning: cannot start background port-forwards: %s\n", err)
+	}
 
 	return nil
 }
@@ -83,9 +130,14 @@ func startRun(cmd *cobra.Command, args []string) error {
 
 	checkLatestVersion()
 
+	err := checkCompatibility(configuredKubernetesVersion())
+	if err != nil {
+		return err
+	}
+
 	if askForUpgrade {
 		fmt.Println("Upgrading gokube dependencies...")
-		err := upgradeDependencies()
+		err = upgradeDependencies()
 		if err != nil {
 			return err
 		}
@@ -96,39 +148,5 @@ func startRun(cmd *cobra.Command, args []string) error {
 		}
 	}
 	// Start minikube
-	err := start()
-	if err != nil {
-		return err
-	}
-
-	// Add swap to Minikube VM
-	if enableSwap {
-        fmt.Println("Enabling swap drive in minikube VM...")
-        err = addSwapToMinikubeDuringStart()
-        if err != nil {
-    	    fmt.Printf("Warning: cannot enable swap drive in minikube VM - start: %s\n", err)
-        }
-    }
-
-	return nil
+	return start()
 }
-
-func addSwapToMinikubeDuringStart() error {
-
-	// Add swap file commands
-	swapCmds := []string{
-		"sudo swapon /dev/sdb",
-		"echo '/dev/sdb none swap defaults 0 0' | sudo tee -a /etc/fstab",
-	}
-
-	// Execute each command
-	for _, cmd := range swapCmds {
-		sshCmd := exec.Command("minikube", "ssh", cmd)
-		err := sshCmd.Run()
-		if err != nil {
-			return fmt.Errorf("error running command '%s': %w", cmd, err)
-		}
-	}
-
-	return nil
-}
\ No newline at end of file
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"github.com/gemalto/gokube/pkg/gokube"
 	"github.com/gemalto/gokube/pkg/minikube"
+	"github.com/gemalto/gokube/pkg/portforward"
 	"github.com/gemalto/gokube/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -47,9 +48,13 @@ func stopRun(cmd *cobra.Command, args []string) error {
 
 	checkLatestVersion()
 
-	if !quiet {
+	if !quiet && !dryRun {
 		gokube.ConfirmStopCommandExecution()
 	}
+	err := portforward.StopDaemon()
+	if err != nil {
+		fmt.Printf("Warning: cannot stop background port-forwards: %s\n", err)
+	}
 	fmt.Println("Stopping minikube VM...")
 	return minikube.Stop()
 }
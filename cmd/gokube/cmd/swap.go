@@ -0,0 +1,262 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/gemalto/gokube/pkg/minikube"
+	"github.com/gemalto/gokube/pkg/virtualbox"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var swapSize int16
+
+// swapCmd represents the swap command
+var swapCmd = &cobra.Command{
+	Use:   "swap",
+	Short: "Manages minikube VM swap. This command enables, disables, resizes or shows the swap drive of the minikube VM",
+	Long:  "Manages minikube VM swap. This command enables, disables, resizes or shows the swap drive of the minikube VM",
+}
+
+var swapEnableCmd = &cobra.Command{
+	Use:          "enable",
+	Short:        "Creates & attaches a swap drive to the minikube VM and enables it",
+	Long:         "Creates & attaches a swap drive to the minikube VM and enables it",
+	RunE:         swapEnableRun,
+	SilenceUsage: true,
+}
+
+var swapDisableCmd = &cobra.Command{
+	Use:          "disable",
+	Short:        "Disables the swap drive and removes it from the minikube VM",
+	Long:         "Disables the swap drive and removes it from the minikube VM",
+	RunE:         swapDisableRun,
+	SilenceUsage: true,
+}
+
+var swapResizeCmd = &cobra.Command{
+	Use:          "resize",
+	Short:        "Recreates the swap drive of the minikube VM with a new size",
+	Long:         "Recreates the swap drive of the minikube VM with a new size",
+	RunE:         swapResizeRun,
+	SilenceUsage: true,
+}
+
+var swapStatusCmd = &cobra.Command{
+	Use:          "status",
+	Short:        "Shows the swap configuration and status of the minikube VM",
+	Long:         "Shows the swap configuration and status of the minikube VM",
+	RunE:         swapStatusRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	swapEnableCmd.Flags().Int16VarP(&swapSize, "size", "s", 0, "Amount of SWAP allocated to the minikube VM in MB (configured one if not provided)")
+	swapResizeCmd.Flags().Int16VarP(&swapSize, "size", "s", 0, "New amount of SWAP allocated to the minikube VM in MB")
+	swapCmd.AddCommand(swapEnableCmd)
+	swapCmd.AddCommand(swapDisableCmd)
+	swapCmd.AddCommand(swapResizeCmd)
+	swapCmd.AddCommand(swapStatusCmd)
+	rootCmd.AddCommand(swapCmd)
+}
+
+// configuredSwap returns the persisted swap size, falling back on MINIKUBE_SWAP for configurations written by older gokube versions
+func configuredSwap() int16 {
+	if viper.IsSet("swap") {
+		return int16(viper.GetInt("swap"))
+	}
+	envSwap, _ := strconv.Atoi(os.Getenv("MINIKUBE_SWAP"))
+	return int16(envSwap)
+}
+
+// stopIfRunning stops minikube VM and returns true if it was running
+func stopIfRunning() (bool, error) {
+	running, err := virtualbox.IsRunning()
+	if err != nil {
+		return false, fmt.Errorf("cannot check if minikube VM is running: %w", err)
+	}
+	if running {
+		fmt.Println("Stopping minikube VM...")
+		err = minikube.Stop()
+		if err != nil {
+			return true, fmt.Errorf("cannot stop minikube VM: %w", err)
+		}
+	}
+	return running, nil
+}
+
+// setupSwap attaches a swap drive of the given size while minikube VM is stopped, then (re)starts VM which enables it
+func setupSwap(size int16) error {
+	running, err := stopIfRunning()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Creating & attaching %dMB swap drive to minikube VM...\n", size)
+	err = virtualbox.NewVBoxManager().AddSwapDisk(size)
+	if err != nil {
+		return fmt.Errorf("cannot create & attach swap drive to minikube VM: %w", err)
+	}
+	err = gokube.UpdateConfig(map[string]interface{}{"swap": size})
+	if err != nil {
+		return fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	if running {
+		return start()
+	}
+	return nil
+}
+
+// removeSwap disables swap inside minikube VM (if running) and detaches & deletes the swap drive while VM is stopped
+func removeSwap() (bool, error) {
+	running, err := virtualbox.IsRunning()
+	if err != nil {
+		return false, fmt.Errorf("cannot check if minikube VM is running: %w", err)
+	}
+	if running {
+		fmt.Println("Disabling swap drive in minikube VM...")
+		err = minikube.DisableSwap()
+		if err != nil {
+			return running, fmt.Errorf("cannot disable swap drive in minikube VM: %w", err)
+		}
+		fmt.Println("Stopping minikube VM...")
+		err = minikube.Stop()
+		if err != nil {
+			return running, fmt.Errorf("cannot stop minikube VM: %w", err)
+		}
+	}
+	fmt.Println("Detaching & deleting swap drive from minikube VM...")
+	err = virtualbox.NewVBoxManager().RemoveSwapDisk()
+	if err != nil {
+		return running, err
+	}
+	return running, nil
+}
+
+func swapEnableRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	if swapSize == 0 {
+		swapSize = configuredSwap()
+	}
+	if swapSize <= 0 {
+		return fmt.Errorf("no swap size configured, please provide one with --size")
+	}
+	// An existing swap drive is attached as is, so it must already have the requested size
+	if _, err := os.Stat(virtualbox.SwapDiskPath()); err == nil {
+		capacity, err := virtualbox.NewVBoxManager().GetDiskCapacity(virtualbox.SwapDiskPath())
+		if err != nil {
+			return fmt.Errorf("cannot get swap drive capacity: %w", err)
+		}
+		if capacity != int(swapSize) {
+			return fmt.Errorf("swap drive already exists with %dMB, please use 'gokube swap resize --size %d' to change its size", capacity, swapSize)
+		}
+	}
+	return setupSwap(swapSize)
+}
+
+func swapDisableRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	running, err := removeSwap()
+	if err != nil {
+		return err
+	}
+	err = gokube.UpdateConfig(map[string]interface{}{"swap": 0})
+	if err != nil {
+		return fmt.Errorf("cannot write gokube configuration: %w", err)
+	}
+	if running {
+		return start()
+	}
+	return nil
+}
+
+func swapResizeRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	if swapSize <= 0 {
+		return fmt.Errorf("please provide the new swap size with --size (use 'gokube swap disable' to remove swap)")
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	running, err := removeSwap()
+	if err != nil {
+		return err
+	}
+	err = setupSwap(swapSize)
+	if err != nil {
+		return err
+	}
+	if running {
+		return start()
+	}
+	return nil
+}
+
+func swapStatusRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	err := gokube.ReadConfig(verbose)
+	if err != nil {
+		return fmt.Errorf("cannot read gokube configuration file: %w", err)
+	}
+	fmt.Printf("Configured swap size: %dMB\n", configuredSwap())
+	disk, err := virtualbox.NewVBoxManager().GetSwapDisk()
+	if err != nil {
+		return fmt.Errorf("cannot get minikube VM disks: %w", err)
+	}
+	if len(disk) > 0 {
+		fmt.Printf("Swap drive: %s\n", disk)
+	} else {
+		fmt.Println("Swap drive: none")
+	}
+	running, err := virtualbox.IsRunning()
+	if err != nil {
+		return fmt.Errorf("cannot check if minikube VM is running: %w", err)
+	}
+	if !running {
+		fmt.Println("Swap status: unknown (minikube VM is not running)")
+		return nil
+	}
+	status, err := minikube.SwapStatus()
+	if err != nil {
+		return fmt.Errorf("cannot get swap status: %w", err)
+	}
+	if len(status) > 0 {
+		fmt.Printf("Swap status: active (%s)\n", status)
+	} else {
+		fmt.Println("Swap status: inactive")
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gemalto/gokube/pkg/helm"
+	"github.com/gemalto/gokube/pkg/tool"
+	"github.com/spf13/viper"
+)
+
+// registerConfiguredTools registers the user-defined tools of gokube configuration, which are then managed like built-in ones:
+//
+//	tools:
+//	- name: kubectx
+//	  url: https://github.com/ahmetb/kubectx/releases/download/v%s/kubectx_v%s_windows_x86_64.zip
+//	  version: 0.9.5
+func registerConfiguredTools() {
+	var descriptors []*tool.Descriptor
+	err := viper.UnmarshalKey("tools", &descriptors)
+	if err != nil {
+		fmt.Printf("Warning: invalid tools in gokube configuration: %s\n", err)
+		return
+	}
+	for _, descriptor := range descriptors {
+		if helm.IsBuiltinPlugin(descriptor.Name) {
+			fmt.Printf("Warning: ignoring tool %s from gokube configuration: tool name %s is reserved\n", descriptor.Name, descriptor.Name)
+			continue
+		}
+		err = tool.Register(descriptor)
+		if err != nil {
+			fmt.Printf("Warning: ignoring tool %s from gokube configuration: %s\n", descriptor.Name, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gemalto/gokube/pkg/trust"
+	"github.com/spf13/viper"
+)
+
+var signaturePolicy string
+
+// loadSignatures applies the signature policy, given by --signature-policy or by gokube configuration, and the configured
+// signatures overriding the ones declared by tools:
+//
+//	signature-policy: require
+//	signatures:
+//	  k9s:
+//	    type: cosign
+//	    key: k9s.pub
+//	  docker:
+//	    policy: off
+func loadSignatures() error {
+	policy := signaturePolicy
+	if len(policy) == 0 {
+		policy = viper.GetString("signature-policy")
+	}
+	if len(policy) == 0 {
+		policy = trust.POLICY_OFF
+	}
+	err := trust.SetPolicy(policy)
+	if err != nil {
+		return err
+	}
+	var signatures map[string]*trust.Signature
+	err = viper.UnmarshalKey("signatures", &signatures)
+	if err != nil {
+		return fmt.Errorf("invalid signatures in gokube configuration: %w", err)
+	}
+	trust.SetSignatures(signatures)
+	return nil
+}
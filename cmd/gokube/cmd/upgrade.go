@@ -0,0 +1,126 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gemalto/gokube/pkg/gokube"
+	"github.com/spf13/cobra"
+)
+
+var upgradeOnly []string
+var checkUpgrade bool
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:          "upgrade",
+	Short:        "Upgrades gokube dependencies. This command replaces the tools whose installed version differs from the configured one",
+	Long:         "Upgrades gokube dependencies (minikube, helm, docker, kubectl, stern, k9s and helm plugins) whose installed version differs from the configured one. Executables are only replaced once the new version has been successfully downloaded",
+	RunE:         upgradeRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	upgradeCmd.Flags().StringSliceVar(&upgradeOnly, "only", nil, "Comma-separated list of tools to upgrade (default all)")
+	upgradeCmd.Flags().BoolVarP(&checkUpgrade, "check", "", false, "Only display installed and target versions, without upgrading anything")
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+// selectTools returns the given tools restricted to the names of --only flag
+func selectTools(tools []*gokube.Tool, names []string) ([]*gokube.Tool, error) {
+	if len(names) == 0 {
+		return tools, nil
+	}
+	byName := map[string]*gokube.Tool{}
+	var known []string
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+		known = append(known, tool.Name)
+	}
+	selected := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("unknown tool %q, expecting one of %s", name, strings.Join(known, ", "))
+		}
+		selected[name] = true
+	}
+	var result []*gokube.Tool
+	for _, tool := range tools {
+		if selected[tool.Name] {
+			result = append(result, tool)
+		}
+	}
+	return result, nil
+}
+
+func upgradeRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+
+	checkLatestVersion()
+
+	err := checkCompatibility(configuredKubernetesVersion())
+	if err != nil {
+		return err
+	}
+
+	tools, err := selectTools(append(dependencies().Tools(), helmPlugins().Tools()...), upgradeOnly)
+	if err != nil {
+		return err
+	}
+
+	statuses := gokube.CheckTools(tools)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TOOL\tINSTALLED\tTARGET\tSTATUS")
+	var outdated []*gokube.Tool
+	for _, status := range statuses {
+		installed := status.Installed
+		if len(installed) == 0 {
+			installed = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Tool.Name, installed, status.Tool.Version, status.Status())
+		if !status.IsUpToDate() {
+			outdated = append(outdated, status.Tool)
+		}
+	}
+	_ = w.Flush()
+	for _, status := range statuses {
+		if status.Err != nil {
+			fmt.Printf("Warning: cannot detect installed %s version: %s\n", status.Tool.Name, status.Err)
+		}
+	}
+
+	if checkUpgrade {
+		return nil
+	}
+	if len(outdated) == 0 {
+		fmt.Println("All tools are up-to-date")
+		return nil
+	}
+	for _, tool := range outdated {
+		fmt.Printf("Upgrading %s to %s...\n", tool.Name, tool.Version)
+		err = tool.Upgrade()
+		if err != nil {
+			return fmt.Errorf("cannot download or upgrade %s: %w", tool.Name, err)
+		}
+	}
+	return nil
+}
@@ -16,15 +16,14 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/cvila84/go-latest"
-	"github.com/gemalto/gokube/pkg/docker"
+	"github.com/coreos/go-semver/semver"
 	"github.com/gemalto/gokube/pkg/helm"
-	"github.com/gemalto/gokube/pkg/kubectl"
 	"github.com/gemalto/gokube/pkg/minikube"
-	"github.com/gemalto/gokube/pkg/stern"
-	"github.com/gemalto/gokube/pkg/k9s"
+	"github.com/gemalto/gokube/pkg/selfupdate"
+	"github.com/gemalto/gokube/pkg/utils"
 	"github.com/spf13/cobra"
-	"strings"
+	"github.com/spf13/viper"
+	"os"
 	"time"
 )
 
@@ -34,20 +33,17 @@ const (
 
 var gokubeVersion string
 
-var (
-	githubTag = &latest.GithubTag{
-		Owner:      "ThalesGroup",
-		Repository: "gokube",
-		TagFilterFunc: func(release string) bool {
-			if strings.ContainsRune(release, '-') {
-				return false
-			} else {
-				return true
-			}
-		},
-	}
+const (
+	DEFAULT_UPDATE_CHECK_INTERVAL = 24 * time.Hour
+	updateCheckTimeout            = 5 * time.Second
 )
 
+// cachedUpdateCheck is the result of the last update check, displayed once the command is done
+var cachedUpdateCheck *selfupdate.CheckResult
+
+// updateCheck receives the result of the update check refreshing the cache, if any
+var updateCheck chan *selfupdate.CheckResult
+
 var allVersions bool
 
 // versionCmd represents the version command
@@ -59,36 +55,84 @@ var versionCmd = &cobra.Command{
 	SilenceUsage: true,
 }
 
+// checkLatestVersion loads the cached result of the last update check and, if it is older than the configured interval,
+// refreshes it in background for next commands (the refresh is abandoned if the command ends first, so that it never
+// delays it)
 func checkLatestVersion() {
-	res, _ := latest.Check(githubTag, GOKUBE_VERSION, 5*time.Second)
-	if res == nil {
-		fmt.Println("Warning: cannot find gokube latest release, please check your connection")
+	if len(os.Getenv("GOKUBE_NO_UPDATE_CHECK")) > 0 || (viper.IsSet("update-check") && !viper.GetBool("update-check")) {
+		return
+	}
+	channel := utils.GetValueFromEnv("GOKUBE_UPDATE_CHANNEL", viper.GetString("update-channel"))
+	if len(channel) == 0 {
+		channel = selfupdate.CHANNEL_STABLE
+	}
+	interval := DEFAULT_UPDATE_CHECK_INTERVAL
+	if viper.IsSet("update-check-interval") {
+		interval = viper.GetDuration("update-check-interval")
 	}
-	if res != nil {
-		if res.Outdated {
-			fmt.Printf("Warning: this version of gokube is outdated, please download the newest one on https://github.com/ThalesGroup/gokube/releases/tag/v%s\n", res.Current)
-		} else if res.New {
-			fmt.Println("Warning: this version of gokube has not yet been published, use it at your own risk !")
+	cachedUpdateCheck = selfupdate.ReadCheckCache(channel)
+	if cachedUpdateCheck != nil && time.Since(cachedUpdateCheck.CheckedAt) <= interval {
+		return
+	}
+	updateCheck = make(chan *selfupdate.CheckResult, 1)
+	go func() {
+		result, err := selfupdate.Check(releasesAPIURL(), channel, updateCheckTimeout)
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: cannot find gokube latest release: %s\n", err)
+			}
+			updateCheck <- nil
+			return
 		}
+		_ = selfupdate.WriteCheckCache(result)
+		updateCheck <- result
+	}()
+}
+
+// printLatestVersion displays the result of the update check started by checkLatestVersion: the refreshed one if it
+// is received within the given duration (zero meaning it is not waited for), the cached one otherwise
+func printLatestVersion(wait time.Duration) {
+	result := cachedUpdateCheck
+	if updateCheck != nil {
+		var refreshed *selfupdate.CheckResult
+		select {
+		case refreshed = <-updateCheck:
+		default:
+			if wait > 0 {
+				select {
+				case refreshed = <-updateCheck:
+				case <-time.After(wait):
+				}
+			}
+		}
+		if refreshed != nil {
+			result = refreshed
+		}
+	}
+	cachedUpdateCheck, updateCheck = nil, nil
+	if result == nil {
+		return
+	}
+	latestVersion, err := semver.NewVersion(result.Latest)
+	if err != nil {
+		return
+	}
+	currentVersion := semver.New(GOKUBE_VERSION)
+	if currentVersion.LessThan(*latestVersion) {
+		fmt.Printf("Warning: this version of gokube is outdated, please run 'gokube self-update' or download the newest one on https://github.com/ThalesGroup/gokube/releases/tag/v%s\n", result.Latest)
+	} else if latestVersion.LessThan(*currentVersion) && result.Channel == selfupdate.CHANNEL_STABLE {
+		fmt.Println("Warning: this version of gokube has not yet been published, use it at your own risk !")
 	}
 }
 
-func init() {
-	versionCmd.Flags().BoolVarP(&allVersions, "all", "a", false, "Also display all third parties versions")
-	rootCmd.AddCommand(versionCmd)
+// releasesAPIURL returns the URL of gokube releases API (which can be changed for GitHub Enterprise or mirrors)
+func releasesAPIURL() string {
+	return utils.GetValueFromEnv("GOKUBE_RELEASES_API_URL", selfupdate.DEFAULT_API_URL)
 }
 
-func getDockerVersion() error {
-	dockerC := make(chan error, 1)
-	go func() {
-		dockerC <- docker.Version()
-	}()
-	select {
-	case <-time.After(3 * time.Second):
-		return nil
-	case result := <-dockerC:
-		return result
-	}
+func init() {
+	versionCmd.Flags().BoolVarP(&allVersions, "all", "a", false, "Also display all third parties versions (including user-defined tools)")
+	rootCmd.AddCommand(versionCmd)
 }
 
 func versionRun(cmd *cobra.Command, args []string) error {
@@ -99,14 +143,13 @@ func versionRun(cmd *cobra.Command, args []string) error {
 	checkLatestVersion()
 
 	fmt.Println("gokube version: v" + GOKUBE_VERSION)
+	printLatestVersion(updateCheckTimeout)
 	if allVersions {
 		_ = minikube.Version()
-		_ = helm.Version()
+		for _, descriptor := range dependencies().Descriptors() {
+			_ = descriptor.PrintVersion()
+		}
 		_ = helm.PluginsVersion()
-		_ = stern.Version()
-		_ = k9s.Version()
-		_ = getDockerVersion()
-		_ = kubectl.Version()
 	}
 	return nil
 }
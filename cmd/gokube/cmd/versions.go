@@ -0,0 +1,78 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/gemalto/gokube/pkg/compat"
+	"github.com/spf13/cobra"
+)
+
+var checkVersions bool
+
+// versionsCmd represents the versions command
+var versionsCmd = &cobra.Command{
+	Use:          "versions",
+	Short:        "Shows configured versions of gokube dependencies. This command also checks their compatibility with --check",
+	Long:         "Shows configured versions of gokube dependencies. This command also checks their compatibility with --check",
+	RunE:         versionsRun,
+	SilenceUsage: true,
+}
+
+func init() {
+	versionsCmd.Flags().BoolVarP(&checkVersions, "check", "", false, "Check configured versions against gokube compatibility matrix")
+	rootCmd.AddCommand(versionsCmd)
+}
+
+func versionsRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmd.Usage()
+	}
+	versions := configuredVersions(configuredKubernetesVersion())
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "COMPONENT\tVERSION")
+	_, _ = fmt.Fprintf(w, "kubernetes\t%s\n", versions.Kubernetes)
+	_, _ = fmt.Fprintf(w, "minikube\t%s\n", versions.Minikube)
+	_, _ = fmt.Fprintf(w, "kubectl\t%s\n", versions.Kubectl)
+	_, _ = fmt.Fprintf(w, "helm\t%s\n", versions.Helm)
+	for _, plugin := range []string{"helm-spray", "helm-push", "helm-image"} {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", plugin, versions.HelmPlugins[plugin])
+	}
+	_ = w.Flush()
+	if !checkVersions {
+		return nil
+	}
+
+	fmt.Println()
+	results, err := compat.Check(versions)
+	if err != nil {
+		return err
+	}
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RULE\tCOMPONENT\tVERSION\tCONSTRAINT\tSTATUS")
+	for _, r := range results {
+		status := "ok"
+		if !r.OK {
+			status = r.Severity
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Rule, r.Component, r.Version, r.Constraint, status)
+	}
+	_ = w.Flush()
+	_, err = compat.Validate(versions)
+	return err
+}
@@ -0,0 +1,156 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+const (
+	bundleFileName      = "ca-bundle.pem"
+	minikubeCertsPrefix = "gokube-ca-"
+)
+
+func bundlePath() string {
+	return filepath.Join(utils.GetUserHome(), ".gokube", "certs", bundleFileName)
+}
+
+func minikubeCertsDir() string {
+	return filepath.Join(utils.GetUserHome(), ".minikube", "certs")
+}
+
+// Setup validates the given PEM files and writes their certificates into the gokube CA bundle (removed when no file is given)
+func Setup(files []string) error {
+	var bundle bytes.Buffer
+	for _, file := range files {
+		certs, err := readCertificates(file)
+		if err != nil {
+			return err
+		}
+		for _, cert := range certs {
+			_ = pem.Encode(&bundle, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		}
+	}
+	if runner.DryRun("write CA bundle %s with certificates of %v", bundlePath(), files) {
+		return nil
+	}
+	if bundle.Len() == 0 {
+		err := os.Remove(bundlePath())
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove CA bundle: %w", err)
+		}
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(bundlePath()), 0755)
+	if err != nil {
+		return fmt.Errorf("cannot create CA bundle directory: %w", err)
+	}
+	err = os.WriteFile(bundlePath(), bundle.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("cannot write CA bundle: %w", err)
+	}
+	return nil
+}
+
+// BundleFile returns the path of the gokube CA bundle, or an empty string when no CA certificate is configured
+func BundleFile() string {
+	if _, err := os.Stat(bundlePath()); err != nil {
+		return ""
+	}
+	return bundlePath()
+}
+
+// CertPool returns the system CA certificates extended with the gokube CA bundle ones
+func CertPool() (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if len(BundleFile()) == 0 {
+		return pool, nil
+	}
+	certs, err := readCertificates(bundlePath())
+	if err != nil {
+		return nil, err
+	}
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// InstallInMinikube copies the gokube CA bundle certificates into ~/.minikube/certs (one file per certificate)
+// so that minikube installs them into the VM on start
+func InstallInMinikube() error {
+	if runner.DryRun("copy CA bundle certificates into %s", minikubeCertsDir()) {
+		return nil
+	}
+	previous, _ := filepath.Glob(filepath.Join(minikubeCertsDir(), minikubeCertsPrefix+"*.pem"))
+	for _, file := range previous {
+		err := os.Remove(file)
+		if err != nil {
+			return fmt.Errorf("cannot remove previous CA certificate %s: %w", file, err)
+		}
+	}
+	if len(BundleFile()) == 0 {
+		return nil
+	}
+	certs, err := readCertificates(bundlePath())
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(minikubeCertsDir(), 0755)
+	if err != nil {
+		return fmt.Errorf("cannot create minikube certificates directory: %w", err)
+	}
+	for i, cert := range certs {
+		file := filepath.Join(minikubeCertsDir(), fmt.Sprintf("%s%d.pem", minikubeCertsPrefix, i+1))
+		err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0644)
+		if err != nil {
+			return fmt.Errorf("cannot write CA certificate %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func readCertificates(file string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CA certificate file: %w", err)
+	}
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate in %s: %w", file, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificate found in %s", file)
+	}
+	return certs, nil
+}
@@ -0,0 +1,202 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compat
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+//go:embed matrix.yaml
+var matrixData []byte
+
+type rangeRule struct {
+	Versions   string `json:"versions"`
+	Kubernetes string `json:"kubernetes"`
+	Helm       string `json:"helm"`
+}
+
+type matrix struct {
+	Minimum     map[string]string      `json:"minimum"`
+	Minikube    []rangeRule            `json:"minikube"`
+	KubectlSkew uint64                 `json:"kubectlSkew"`
+	Helm        []rangeRule            `json:"helm"`
+	HelmPlugins map[string][]rangeRule `json:"helmPlugins"`
+}
+
+// Versions are the versions of gokube components to check
+type Versions struct {
+	Kubernetes  string
+	Minikube    string
+	Kubectl     string
+	Helm        string
+	HelmPlugins map[string]string
+}
+
+// Result is the outcome of a compatibility rule
+type Result struct {
+	Rule       string
+	Component  string
+	Version    string
+	Constraint string
+	Severity   string
+	OK         bool
+}
+
+func (r Result) String() string {
+	if len(r.Constraint) == 0 {
+		return fmt.Sprintf("%s: %s %s is not a valid version", r.Rule, r.Component, r.Version)
+	}
+	return fmt.Sprintf("%s: %s %s does not match %s", r.Rule, r.Component, r.Version, r.Constraint)
+}
+
+// IncompatibilityError lists the compatibility rules with error severity violated by a set of versions
+type IncompatibilityError struct {
+	Violations []Result
+}
+
+func (e *IncompatibilityError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.String())
+	}
+	return "incompatible versions: " + strings.Join(messages, "; ")
+}
+
+// Validate checks the versions against the compatibility matrix and returns the violated rules with warning severity,
+// and an IncompatibilityError if rules with error severity are violated
+func Validate(versions Versions) ([]Result, error) {
+	results, err := Check(versions)
+	if err != nil {
+		return nil, err
+	}
+	var warnings, violations []Result
+	for _, r := range results {
+		switch {
+		case r.OK:
+		case r.Severity == SeverityWarning:
+			warnings = append(warnings, r)
+		default:
+			violations = append(violations, r)
+		}
+	}
+	if len(violations) > 0 {
+		return warnings, &IncompatibilityError{Violations: violations}
+	}
+	return warnings, nil
+}
+
+// Check returns the result of each compatibility rule applying to the versions (empty versions are not checked)
+func Check(versions Versions) ([]Result, error) {
+	var m matrix
+	err := yaml.Unmarshal(matrixData, &m)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse compatibility matrix: %w", err)
+	}
+	components := map[string]string{
+		"kubernetes": versions.Kubernetes,
+		"minikube":   versions.Minikube,
+		"kubectl":    versions.Kubectl,
+		"helm":       versions.Helm,
+	}
+	for name, version := range versions.HelmPlugins {
+		components[name] = version
+	}
+
+	var results []Result
+	parsed := map[string]*semver.Version{}
+	for _, name := range sortedKeys(components) {
+		if len(components[name]) == 0 {
+			continue
+		}
+		v, err := semver.NewVersion(components[name])
+		if err != nil {
+			results = append(results, Result{Rule: "version format", Component: name, Version: components[name], Severity: SeverityError})
+			continue
+		}
+		parsed[name] = v
+	}
+
+	for _, name := range sortedKeys(m.Minimum) {
+		if v, ok := parsed[name]; ok {
+			results = append(results, check("minimum version", name, v, m.Minimum[name], SeverityError))
+		}
+	}
+	if k8s, ok := parsed["kubernetes"]; ok {
+		if v, ok := parsed["minikube"]; ok {
+			results = append(results, checkRange("minikube supported kubernetes", "kubernetes", k8s, v, m.Minikube, func(r rangeRule) string { return r.Kubernetes }, SeverityError)...)
+		}
+		if v, ok := parsed["kubectl"]; ok {
+			results = append(results, checkSkew(v, k8s, m.KubectlSkew))
+		}
+		if v, ok := parsed["helm"]; ok {
+			results = append(results, checkRange("helm supported kubernetes", "kubernetes", k8s, v, m.Helm, func(r rangeRule) string { return r.Kubernetes }, SeverityWarning)...)
+		}
+	}
+	if helm, ok := parsed["helm"]; ok {
+		for _, plugin := range sortedKeys(m.HelmPlugins) {
+			if v, ok := parsed[plugin]; ok {
+				results = append(results, checkRange(plugin+" supported helm", "helm", helm, v, m.HelmPlugins[plugin], func(r rangeRule) string { return r.Helm }, SeverityError)...)
+			}
+		}
+	}
+	return results, nil
+}
+
+func check(rule string, component string, version *semver.Version, constraint string, severity string) Result {
+	result := Result{Rule: rule, Component: component, Version: version.Original(), Constraint: constraint, Severity: severity}
+	c, err := semver.NewConstraint(constraint)
+	result.OK = err == nil && c.Check(version)
+	return result
+}
+
+// checkRange checks the target version against the constraint of the first rule matching the owner version
+func checkRange(rule string, target string, targetVersion *semver.Version, ownerVersion *semver.Version, rules []rangeRule, constraint func(rangeRule) string, severity string) []Result {
+	for _, r := range rules {
+		c, err := semver.NewConstraint(r.Versions)
+		if err == nil && c.Check(ownerVersion) {
+			return []Result{check(fmt.Sprintf("%s (%s)", rule, ownerVersion.Original()), target, targetVersion, constraint(r), severity)}
+		}
+	}
+	return nil
+}
+
+func checkSkew(kubectl *semver.Version, kubernetes *semver.Version, skew uint64) Result {
+	low, high := uint64(0), kubernetes.Minor()+skew
+	if kubernetes.Minor() > skew {
+		low = kubernetes.Minor() - skew
+	}
+	constraint := fmt.Sprintf(">= %d.%d.0-0, < %d.%d.0-0", kubernetes.Major(), low, kubernetes.Major(), high+1)
+	return check(fmt.Sprintf("kubectl skew with kubernetes (%s)", kubernetes.Original()), "kubectl", kubectl, constraint, SeverityWarning)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,187 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"sigs.k8s.io/yaml"
+)
+
+// supported are versions supported together by the embedded matrix
+func supported() Versions {
+	return Versions{
+		Kubernetes: "v1.35.0",
+		Minikube:   "v1.38.0",
+		Kubectl:    "v1.35.0",
+		Helm:       "v3.20.0",
+		HelmPlugins: map[string]string{
+			"helm-spray": "v4.0.13",
+			"helm-push":  "0.10.4",
+			"helm-image": "v1.1.0",
+		},
+	}
+}
+
+func TestValidateSupported(t *testing.T) {
+	results, err := Check(supported())
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatalf("Check() returned no result")
+	}
+	for _, r := range results {
+		if !r.OK {
+			t.Errorf("Check() %s, want supported", r)
+		}
+	}
+	warnings, err := Validate(supported())
+	if err != nil || len(warnings) > 0 {
+		t.Errorf("Validate() = %v, %v, want no warning nor error", warnings, err)
+	}
+}
+
+func TestValidateKubectlSkew(t *testing.T) {
+	tests := []struct {
+		kubectl     string
+		wantWarning bool
+	}{
+		{"v1.34.2", false},
+		{"v1.35.1", false},
+		{"v1.36.0-rc.0", false},
+		{"v1.33.9", true},
+		{"v1.37.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kubectl, func(t *testing.T) {
+			versions := supported()
+			versions.Kubectl = tt.kubectl
+			warnings, err := Validate(versions)
+			if err != nil {
+				t.Fatalf("Validate() error = %v, kubectl skew must only be a warning", err)
+			}
+			if got := len(warnings) > 0; got != tt.wantWarning {
+				t.Errorf("Validate() warnings = %v, want warning %v", warnings, tt.wantWarning)
+			}
+			for _, w := range warnings {
+				if w.Component != "kubectl" || w.Severity != SeverityWarning {
+					t.Errorf("Validate() warning = %+v, want kubectl skew warning", w)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateUnsupported(t *testing.T) {
+	versions := supported()
+	versions.Minikube = "v1.37.0"
+	versions.Helm = "v3.19.0"
+	warnings, err := Validate(versions)
+	var incompatible *IncompatibilityError
+	if !errors.As(err, &incompatible) {
+		t.Fatalf("Validate() error = %v, want IncompatibilityError", err)
+	}
+	if len(incompatible.Violations) != 1 || incompatible.Violations[0].Rule != "minikube supported kubernetes (v1.37.0)" {
+		t.Errorf("Validate() violations = %v, want minikube v1.37.0 not supporting kubernetes v1.35.0", incompatible.Violations)
+	}
+	if !strings.Contains(err.Error(), "kubernetes v1.35.0 does not match >= 1.20.0-0, < 1.35.0-0") {
+		t.Errorf("Validate() error = %v, want the violated constraint", err)
+	}
+	// Helm supported kubernetes versions are only a warning
+	if len(warnings) != 1 || warnings[0].Rule != "helm supported kubernetes (v3.19.0)" {
+		t.Errorf("Validate() warnings = %v, want helm v3.19.0 not supporting kubernetes v1.35.0", warnings)
+	}
+}
+
+func TestValidateBelowMinimum(t *testing.T) {
+	versions := supported()
+	versions.HelmPlugins["helm-push"] = "0.10.0"
+	_, err := Validate(versions)
+	var incompatible *IncompatibilityError
+	if !errors.As(err, &incompatible) {
+		t.Fatalf("Validate() error = %v, want IncompatibilityError", err)
+	}
+	if len(incompatible.Violations) != 1 || incompatible.Violations[0].Rule != "minimum version" || incompatible.Violations[0].Component != "helm-push" {
+		t.Errorf("Validate() violations = %v, want helm-push minimum version", incompatible.Violations)
+	}
+}
+
+func TestValidateUnknownVersion(t *testing.T) {
+	versions := supported()
+	versions.Minikube = "latest"
+	_, err := Validate(versions)
+	var incompatible *IncompatibilityError
+	if !errors.As(err, &incompatible) {
+		t.Fatalf("Validate() error = %v, want IncompatibilityError", err)
+	}
+	want := "version format: minikube latest is not a valid version"
+	if len(incompatible.Violations) != 1 || incompatible.Violations[0].String() != want {
+		t.Errorf("Validate() violations = %v, want %s", incompatible.Violations, want)
+	}
+}
+
+func TestValidateEmptyVersions(t *testing.T) {
+	results, err := Check(Versions{})
+	if err != nil || len(results) != 0 {
+		t.Errorf("Check() = %v, %v, want no result for empty versions", results, err)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	var m matrix
+	err := yaml.Unmarshal(matrixData, &m)
+	if err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+	if m.KubectlSkew != 1 {
+		t.Errorf("kubectlSkew = %d, want 1", m.KubectlSkew)
+	}
+	for _, name := range []string{"kubernetes", "minikube", "helm"} {
+		if _, ok := m.Minimum[name]; !ok {
+			t.Errorf("minimum %s version is missing", name)
+		}
+	}
+	if len(m.Minikube) == 0 || len(m.Helm) == 0 || len(m.HelmPlugins) == 0 {
+		t.Errorf("matrix = %+v, want minikube, helm and helm plugins rules", m)
+	}
+	var constraints []string
+	for _, c := range m.Minimum {
+		constraints = append(constraints, c)
+	}
+	rules := [][]rangeRule{m.Minikube, m.Helm}
+	for _, pluginRules := range m.HelmPlugins {
+		rules = append(rules, pluginRules)
+	}
+	for _, rules := range rules {
+		for _, r := range rules {
+			constraints = append(constraints, r.Versions)
+			if len(r.Kubernetes) > 0 {
+				constraints = append(constraints, r.Kubernetes)
+			}
+			if len(r.Helm) > 0 {
+				constraints = append(constraints, r.Helm)
+			}
+		}
+	}
+	for _, c := range constraints {
+		if _, err := semver.NewConstraint(c); err != nil {
+			t.Errorf("constraint %q error = %v", c, err)
+		}
+	}
+}
@@ -15,47 +15,15 @@ limitations under the License.
 package docker
 
 import (
+	"encoding/json"
 	"fmt"
-	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/runner"
 	"github.com/gemalto/gokube/pkg/utils"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
-const (
-	DEFAULT_URL           = "https://download.docker.com/win/static/stable/x86_64/docker-%s.zip"
-	LOCAL_EXECUTABLE_NAME = "docker.exe"
-)
-
-// Version ...
-func Version() error {
-	fmt.Println("docker version:")
-	cmd := exec.Command("docker", "version")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-// DownloadExecutable ...
-func DownloadExecutable(dockerURL string, dockerVersion string) error {
-	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
-	if _, err := os.Stat(localFile); os.IsNotExist(err) {
-		fileMap := &download.FileMap{Src: "docker" + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME, Dst: LOCAL_EXECUTABLE_NAME}
-		_, err = download.FromUrl(dockerURL, dockerVersion, "docker", []*download.FileMap{fileMap}, filepath.Dir(localFile))
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// DeleteExecutable ...
-func DeleteExecutable() error {
-	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
-	return os.RemoveAll(localFile)
-}
-
 // InitWorkingDirectory ...
 func InitWorkingDirectory() error {
 	var dockerHome = utils.GetUserHome() + string(os.PathSeparator) + ".docker"
@@ -64,6 +32,9 @@ func InitWorkingDirectory() error {
 	if err == nil {
 		return nil
 	}
+	if runner.DryRun("create %s", configJsonPath) {
+		return nil
+	}
 	err = utils.CreateDirs(dockerHome)
 	if err != nil {
 		return err
@@ -81,8 +52,69 @@ func InitWorkingDirectory() error {
 	return nil
 }
 
-// DeleteWorkingDirectory ...
-func DeleteWorkingDirectory() error {
-	// Delete and recreate will not work if .docker is a symlink !
-	return utils.CleanDir(utils.GetUserHome() + string(os.PathSeparator) + ".docker")
+// GetWorkingDirectory ...
+func GetWorkingDirectory() string {
+	return utils.GetUserHome() + string(os.PathSeparator) + ".docker"
+}
+
+// DeleteMinikubeContexts removes minikube related docker contexts, keeping other contexts and credentials as they are
+func DeleteMinikubeContexts() error {
+	dockerHome := GetWorkingDirectory()
+	metaDir := filepath.Join(dockerHome, "contexts", "meta")
+	entries, err := os.ReadDir(metaDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	removed := map[string]bool{}
+	for _, e := range entries {
+		meta, err := os.ReadFile(filepath.Join(metaDir, e.Name(), "meta.json"))
+		if err != nil {
+			continue
+		}
+		var context struct {
+			Name string
+		}
+		if json.Unmarshal(meta, &context) != nil || !strings.Contains(context.Name, "minikube") {
+			continue
+		}
+		err = utils.RemoveAll(filepath.Join(metaDir, e.Name()))
+		if err != nil {
+			return err
+		}
+		err = utils.RemoveAll(filepath.Join(dockerHome, "contexts", "tls", e.Name()))
+		if err != nil {
+			return err
+		}
+		removed[context.Name] = true
+	}
+	return resetCurrentContext(filepath.Join(dockerHome, "config.json"), removed)
+}
+
+// resetCurrentContext removes currentContext from docker configuration if it references a removed context
+func resetCurrentContext(configJsonPath string, removed map[string]bool) error {
+	content, err := os.ReadFile(configJsonPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var config map[string]interface{}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %w", configJsonPath, err)
+	}
+	currentContext, ok := config["currentContext"].(string)
+	if !ok || !removed[currentContext] {
+		return nil
+	}
+	if runner.DryRun("reset docker current context %s in %s", currentContext, configJsonPath) {
+		return nil
+	}
+	delete(config, "currentContext")
+	content, err = json.MarshalIndent(config, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(configJsonPath, content, 0600)
 }
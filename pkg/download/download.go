@@ -15,14 +15,19 @@ limitations under the License.
 package download
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/gemalto/gokube/pkg/extract"
+	"github.com/gemalto/gokube/pkg/httpclient"
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/trust"
 	"github.com/gemalto/gokube/pkg/utils"
 	"gopkg.in/cheggaaa/pb.v2"
 )
@@ -32,20 +37,84 @@ type FileMap struct {
 	Dst string
 }
 
-func fromUrl(url string, name string, dir string, fileName string) (int64, error) {
-	file, err := os.Create(dir + string(os.PathSeparator) + fileName)
-	defer utils.CloseFile(file)
+// lockedChecksums maps the URLs allowed to be downloaded to their expected SHA-256, nil meaning any URL is allowed
+var lockedChecksums map[string]string
+
+// SetLockedChecksums restricts downloads to the given URLs, which must match the associated SHA-256 (nil removes the restriction)
+func SetLockedChecksums(checksums map[string]string) {
+	lockedChecksums = checksums
+}
+
+// ExpectedChecksum returns the locked SHA-256 of the given URL, if any, or an error if downloads are locked and the URL is not
+func ExpectedChecksum(url string) (string, error) {
+	if lockedChecksums == nil {
+		return "", nil
+	}
+	checksum, ok := lockedChecksums[url]
+	if !ok {
+		return "", fmt.Errorf("%s is not locked", url)
+	}
+	return checksum, nil
+}
+
+// URL returns the download URL for the given URL template and version
+func URL(urlTpl string, version string) string {
+	return strings.Replace(urlTpl, "%s", version, -1)
+}
+
+// Checksum downloads the given URL and returns the SHA-256 of its content
+func Checksum(url string) (string, error) {
+	response, err := httpclient.New().Get(url)
 	if err != nil {
-		return -1, err
+		return "", err
+	}
+	defer utils.Close(response.Body)
+	if response.StatusCode != 200 {
+		return "", fmt.Errorf("cannot download %s: %s", url, response.Status)
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(hash, response.Body); err != nil {
+		return "", fmt.Errorf("cannot download %s: %w", url, err)
 	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
 
-	response, err := http.Get(url)
+// PublishedChecksum returns the SHA-256 published alongside the given URL in a .sha256 file
+func PublishedChecksum(url string) (string, error) {
+	response, err := httpclient.New().Get(url + ".sha256")
+	if err != nil {
+		return "", err
+	}
 	defer utils.Close(response.Body)
+	if response.StatusCode != 200 {
+		return "", fmt.Errorf("cannot download %s.sha256: %s", url, response.Status)
+	}
+	content, err := io.ReadAll(response.Body)
 	if err != nil {
-		return -1, err
+		return "", fmt.Errorf("cannot download %s.sha256: %w", url, err)
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%s.sha256 is empty", url)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// fromUrl downloads the given URL into dir, checking its locked SHA-256 if any, and returns its size and SHA-256
+func fromUrl(url string, name string, dir string, fileName string) (int64, string, error) {
+	file, err := os.Create(dir + string(os.PathSeparator) + fileName)
+	if err != nil {
+		return -1, "", err
+	}
+	defer utils.CloseFile(file)
+
+	response, err := httpclient.New().Get(url)
+	if err != nil {
+		return -1, "", err
 	}
+	defer utils.Close(response.Body)
 	if response.StatusCode != 200 {
-		return -1, fmt.Errorf("cannot download %s", url)
+		return -1, "", fmt.Errorf("cannot download %s", url)
 	}
 
 	count := int(response.ContentLength)
@@ -57,9 +126,14 @@ func fromUrl(url string, name string, dir string, fileName string) (int64, error
 	// create proxy reader
 	reader := bar.NewProxyReader(response.Body)
 	defer utils.ClosePBReader(reader)
-	n, err := io.Copy(file, reader)
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(file, hash), reader)
 	if err != nil {
-		return -1, err
+		return -1, "", err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if expected, ok := lockedChecksums[url]; ok && !strings.EqualFold(expected, checksum) {
+		return -1, "", fmt.Errorf("SHA-256 of %s does not match locked one %s", url, expected)
 	}
 
 	var fi os.FileInfo
@@ -68,45 +142,66 @@ func fromUrl(url string, name string, dir string, fileName string) (int64, error
 		bar.Increment()
 		time.Sleep(time.Millisecond)
 	}
+	return n, checksum, nil
+}
 
-	tokens := strings.Split(fileName, ".")
-	fileType := tokens[len(tokens)-1]
-	switch fileType {
-	case "zip":
-		if err = utils.Unzip(file.Name(), dir); err != nil {
-			return -1, err
-		}
-	case "tgz":
-		if err = utils.Untar(file.Name(), dir); err != nil {
-			return -1, err
-		}
-	case "gz":
-		if err = utils.Untar(file.Name(), dir); err != nil {
-			return -1, err
-		}
+// ToFile downloads the given URL into dir and returns the downloaded file, once its SHA-256 is checked against the locked one
+// and the given expected one (if any)
+func ToFile(url string, name string, dir string, expected string) (string, error) {
+	if _, ok := lockedChecksums[url]; lockedChecksums != nil && !ok {
+		return "", fmt.Errorf("%s is not locked", url)
 	}
-	return n, nil
+	tokens := strings.Split(url, "/")
+	fileName := tokens[len(tokens)-1]
+	_, checksum, err := fromUrl(url, name, dir, fileName)
+	if err != nil {
+		return "", err
+	}
+	if len(expected) > 0 && !strings.EqualFold(expected, checksum) {
+		return "", fmt.Errorf("SHA-256 of %s is %s instead of %s", url, checksum, expected)
+	}
+	return filepath.Join(dir, fileName), nil
 }
 
-// FromUrl ...
-func FromUrl(urlTpl string, version string, name string, fileMaps []*FileMap, dst string) (int64, error) {
+// FromUrl downloads the given version and extracts the mapped files into dst, once its signature is verified according to the signature policy
+func FromUrl(urlTpl string, version string, name string, fileMaps []*FileMap, dst string, sig *trust.Signature) (int64, error) {
 
-	url := strings.Replace(urlTpl, "%s", version, -1)
-	if version[0:1] == "v" {
-		name = name + " " + version
+	url := URL(urlTpl, version)
+	if _, ok := lockedChecksums[url]; lockedChecksums != nil && !ok {
+		return -1, fmt.Errorf("%s is not locked", url)
+	}
+	if runner.DryRun("download %s to %s", url, dst) {
+		return 0, nil
+	}
+	label := name
+	if strings.HasPrefix(version, "v") {
+		label = name + " " + version
 	} else {
-		name = name + " v" + version
+		label = name + " v" + version
 	}
 	tokens := strings.Split(url, "/")
 	urlFileName := tokens[len(tokens)-1]
 
 	tempDir, err := os.MkdirTemp(os.TempDir(), "*")
+	if err != nil {
+		return -1, err
+	}
 	defer utils.DeleteDir(tempDir)
 
-	n, err := fromUrl(url, name, tempDir, urlFileName)
+	n, _, err := fromUrl(url, label, tempDir, urlFileName)
 	if err != nil {
 		return -1, err
 	}
+	// Signature is verified before anything is extracted from the downloaded file
+	downloaded := tempDir + string(os.PathSeparator) + urlFileName
+	err = trust.Verify(name, version, url, downloaded, sig)
+	if err != nil {
+		return -1, err
+	}
+	// Archive format is detected from content, whatever the file extension
+	if _, err = extract.Extract(downloaded, tempDir, nil); err != nil {
+		return -1, err
+	}
 
 	for _, fileMap := range fileMaps {
 		fileDst := dst + string(os.PathSeparator) + fileMap.Dst
@@ -116,7 +211,7 @@ func FromUrl(urlTpl string, version string, name string, fileMaps []*FileMap, ds
 			}
 		}
 		fileSrc := tempDir + string(os.PathSeparator) + fileMap.Src
-		err = os.Rename(fileSrc, fileDst)
+		err = replaceFile(fileSrc, fileDst)
 		if err != nil {
 			return -1, err
 		}
@@ -124,3 +219,24 @@ func FromUrl(urlTpl string, version string, name string, fileMaps []*FileMap, ds
 
 	return n, nil
 }
+
+// replaceFile moves src to dst, an existing dst being only replaced once src has been entirely copied next to it
+func replaceFile(src string, dst string) error {
+	tmpFile := dst + ".new"
+	if err := os.Rename(src, tmpFile); err != nil {
+		// Temporary directory may be on another volume
+		info, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		if err = utils.CopyFile(src, tmpFile, info.Mode().Perm()); err != nil {
+			_ = os.Remove(tmpFile)
+			return fmt.Errorf("cannot copy %s: %w", src, err)
+		}
+	}
+	if err := os.Rename(tmpFile, dst); err != nil {
+		_ = os.Remove(tmpFile)
+		return fmt.Errorf("cannot replace %s: %w", dst, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const releaseContent = "gokube release"
+
+func newReleaseServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.2.0/tool.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(releaseContent))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestToFile(t *testing.T) {
+	server := newReleaseServer(t)
+	sum := sha256.Sum256([]byte(releaseContent))
+	checksum := hex.EncodeToString(sum[:])
+	dir := t.TempDir()
+	file, err := ToFile(server.URL+"/v1.2.0/tool.zip", "tool", dir, checksum)
+	if err != nil {
+		t.Fatalf("ToFile() error = %v", err)
+	}
+	if file != filepath.Join(dir, "tool.zip") {
+		t.Errorf("ToFile() = %s, want %s", file, filepath.Join(dir, "tool.zip"))
+	}
+	content, err := os.ReadFile(file)
+	if err != nil || string(content) != releaseContent {
+		t.Errorf("ToFile() content = %q, %v, want %q", content, err, releaseContent)
+	}
+	_, err = ToFile(server.URL+"/v1.2.0/tool.zip", "tool", dir, strings.Repeat("0", 64))
+	if err == nil || !strings.Contains(err.Error(), "instead of") {
+		t.Errorf("ToFile() error = %v, want checksum mismatch", err)
+	}
+}
+
+func TestToFileNotFound(t *testing.T) {
+	server := newReleaseServer(t)
+	_, err := ToFile(server.URL+"/v1.2.0/missing.zip", "tool", t.TempDir(), "")
+	if err == nil || !strings.Contains(err.Error(), "cannot download") {
+		t.Errorf("ToFile() error = %v, want cannot download", err)
+	}
+}
+
+func TestToFileUnreachable(t *testing.T) {
+	server := newReleaseServer(t)
+	url := server.URL + "/v1.2.0/tool.zip"
+	server.Close()
+	_, err := ToFile(url, "tool", t.TempDir(), "")
+	if err == nil {
+		t.Errorf("ToFile() from a closed server error = nil, want connection error")
+	}
+}
+
+func TestToFileInvalidDir(t *testing.T) {
+	server := newReleaseServer(t)
+	_, err := ToFile(server.URL+"/v1.2.0/tool.zip", "tool", filepath.Join(t.TempDir(), "missing"), "")
+	if err == nil {
+		t.Errorf("ToFile() into a missing directory error = nil, want file creation error")
+	}
+}
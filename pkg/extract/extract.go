@@ -0,0 +1,369 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Archive formats, detected from content
+const (
+	FORMAT_NONE    = ""
+	FORMAT_ZIP     = "zip"
+	FORMAT_TAR     = "tar"
+	FORMAT_TAR_GZ  = "tar.gz"
+	FORMAT_TAR_XZ  = "tar.xz"
+	FORMAT_TAR_BZ2 = "tar.bz2"
+	FORMAT_GZ      = "gz"
+	FORMAT_XZ      = "xz"
+	FORMAT_BZ2     = "bz2"
+)
+
+// Symbolic link policies
+const (
+	// SYMLINKS_IGNORE skips symbolic links
+	SYMLINKS_IGNORE = "ignore"
+	// SYMLINKS_CONTAIN creates symbolic links whose target stays within the destination directory, and fails on others
+	SYMLINKS_CONTAIN = "contain"
+	// SYMLINKS_REJECT fails on any symbolic link
+	SYMLINKS_REJECT = "reject"
+)
+
+const (
+	DEFAULT_MAX_FILE_SIZE  = 1 << 30
+	DEFAULT_MAX_TOTAL_SIZE = 4 << 30
+	DEFAULT_MAX_FILES      = 100000
+
+	// tar magic is located at offset 257 of the first header
+	tarMagicOffset = 257
+	sniffSize      = 512
+)
+
+var (
+	ErrUnsafePath   = errors.New("unsafe path")
+	ErrSymlink      = errors.New("symbolic link not allowed")
+	ErrSizeExceeded = errors.New("size limit exceeded")
+	ErrTooManyFiles = errors.New("too many files")
+)
+
+// Options limits what an archive can extract
+type Options struct {
+	// MaxFileSize is the maximum size of an extracted file (DEFAULT_MAX_FILE_SIZE if 0)
+	MaxFileSize int64
+	// MaxTotalSize is the maximum size of all extracted files (DEFAULT_MAX_TOTAL_SIZE if 0)
+	MaxTotalSize int64
+	// MaxFiles is the maximum number of extracted entries (DEFAULT_MAX_FILES if 0)
+	MaxFiles int
+	// Symlinks is the symbolic link policy (SYMLINKS_IGNORE if empty)
+	Symlinks string
+}
+
+// extractor holds the destination directory and what has been extracted so far
+type extractor struct {
+	dst       string
+	options   Options
+	totalSize int64
+	files     int
+}
+
+// Detect returns the archive format of the given file from its content, FORMAT_NONE meaning it is not an archive
+func Detect(src string) (string, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return FORMAT_NONE, err
+	}
+	defer func() { _ = file.Close() }()
+	return detect(bufio.NewReaderSize(file, sniffSize))
+}
+
+func detect(reader *bufio.Reader) (string, error) {
+	header, err := reader.Peek(sniffSize)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return FORMAT_NONE, err
+	}
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")) || bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return FORMAT_ZIP, nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return compressedFormat(reader, FORMAT_GZ, FORMAT_TAR_GZ)
+	case bytes.HasPrefix(header, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return compressedFormat(reader, FORMAT_XZ, FORMAT_TAR_XZ)
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return compressedFormat(reader, FORMAT_BZ2, FORMAT_TAR_BZ2)
+	case isTar(header):
+		return FORMAT_TAR, nil
+	}
+	return FORMAT_NONE, nil
+}
+
+// compressedFormat tells whether the compressed stream contains a tarball or a single file
+func compressedFormat(reader *bufio.Reader, single string, tarball string) (string, error) {
+	decompressed, err := decompress(reader, single)
+	if err != nil {
+		return FORMAT_NONE, err
+	}
+	header := make([]byte, sniffSize)
+	n, err := io.ReadFull(decompressed, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return FORMAT_NONE, fmt.Errorf("cannot decompress %s stream: %w", single, err)
+	}
+	if isTar(header[:n]) {
+		return tarball, nil
+	}
+	return single, nil
+}
+
+func isTar(header []byte) bool {
+	return len(header) >= tarMagicOffset+5 && string(header[tarMagicOffset:tarMagicOffset+5]) == "ustar"
+}
+
+// decompress returns a reader decompressing the given stream with the given compression format
+func decompress(reader io.Reader, format string) (io.Reader, error) {
+	switch format {
+	case FORMAT_GZ, FORMAT_TAR_GZ:
+		return gzip.NewReader(reader)
+	case FORMAT_XZ, FORMAT_TAR_XZ:
+		return xz.NewReader(reader)
+	case FORMAT_BZ2, FORMAT_TAR_BZ2:
+		return bzip2.NewReader(reader), nil
+	}
+	return reader, nil
+}
+
+// Extract extracts the given archive file into the destination directory, its format being detected from its content.
+// Nothing is done if the file is not an archive, and a single compressed file is extracted without its compression extension.
+func Extract(src string, dst string, options *Options) (string, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return FORMAT_NONE, err
+	}
+	defer func() { _ = file.Close() }()
+	format, err := detect(bufio.NewReaderSize(file, sniffSize))
+	if err != nil {
+		return FORMAT_NONE, err
+	}
+	if format == FORMAT_NONE {
+		return format, nil
+	}
+	// Detection reads the beginning of compressed streams
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return format, err
+	}
+	reader := bufio.NewReader(file)
+	e, err := newExtractor(dst, options)
+	if err != nil {
+		return format, err
+	}
+	switch format {
+	case FORMAT_ZIP:
+		err = e.extractZip(src)
+	case FORMAT_GZ, FORMAT_XZ, FORMAT_BZ2:
+		var decompressed io.Reader
+		decompressed, err = decompress(reader, format)
+		if err == nil {
+			err = e.extractFile(singleFileName(src, format), decompressed, 0644)
+		}
+	default:
+		var decompressed io.Reader
+		decompressed, err = decompress(reader, format)
+		if err == nil {
+			err = e.extractTar(decompressed)
+		}
+	}
+	if err != nil {
+		return format, fmt.Errorf("cannot extract %s: %w", filepath.Base(src), err)
+	}
+	return format, nil
+}
+
+func newExtractor(dst string, options *Options) (*extractor, error) {
+	dst, err := filepath.Abs(dst)
+	if err != nil {
+		return nil, err
+	}
+	e := &extractor{dst: dst}
+	if options != nil {
+		e.options = *options
+	}
+	if e.options.MaxFileSize <= 0 {
+		e.options.MaxFileSize = DEFAULT_MAX_FILE_SIZE
+	}
+	if e.options.MaxTotalSize <= 0 {
+		e.options.MaxTotalSize = DEFAULT_MAX_TOTAL_SIZE
+	}
+	if e.options.MaxFiles <= 0 {
+		e.options.MaxFiles = DEFAULT_MAX_FILES
+	}
+	switch e.options.Symlinks {
+	case "":
+		e.options.Symlinks = SYMLINKS_IGNORE
+	case SYMLINKS_IGNORE, SYMLINKS_CONTAIN, SYMLINKS_REJECT:
+	default:
+		return nil, fmt.Errorf("unknown symbolic link policy %q", e.options.Symlinks)
+	}
+	return e, os.MkdirAll(dst, 0755)
+}
+
+// singleFileName returns the name of the file compressed in src, i.e. src name without its compression extension
+func singleFileName(src string, format string) string {
+	name := filepath.Base(src)
+	trimmed := strings.TrimSuffix(name, "."+format)
+	if trimmed == name || len(trimmed) == 0 {
+		return name + ".out"
+	}
+	return trimmed
+}
+
+// target returns the path where the given archive entry is extracted, failing if it is not contained in destination directory
+func (e *extractor) target(name string) (string, error) {
+	if len(name) == 0 || strings.ContainsAny(name, ":\x00") || strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+	rel := filepath.Clean(filepath.FromSlash(strings.ReplaceAll(name, `\`, "/")))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+	// Entries are never extracted through a previously extracted symbolic link
+	dir := e.dst
+	for _, element := range strings.Split(filepath.Dir(rel), string(os.PathSeparator)) {
+		if element == "." {
+			break
+		}
+		dir = filepath.Join(dir, element)
+		info, err := os.Lstat(dir)
+		if err != nil {
+			break
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %q is extracted through a symbolic link", ErrUnsafePath, name)
+		}
+	}
+	return filepath.Join(e.dst, rel), nil
+}
+
+// isContained returns true if path is the destination directory or one of its descendants
+func (e *extractor) isContained(path string) bool {
+	rel, err := filepath.Rel(e.dst, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) && !filepath.IsAbs(rel)
+}
+
+// count records a new entry, failing once too many entries have been extracted
+func (e *extractor) count() error {
+	e.files++
+	if e.files > e.options.MaxFiles {
+		return fmt.Errorf("%w: more than %d entries", ErrTooManyFiles, e.options.MaxFiles)
+	}
+	return nil
+}
+
+func (e *extractor) extractDir(name string) error {
+	if err := e.count(); err != nil {
+		return err
+	}
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(target, 0755)
+}
+
+// extractFile writes the given content, enforcing size limits and never writing through an existing symbolic link
+func (e *extractor) extractFile(name string, content io.Reader, mode os.FileMode) error {
+	if err := e.count(); err != nil {
+		return err
+	}
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err = os.Remove(target); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	limit := e.options.MaxFileSize
+	if remaining := e.options.MaxTotalSize - e.totalSize; remaining < limit {
+		limit = remaining
+	}
+	n, err := io.Copy(file, io.LimitReader(content, limit+1))
+	e.totalSize += n
+	if err == nil && n > limit {
+		err = fmt.Errorf("%w: %s", ErrSizeExceeded, name)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(target)
+	}
+	return err
+}
+
+// extractSymlink applies the symbolic link policy to the given link
+func (e *extractor) extractSymlink(name string, linkTarget string) error {
+	switch e.options.Symlinks {
+	case SYMLINKS_IGNORE:
+		return nil
+	case SYMLINKS_REJECT:
+		return fmt.Errorf("%w: %q", ErrSymlink, name)
+	}
+	if err := e.count(); err != nil {
+		return err
+	}
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	// Link target must be relative and can only go up first, so that its lexical resolution is the actual one
+	// Drive letters are rejected whatever the host OS, like in entry names
+	if len(linkTarget) == 0 || strings.ContainsAny(linkTarget, ":\x00") || filepath.VolumeName(linkTarget) != "" ||
+		strings.HasPrefix(linkTarget, "/") || strings.HasPrefix(linkTarget, `\`) {
+		return fmt.Errorf("%w: %q links to an absolute path", ErrSymlink, name)
+	}
+	up := true
+	for _, element := range strings.Split(strings.ReplaceAll(linkTarget, `\`, "/"), "/") {
+		if element != ".." {
+			up = false
+		} else if !up {
+			return fmt.Errorf("%w: %q links to a path going up after going down", ErrSymlink, name)
+		}
+	}
+	if !e.isContained(filepath.Join(filepath.Dir(target), filepath.FromSlash(linkTarget))) {
+		return fmt.Errorf("%w: %q links outside of destination", ErrSymlink, name)
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	_ = os.Remove(target)
+	return os.Symlink(filepath.FromSlash(linkTarget), target)
+}
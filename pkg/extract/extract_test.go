@@ -0,0 +1,399 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// entry is an archive entry, a symbolic link if link is set and a hard link if hardlink is set
+type entry struct {
+	name     string
+	body     string
+	link     string
+	hardlink string
+	dir      bool
+}
+
+func tarArchive(t testing.TB, entries ...entry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		switch {
+		case e.dir:
+			header = &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}
+		case len(e.link) > 0:
+			header = &tar.Header{Name: e.name, Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: e.link}
+		case len(e.hardlink) > 0:
+			header = &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeLink, Linkname: e.hardlink}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("WriteHeader() error = %v", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func gzipContent(t testing.TB, content []byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(content); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func xzContent(t testing.TB, content []byte) []byte {
+	var buf bytes.Buffer
+	xw, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if _, err = xw.Write(content); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err = xw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zipArchive(t testing.TB, entries ...entry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		switch {
+		case e.dir:
+			header.SetMode(os.ModeDir | 0755)
+		case len(e.link) > 0:
+			header.SetMode(os.ModeSymlink | 0777)
+			body = e.link
+		default:
+			header.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("CreateHeader() error = %v", err)
+		}
+		if _, err = w.Write([]byte(body)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// writeArchive writes the archive in its own directory, next to the extraction directory
+func writeArchive(t testing.TB, name string, content []byte) (string, string) {
+	root := t.TempDir()
+	src := filepath.Join(root, name)
+	if err := os.WriteFile(src, content, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return src, filepath.Join(root, "dst")
+}
+
+// helloBz2 is "hello" compressed with bzip2, which the standard library cannot write
+var helloBz2 = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x19, 0x31,
+	0x65, 0x3d, 0x00, 0x00, 0x00, 0x81, 0x00, 0x02, 0x44, 0xa0, 0x00, 0x21,
+	0x9a, 0x68, 0x33, 0x4d, 0x07, 0x33, 0x8b, 0xb9, 0x22, 0x9c, 0x28, 0x48,
+	0x0c, 0x98, 0xb2, 0x9e, 0x80,
+}
+
+func TestDetect(t *testing.T) {
+	tarball := tarArchive(t, entry{name: "a.txt", body: "a"})
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"zip", zipArchive(t, entry{name: "a.txt", body: "a"}), FORMAT_ZIP},
+		{"empty zip", zipArchive(t), FORMAT_ZIP},
+		{"tar", tarball, FORMAT_TAR},
+		{"tar.gz", gzipContent(t, tarball), FORMAT_TAR_GZ},
+		{"gz", gzipContent(t, []byte("hello")), FORMAT_GZ},
+		{"tar.xz", xzContent(t, tarball), FORMAT_TAR_XZ},
+		{"xz", xzContent(t, []byte("hello")), FORMAT_XZ},
+		{"bz2", helloBz2, FORMAT_BZ2},
+		{"binary", []byte("\x7fELF\x02\x01\x01"), FORMAT_NONE},
+		{"empty", nil, FORMAT_NONE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// File name never tells the format
+			src, _ := writeArchive(t, "download", tt.content)
+			got, err := Detect(src)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tarball := tarArchive(t,
+		entry{name: "bin/", dir: true},
+		entry{name: "bin/tool", body: "tool"},
+		entry{name: "./README.md", body: "readme"},
+	)
+	tests := []struct {
+		name    string
+		src     string
+		content []byte
+		want    map[string]string
+	}{
+		{"tar.gz", "tool.tar.gz", gzipContent(t, tarball), map[string]string{"bin/tool": "tool", "README.md": "readme"}},
+		{"tar.xz", "tool.tar.xz", xzContent(t, tarball), map[string]string{"bin/tool": "tool", "README.md": "readme"}},
+		{"zip", "tool.zip", zipArchive(t, entry{name: "bin/tool", body: "tool"}), map[string]string{"bin/tool": "tool"}},
+		{"gz", "tool.gz", gzipContent(t, []byte("tool")), map[string]string{"tool": "tool"}},
+		{"bz2", "hello.bz2", helloBz2, map[string]string{"hello": "hello"}},
+		{"none", "tool", []byte("tool"), map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, dst := writeArchive(t, tt.src, tt.content)
+			_, err := Extract(src, dst, nil)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			for name, want := range tt.want {
+				content, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatalf("ReadFile() error = %v", err)
+				}
+				if string(content) != want {
+					t.Errorf("Extract() %s = %q, want %q", name, content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractUnsafe(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		content  []byte
+		symlinks string
+		want     error
+	}{
+		{"tar zip-slip", "a.tar", tarArchive(t, entry{name: "../evil", body: "evil"}), "", ErrUnsafePath},
+		{"tar nested zip-slip", "a.tar", tarArchive(t, entry{name: "a/../../evil", body: "evil"}), "", ErrUnsafePath},
+		{"zip zip-slip", "a.zip", zipArchive(t, entry{name: "../evil", body: "evil"}), "", ErrUnsafePath},
+		{"zip backslash zip-slip", "a.zip", zipArchive(t, entry{name: `..\evil`, body: "evil"}), "", ErrUnsafePath},
+		{"tar absolute path", "a.tar", tarArchive(t, entry{name: "/tmp/evil", body: "evil"}), "", ErrUnsafePath},
+		{"zip absolute path", "a.zip", zipArchive(t, entry{name: "/tmp/evil", body: "evil"}), "", ErrUnsafePath},
+		{"zip backslash absolute path", "a.zip", zipArchive(t, entry{name: `\tmp\evil`, body: "evil"}), "", ErrUnsafePath},
+		{"tar drive path", "a.tar", tarArchive(t, entry{name: "C:/Windows/evil", body: "evil"}), "", ErrUnsafePath},
+		{"zip drive path", "a.zip", zipArchive(t, entry{name: `C:\Windows\evil`, body: "evil"}), "", ErrUnsafePath},
+		{"zip drive relative path", "a.zip", zipArchive(t, entry{name: "C:evil", body: "evil"}), "", ErrUnsafePath},
+		{"tar symlink escape", "a.tar", tarArchive(t, entry{name: "link", link: "../outside"}), SYMLINKS_CONTAIN, ErrSymlink},
+		{"tar absolute symlink", "a.tar", tarArchive(t, entry{name: "link", link: "/etc"}), SYMLINKS_CONTAIN, ErrSymlink},
+		{"tar symlink going up after down", "a.tar", tarArchive(t, entry{name: "link", link: "a/../../outside"}), SYMLINKS_CONTAIN, ErrSymlink},
+		{"zip symlink escape", "a.zip", zipArchive(t, entry{name: "a/link", link: "../../outside"}), SYMLINKS_CONTAIN, ErrSymlink},
+		{"zip drive symlink", "a.zip", zipArchive(t, entry{name: "link", link: `C:\Windows`}), SYMLINKS_CONTAIN, ErrSymlink},
+		{"symlink rejected", "a.tar", tarArchive(t, entry{name: "link", link: "file"}), SYMLINKS_REJECT, ErrSymlink},
+		{"tar write through symlink", "a.tar", tarArchive(t,
+			entry{name: "dir/", dir: true},
+			entry{name: "link", link: "dir"},
+			entry{name: "link/file", body: "evil"},
+		), SYMLINKS_CONTAIN, ErrUnsafePath},
+		{"tar hardlink outside", "a.tar", tarArchive(t, entry{name: "link", hardlink: "../outside"}), "", ErrUnsafePath},
+		{"tar absolute hardlink", "a.tar", tarArchive(t, entry{name: "link", hardlink: "/etc/passwd"}), "", ErrUnsafePath},
+		{"tar hardlink to missing file", "a.tar", tarArchive(t, entry{name: "link", hardlink: "missing"}), "", ErrUnsafePath},
+		{"tar hardlink through symlink", "a.tar", tarArchive(t,
+			entry{name: "up", link: "."},
+			entry{name: "link", hardlink: "up/../outside"},
+		), SYMLINKS_CONTAIN, ErrUnsafePath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, dst := writeArchive(t, tt.src, tt.content)
+			outside := filepath.Join(filepath.Dir(dst), "outside")
+			if err := os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			_, err := Extract(src, dst, &Options{Symlinks: tt.symlinks})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Extract() error = %v, want %v", err, tt.want)
+			}
+			assertContained(t, filepath.Dir(dst), src, outside)
+		})
+	}
+}
+
+func TestExtractContainedSymlink(t *testing.T) {
+	src, dst := writeArchive(t, "a.tar", tarArchive(t,
+		entry{name: "bin/tool", body: "tool"},
+		entry{name: "tool", link: "bin/tool"},
+		entry{name: "lib/link", link: "../bin/tool"},
+	))
+	_, err := Extract(src, dst, &Options{Symlinks: SYMLINKS_CONTAIN})
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	for _, link := range []string{"tool", filepath.Join("lib", "link")} {
+		content, err := os.ReadFile(filepath.Join(dst, link))
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(content) != "tool" {
+			t.Errorf("Extract() %s = %q, want tool", link, content)
+		}
+	}
+	src, dst = writeArchive(t, "a.tar", tarArchive(t, entry{name: "tool", link: "bin/tool"}))
+	_, err = Extract(src, dst, nil)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if _, err = os.Lstat(filepath.Join(dst, "tool")); !os.IsNotExist(err) {
+		t.Errorf("Extract() created ignored symbolic link, error = %v", err)
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	big := strings.Repeat("x", 1024)
+	tests := []struct {
+		name    string
+		src     string
+		content []byte
+		options *Options
+		want    error
+	}{
+		{"tar file size", "a.tar", tarArchive(t, entry{name: "big", body: big}), &Options{MaxFileSize: 1023}, ErrSizeExceeded},
+		{"tar total size", "a.tar", tarArchive(t, entry{name: "a", body: big}, entry{name: "b", body: big}), &Options{MaxTotalSize: 2047}, ErrSizeExceeded},
+		{"tar hardlink total size", "a.tar", tarArchive(t, entry{name: "a", body: big}, entry{name: "b", hardlink: "a"}), &Options{MaxTotalSize: 2047}, ErrSizeExceeded},
+		{"zip file size", "a.zip", zipArchive(t, entry{name: "big", body: big}), &Options{MaxFileSize: 1023}, ErrSizeExceeded},
+		{"zip total size", "a.zip", zipArchive(t, entry{name: "a", body: big}, entry{name: "b", body: big}), &Options{MaxTotalSize: 2047}, ErrSizeExceeded},
+		{"gz bomb", "big.gz", gzipContent(t, bytes.Repeat([]byte{0}, 1<<20)), &Options{MaxFileSize: 1 << 10}, ErrSizeExceeded},
+		{"tar.gz bomb", "big.tar.gz", gzipContent(t, tarArchive(t, entry{name: "big", body: strings.Repeat("0", 1<<20)})), &Options{MaxTotalSize: 1 << 10}, ErrSizeExceeded},
+		{"tar files", "a.tar", tarArchive(t, entry{name: "a", body: "a"}, entry{name: "b", body: "b"}, entry{name: "c/", dir: true}), &Options{MaxFiles: 2}, ErrTooManyFiles},
+		{"zip files", "a.zip", zipArchive(t, entry{name: "a", body: "a"}, entry{name: "b", body: "b"}), &Options{MaxFiles: 1}, ErrTooManyFiles},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, dst := writeArchive(t, tt.src, tt.content)
+			_, err := Extract(src, dst, tt.options)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Extract() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	src, dst := writeArchive(t, "a.tar", tarArchive(t, entry{name: "a", body: big}, entry{name: "b", body: big}))
+	_, err := Extract(src, dst, &Options{MaxFileSize: 1024, MaxTotalSize: 2048, MaxFiles: 2})
+	if err != nil {
+		t.Errorf("Extract() error = %v, want archive within limits extracted", err)
+	}
+}
+
+// assertContained checks that root only holds the given files and the extraction directory, whose symbolic links
+// all resolve inside of it
+func assertContained(t testing.TB, root string, files ...string) {
+	dst := filepath.Join(root, "dst")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	expected := map[string]bool{"dst": true}
+	for _, file := range files {
+		expected[filepath.Base(file)] = true
+	}
+	for _, e := range entries {
+		if !expected[e.Name()] {
+			t.Fatalf("Extract() wrote %s outside of destination", e.Name())
+		}
+	}
+	for _, file := range files {
+		if filepath.Base(file) == "outside" {
+			content, err := os.ReadFile(file)
+			if err != nil || string(content) != "outside" {
+				t.Fatalf("Extract() modified file outside of destination: %q, %v", content, err)
+			}
+		}
+	}
+	realDst, err := filepath.EvalSymlinks(dst)
+	if err != nil {
+		return
+	}
+	err = filepath.Walk(dst, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return err
+		}
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			// Dangling links cannot be followed
+			return nil
+		}
+		rel, err := filepath.Rel(realDst, resolved)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			t.Fatalf("Extract() created %s linking outside of destination to %s", path, resolved)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+}
+
+func FuzzExtract(f *testing.F) {
+	f.Add(tarArchive(f, entry{name: "bin/tool", body: "tool"}, entry{name: "tool", link: "bin/tool"}))
+	f.Add(tarArchive(f, entry{name: "../evil", body: "evil"}))
+	f.Add(tarArchive(f, entry{name: "link", link: "../outside"}, entry{name: "link/evil", body: "evil"}))
+	f.Add(tarArchive(f, entry{name: "up", link: "."}, entry{name: "link", hardlink: "up/../outside"}))
+	f.Add(gzipContent(f, tarArchive(f, entry{name: "a/", dir: true}, entry{name: "a/b", body: "b"})))
+	f.Add(zipArchive(f, entry{name: "a/link", link: "../../outside"}, entry{name: `..\evil`, body: "evil"}))
+	f.Add(gzipContent(f, []byte("single")))
+	f.Add(helloBz2)
+	f.Fuzz(func(t *testing.T, content []byte) {
+		src, dst := writeArchive(t, "archive", content)
+		outside := filepath.Join(filepath.Dir(dst), "outside")
+		if err := os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		// Errors are expected from invalid archives, only what was extracted matters
+		_, _ = Extract(src, dst, &Options{MaxFileSize: 1 << 16, MaxTotalSize: 1 << 18, MaxFiles: 64, Symlinks: SYMLINKS_CONTAIN})
+		assertContained(t, filepath.Dir(dst), src, outside)
+	})
+}
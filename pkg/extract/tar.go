@@ -0,0 +1,71 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+)
+
+func (e *extractor) extractTar(reader io.Reader) error {
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = e.extractDir(header.Name)
+		case tar.TypeReg, tar.TypeRegA:
+			if header.Size > e.options.MaxFileSize {
+				return fmt.Errorf("%w: %s", ErrSizeExceeded, header.Name)
+			}
+			err = e.extractFile(header.Name, tr, os.FileMode(header.Mode))
+		case tar.TypeSymlink:
+			err = e.extractSymlink(header.Name, header.Linkname)
+		case tar.TypeLink:
+			err = e.extractHardlink(header.Name, header.Linkname)
+		default:
+			// Devices, FIFOs and other special files are never extracted
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractHardlink copies the previously extracted file the hard link points to, which must be contained in destination directory
+func (e *extractor) extractHardlink(name string, linkName string) error {
+	source, err := e.target(linkName)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(source)
+	if err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: hard link %q to %q which is not an extracted file", ErrUnsafePath, name, linkName)
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	return e.extractFile(name, file, info.Mode())
+}
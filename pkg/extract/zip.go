@@ -0,0 +1,67 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxSymlinkTargetSize is the maximum size of a zip entry holding a symbolic link target
+const maxSymlinkTargetSize = 4096
+
+func (e *extractor) extractZip(src string) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	for _, f := range r.File {
+		err = e.extractZipEntry(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) extractZipEntry(f *zip.File) error {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return e.extractDir(f.Name)
+	}
+	if mode&os.ModeSymlink == 0 && !mode.IsRegular() {
+		// Devices, FIFOs and other special files are never extracted
+		return nil
+	}
+	if f.UncompressedSize64 > uint64(e.options.MaxFileSize) {
+		return fmt.Errorf("%w: %s", ErrSizeExceeded, f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+	if mode&os.ModeSymlink != 0 {
+		linkTarget, err := io.ReadAll(io.LimitReader(rc, maxSymlinkTargetSize))
+		if err != nil {
+			return err
+		}
+		return e.extractSymlink(f.Name, string(linkTarget))
+	}
+	return e.extractFile(f.Name, rc, mode)
+}
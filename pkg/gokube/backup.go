@@ -0,0 +1,219 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gokube
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gemalto/gokube/pkg/docker"
+	"github.com/gemalto/gokube/pkg/kubectl"
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+const (
+	backupTimeFormat = "20060102-150405"
+	maxConfigBackups = 10
+)
+
+// configDirectories returns the directories saved by a backup, indexed by their name in the backup
+func configDirectories() map[string]string {
+	return map[string]string{
+		"kube":   kubectl.GetWorkingDirectory(),
+		"docker": docker.GetWorkingDirectory(),
+	}
+}
+
+func getBackupsDir() string {
+	return utils.GetUserHome() + string(os.PathSeparator) + ".gokube" + string(os.PathSeparator) + "backups"
+}
+
+// BackupConfigDirectories saves kubectl and docker configuration directories into a timestamped backup and returns its name,
+// then removes the oldest backups except the ones to keep
+func BackupConfigDirectories(keep ...string) (string, error) {
+	timestamp := time.Now().Format(backupTimeFormat)
+	name := timestamp
+	backupDir := filepath.Join(getBackupsDir(), name)
+	if runner.DryRun("backup kubectl and docker configuration directories to %s", backupDir) {
+		return name, nil
+	}
+	err := os.MkdirAll(getBackupsDir(), 0755)
+	if err != nil {
+		return "", fmt.Errorf("cannot create backups directory: %w", err)
+	}
+	// Creating the backup directory reserves its name, suffixed when a backup was already taken in the same second
+	for n := 2; ; n++ {
+		err = os.Mkdir(backupDir, 0755)
+		if !os.IsExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", timestamp, n)
+		backupDir = filepath.Join(getBackupsDir(), name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot create backup directory: %w", err)
+	}
+	for subDir, dir := range configDirectories() {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		err := utils.CopyDir(dir, filepath.Join(backupDir, subDir))
+		if err != nil {
+			return "", fmt.Errorf("cannot backup %s: %w", dir, err)
+		}
+	}
+	err = pruneConfigBackups(append(keep, name))
+	if err != nil {
+		fmt.Printf("Warning: cannot remove old configuration backups: %s\n", err)
+	}
+	return name, nil
+}
+
+// parseBackupName returns the time and the same-second sequence number of a backup name, or false if it is not one
+func parseBackupName(name string) (time.Time, int, bool) {
+	if len(name) < len(backupTimeFormat) {
+		return time.Time{}, 0, false
+	}
+	t, err := time.Parse(backupTimeFormat, name[:len(backupTimeFormat)])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	suffix := name[len(backupTimeFormat):]
+	if len(suffix) == 0 {
+		return t, 1, true
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(suffix, "-"))
+	if err != nil || !strings.HasPrefix(suffix, "-") || n < 2 {
+		return time.Time{}, 0, false
+	}
+	return t, n, true
+}
+
+// ListConfigBackups returns the available backup names, oldest first
+func ListConfigBackups() ([]string, error) {
+	entries, err := os.ReadDir(getBackupsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if _, _, ok := parseBackupName(e.Name()); e.IsDir() && ok {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ti, ni, _ := parseBackupName(names[i])
+		tj, nj, _ := parseBackupName(names[j])
+		return ti.Before(tj) || ti.Equal(tj) && ni < nj
+	})
+	return names, nil
+}
+
+// RestoreConfigDirectories replaces kubectl and docker configuration directories with the content of the given backup
+func RestoreConfigDirectories(name string) error {
+	backupDir := filepath.Join(getBackupsDir(), name)
+	sources, err := backupSources(backupDir)
+	if err != nil {
+		return fmt.Errorf("invalid backup %s: %w", name, err)
+	}
+	if runner.DryRun("restore kubectl and docker configuration directories from %s", backupDir) {
+		return nil
+	}
+	for dir, src := range sources {
+		// Content is cleaned rather than the directory itself, which may be a symlink
+		err := utils.CleanDir(dir)
+		if err != nil {
+			return fmt.Errorf("cannot clean %s: %w", dir, err)
+		}
+		err = utils.CopyDir(src, dir)
+		if err != nil {
+			return fmt.Errorf("cannot restore %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// backupSources returns the backup directories to restore, indexed by the configuration directory they replace.
+// A backup must be a real directory, holding at least one non empty configuration directory.
+func backupSources(backupDir string) (map[string]string, error) {
+	info, err := os.Lstat(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", backupDir)
+	}
+	sources := map[string]string{}
+	empty := true
+	for subDir, dir := range configDirectories() {
+		src := filepath.Join(backupDir, subDir)
+		info, err := os.Lstat(src)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", src)
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return nil, err
+		}
+		sources[dir] = src
+		empty = empty && len(entries) == 0
+	}
+	if empty {
+		return nil, fmt.Errorf("backup is empty")
+	}
+	return sources, nil
+}
+
+// pruneConfigBackups removes the oldest backups beyond the maximum number of backups, except the ones to keep
+func pruneConfigBackups(keep []string) error {
+	names, err := ListConfigBackups()
+	if err != nil {
+		return err
+	}
+	kept := map[string]bool{}
+	for _, name := range keep {
+		kept[name] = true
+	}
+	count := len(names)
+	for _, name := range names {
+		if count <= maxConfigBackups {
+			break
+		}
+		if kept[name] {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(getBackupsDir(), name))
+		if err != nil {
+			return err
+		}
+		count--
+	}
+	return nil
+}
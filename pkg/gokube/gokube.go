@@ -17,15 +17,9 @@ package gokube
 import (
 	"bufio"
 	"fmt"
-	"github.com/gemalto/gokube/pkg/docker"
 	"github.com/gemalto/gokube/pkg/helm"
-	"github.com/gemalto/gokube/pkg/helmimage"
-	"github.com/gemalto/gokube/pkg/helmpush"
-	"github.com/gemalto/gokube/pkg/helmspray"
-	"github.com/gemalto/gokube/pkg/kubectl"
-	"github.com/gemalto/gokube/pkg/minikube"
-	"github.com/gemalto/gokube/pkg/stern"
-	"github.com/gemalto/gokube/pkg/k9s"
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/tool"
 	"github.com/gemalto/gokube/pkg/utils"
 	"github.com/spf13/viper"
 	"os"
@@ -39,6 +33,8 @@ type HelmPlugins struct {
 	ImageVersion string
 	PushURL      string
 	PushVersion  string
+	// Extra are user-defined plugins
+	Extra []*helm.Plugin
 }
 
 type Dependencies struct {
@@ -54,6 +50,8 @@ type Dependencies struct {
 	SternVersion    string
 	K9sURL          string
 	K9sVersion      string
+	// Extra are user-defined tools
+	Extra []*tool.Descriptor
 }
 
 // ReadConfig ...
@@ -93,6 +91,18 @@ func ReadConfig(verbose bool) error {
 
 // WriteConfig ...
 func WriteConfig(gokubeVersion string, kubernetesVersion string, containerRuntime string) error {
+	return UpdateConfig(map[string]interface{}{
+		"gokube-version":     gokubeVersion,
+		"kubernetes-version": kubernetesVersion,
+		"container-runtime":  containerRuntime,
+	})
+}
+
+// UpdateConfig sets the given values and writes gokube configuration file
+func UpdateConfig(values map[string]interface{}) error {
+	if runner.DryRun("write gokube configuration %v", values) {
+		return nil
+	}
 	configPath := utils.GetUserHome() + string(os.PathSeparator) + ".gokube"
 	configFile := "config"
 	configFilePath := configPath + string(os.PathSeparator) + "config.yaml"
@@ -111,9 +121,9 @@ func WriteConfig(gokubeVersion string, kubernetesVersion string, containerRuntim
 	viper.SetConfigName(configFile)
 	viper.AddConfigPath(configPath)
 	viper.SetConfigType("yaml")
-	viper.Set("gokube-version", gokubeVersion)
-	viper.Set("kubernetes-version", kubernetesVersion)
-	viper.Set("container-runtime", containerRuntime)
+	for key, value := range values {
+		viper.Set(key, value)
+	}
 	err := viper.WriteConfig()
 	if err != nil {
 		return err
@@ -121,71 +131,14 @@ func WriteConfig(gokubeVersion string, kubernetesVersion string, containerRuntim
 	return nil
 }
 
+// UpgradeHelmPlugins installs or upgrades the helm plugins whose installed version differs from the given one
 func UpgradeHelmPlugins(plugins *HelmPlugins) error {
-	// TODO rely on helm plugin install
-	_ = helmspray.DeletePlugin()
-	err := helmspray.InstallPlugin(plugins.SprayURL, plugins.SprayVersion)
-	if err != nil {
-		return fmt.Errorf("cannot install helm-spray plugin: %w", err)
-	}
-	_ = helmimage.DeletePlugin()
-	err = helmimage.InstallPlugin(plugins.ImageURL, plugins.ImageVersion)
-	if err != nil {
-		return fmt.Errorf("cannot install helm-image plugin: %w", err)
-	}
-	_ = helmpush.DeletePlugin()
-	err = helmpush.InstallPlugin(plugins.PushURL, plugins.PushVersion)
-	if err != nil {
-		return fmt.Errorf("cannot install helm-push plugin: %w", err)
-	}
-	return nil
+	return UpgradeTools(plugins.Tools())
 }
 
+// UpgradeDependencies downloads or upgrades the dependencies whose installed version differs from the given one
 func UpgradeDependencies(dependencies *Dependencies) error {
-	_ = minikube.DeleteExecutable()
-	err := minikube.DownloadExecutable(dependencies.MinikubeURL, dependencies.MinikubeVersion)
-	if err != nil {
-		return fmt.Errorf("cannot download or upgrade minikube: %w", err)
-	}
-	_ = helm.DeleteExecutable()
-	err = helm.DownloadExecutable(dependencies.HelmURL, dependencies.HelmVersion)
-	if err != nil {
-		return fmt.Errorf("cannot download or upgrade helm: %w", err)
-	}
-	_ = docker.DeleteExecutable()
-	err = docker.DownloadExecutable(dependencies.DockerURL, dependencies.DockerVersion)
-	if err != nil {
-		return fmt.Errorf("cannot download or upgrade docker: %w", err)
-	}
-	_ = kubectl.DeleteExecutable()
-	err = kubectl.DownloadExecutable(dependencies.KubectlURL, dependencies.KubectlVersion)
-	if err != nil {
-		return fmt.Errorf("cannot download or upgrade kubectl: %w", err)
-	}
-	_ = stern.DeleteExecutable()
-	err = stern.DownloadExecutable(dependencies.SternURL, dependencies.SternVersion)
-	if err != nil {
-		return fmt.Errorf("cannot download or upgrade stern: %w", err)
-	}
-	_ = k9s.DeleteExecutable()
-	err = k9s.DownloadExecutable(dependencies.K9sURL, dependencies.K9sVersion)
-	if err != nil {
-		return fmt.Errorf("cannot download or upgrade k9s: %w", err)
-	}
-	return nil
-}
-
-func ConfirmInitCommandExecution() {
-	fmt.Println("Warning: your Virtualbox GUI shall not be open and no other VM shall be currently running")
-	fmt.Print("Press <CTRL+C> within the next 10s if you need to check this or press <ENTER> now to continue...")
-	enter := make(chan bool, 1)
-	go waitEnter(enter)
-	select {
-	case <-enter:
-	case <-time.After(10 * time.Second):
-		fmt.Println()
-	}
-	time.Sleep(200 * time.Millisecond)
+	return UpgradeTools(dependencies.Tools())
 }
 
 func ConfirmSnapshotCommandExecution() {
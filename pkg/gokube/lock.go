@@ -0,0 +1,188 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gokube
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/utils"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	DEFAULT_LOCK_FILE = "gokube.lock"
+)
+
+// LockedArtifact is a downloaded artifact pinned by a lock file
+type LockedArtifact struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256,omitempty"`
+}
+
+// Lock pins the versions, URLs and checksums of gokube dependencies to get reproducible environments
+type Lock struct {
+	KubernetesVersion string            `json:"kubernetes-version"`
+	MinikubeISO       *LockedArtifact   `json:"minikube-iso,omitempty"`
+	Tools             []*LockedArtifact `json:"tools"`
+	HelmPlugins       []*LockedArtifact `json:"helm-plugins"`
+}
+
+// NewLock downloads the given dependencies and helm plugins to compute their checksums.
+// The checksum of minikube ISO is the one published alongside it.
+func NewLock(kubernetesVersion string, isoURL string, dependencies *Dependencies, plugins *HelmPlugins) (*Lock, error) {
+	lock := &Lock{KubernetesVersion: kubernetesVersion}
+	if len(isoURL) > 0 {
+		fmt.Printf("Getting SHA-256 of %s...\n", isoURL)
+		checksum, err := download.PublishedChecksum(isoURL)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get minikube ISO checksum: %w", err)
+		}
+		lock.MinikubeISO = &LockedArtifact{Name: "minikube-iso", Version: isoVersion(isoURL), URL: isoURL, SHA256: checksum}
+	}
+	var err error
+	lock.Tools, err = lockTools(dependencies.Tools())
+	if err != nil {
+		return nil, err
+	}
+	lock.HelmPlugins, err = lockTools(plugins.Tools())
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+func lockTools(tools []*Tool) ([]*LockedArtifact, error) {
+	var artifacts []*LockedArtifact
+	for _, tool := range tools {
+		artifact := &LockedArtifact{Name: tool.Name, Version: tool.Version, URL: tool.URL}
+		if !tool.NoChecksum {
+			fmt.Printf("Computing SHA-256 of %s...\n", tool.URL)
+			checksum, err := download.Checksum(tool.URL)
+			if err != nil {
+				return nil, fmt.Errorf("cannot compute %s checksum: %w", tool.Name, err)
+			}
+			artifact.SHA256 = checksum
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, nil
+}
+
+// isoVersion extracts the version from minikube ISO URL (e.g. minikube-v1.38.0-amd64.iso)
+func isoVersion(isoURL string) string {
+	tokens := strings.Split(isoURL, "/")
+	// Architecture suffix would otherwise be taken for a prerelease
+	name := strings.TrimSuffix(tokens[len(tokens)-1], ".iso")
+	for _, arch := range []string{"-amd64", "-arm64"} {
+		name = strings.TrimSuffix(name, arch)
+	}
+	version := utils.ParseVersion(name)
+	if len(version) == 0 {
+		return ""
+	}
+	return "v" + version
+}
+
+// ReadLock reads the given lock file
+func ReadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lock := &Lock{}
+	err = yaml.Unmarshal(data, lock)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	return lock, nil
+}
+
+// Write writes the lock to the given file
+func (l *Lock) Write(path string) error {
+	if runner.DryRun("write lock file %s", path) {
+		return nil
+	}
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Checksums returns the SHA-256 of locked tools and helm plugins by URL
+func (l *Lock) Checksums() map[string]string {
+	checksums := map[string]string{}
+	for _, artifact := range append(append([]*LockedArtifact{}, l.Tools...), l.HelmPlugins...) {
+		if len(artifact.SHA256) > 0 {
+			checksums[artifact.URL] = artifact.SHA256
+		}
+	}
+	return checksums
+}
+
+// Verify checks that the given kubernetes version, dependencies and helm plugins match the locked ones
+func (l *Lock) Verify(kubernetesVersion string, dependencies *Dependencies, plugins *HelmPlugins) error {
+	var mismatches []string
+	if l.KubernetesVersion != kubernetesVersion {
+		mismatches = append(mismatches, fmt.Sprintf("kubernetes version is %s instead of %s", kubernetesVersion, l.KubernetesVersion))
+	}
+	mismatches = append(mismatches, verifyTools(l.Tools, dependencies.Tools())...)
+	mismatches = append(mismatches, verifyTools(l.HelmPlugins, plugins.Tools())...)
+	if len(mismatches) > 0 {
+		return fmt.Errorf("configuration does not match lock file:\n  %s", strings.Join(mismatches, "\n  "))
+	}
+	return nil
+}
+
+func verifyTools(artifacts []*LockedArtifact, tools []*Tool) []string {
+	locked := map[string]*LockedArtifact{}
+	for _, artifact := range artifacts {
+		locked[artifact.Name] = artifact
+	}
+	var mismatches []string
+	for _, tool := range tools {
+		artifact, ok := locked[tool.Name]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("%s is not locked", tool.Name))
+		case artifact.Version != tool.Version:
+			mismatches = append(mismatches, fmt.Sprintf("%s version is %s instead of %s", tool.Name, tool.Version, artifact.Version))
+		case artifact.URL != tool.URL:
+			mismatches = append(mismatches, fmt.Sprintf("%s URL is %s instead of %s", tool.Name, tool.URL, artifact.URL))
+		}
+	}
+	return mismatches
+}
+
+// VerifyMinikubeISO checks that the checksum published alongside the locked minikube ISO did not change
+func (l *Lock) VerifyMinikubeISO() error {
+	if l.MinikubeISO == nil {
+		return nil
+	}
+	checksum, err := download.PublishedChecksum(l.MinikubeISO.URL)
+	if err != nil {
+		return fmt.Errorf("cannot get minikube ISO checksum: %w", err)
+	}
+	if !strings.EqualFold(checksum, l.MinikubeISO.SHA256) {
+		return fmt.Errorf("SHA-256 of %s does not match locked one %s", l.MinikubeISO.URL, l.MinikubeISO.SHA256)
+	}
+	return nil
+}
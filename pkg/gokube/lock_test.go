@@ -0,0 +1,220 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gokube
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// newArtifactsServer serves each artifact with its path as content, and its checksum alongside it in a .sha256 file
+func newArtifactsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path, ok := strings.CutSuffix(r.URL.Path, ".sha256"); ok {
+			_, _ = w.Write([]byte(checksum(path) + "  " + filepath.Base(path) + "\n"))
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testDependencies returns dependencies and helm plugins downloaded from the given server, helm-push being installed
+// from its repository
+func testDependencies(url string) (*Dependencies, *HelmPlugins) {
+	dependencies := &Dependencies{
+		MinikubeURL:     url + "/minikube/%s/minikube.exe",
+		MinikubeVersion: "v1.38.0",
+		HelmURL:         url + "/helm/helm-%s.zip",
+		HelmVersion:     "v3.20.0",
+		DockerURL:       url + "/docker/docker-%s.zip",
+		DockerVersion:   "29.2.1",
+		KubectlURL:      url + "/kubectl/%s/kubectl.exe",
+		KubectlVersion:  "v1.35.0",
+		SternURL:        url + "/stern/stern_%s.tar.gz",
+		SternVersion:    "1.33.1",
+		K9sURL:          url + "/k9s/v%s/k9s.zip",
+		K9sVersion:      "0.50.18",
+	}
+	plugins := &HelmPlugins{
+		SprayURL:     url + "/helm-spray/%s/helm-spray.tar.gz",
+		SprayVersion: "v4.0.13",
+		ImageURL:     url + "/helm-image/%s/helm-image.tar.gz",
+		ImageVersion: "v1.1.0",
+		PushURL:      "https://github.com/chartmuseum/helm-push",
+		PushVersion:  "0.10.4",
+	}
+	return dependencies, plugins
+}
+
+func TestLockRoundTrip(t *testing.T) {
+	server := newArtifactsServer(t)
+	dependencies, plugins := testDependencies(server.URL)
+	isoURL := server.URL + "/iso/minikube-v1.38.0-amd64.iso"
+	lock, err := NewLock("v1.35.0", isoURL, dependencies, plugins)
+	if err != nil {
+		t.Fatalf("NewLock() error = %v", err)
+	}
+	wantISO := &LockedArtifact{Name: "minikube-iso", Version: "v1.38.0", URL: isoURL, SHA256: checksum("/iso/minikube-v1.38.0-amd64.iso")}
+	if !reflect.DeepEqual(lock.MinikubeISO, wantISO) {
+		t.Errorf("NewLock() minikube ISO = %+v, want %+v", lock.MinikubeISO, wantISO)
+	}
+	if len(lock.Tools) != len(dependencies.Tools()) || len(lock.HelmPlugins) != len(plugins.Tools()) {
+		t.Fatalf("NewLock() locked %d tools and %d helm plugins, want %d and %d", len(lock.Tools), len(lock.HelmPlugins), len(dependencies.Tools()), len(plugins.Tools()))
+	}
+	kubectl := lock.Tools[3]
+	wantKubectl := &LockedArtifact{Name: "kubectl", Version: "v1.35.0", URL: server.URL + "/kubectl/v1.35.0/kubectl.exe", SHA256: checksum("/kubectl/v1.35.0/kubectl.exe")}
+	if !reflect.DeepEqual(kubectl, wantKubectl) {
+		t.Errorf("NewLock() kubectl = %+v, want %+v", kubectl, wantKubectl)
+	}
+	for _, plugin := range lock.HelmPlugins {
+		if plugin.Name == "helm-push" && len(plugin.SHA256) > 0 {
+			t.Errorf("NewLock() helm-push SHA-256 = %s, want none for a repository", plugin.SHA256)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), DEFAULT_LOCK_FILE)
+	err = lock.Write(path)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	read, err := ReadLock(path)
+	if err != nil {
+		t.Fatalf("ReadLock() error = %v", err)
+	}
+	if !reflect.DeepEqual(read, lock) {
+		t.Errorf("ReadLock() = %+v, want %+v", read, lock)
+	}
+	if checksums := read.Checksums(); checksums[wantKubectl.URL] != wantKubectl.SHA256 || len(checksums) != len(lock.Tools)+len(lock.HelmPlugins)-1 {
+		t.Errorf("Checksums() = %v, want a checksum for each downloaded artifact", checksums)
+	}
+	if err = read.Verify("v1.35.0", dependencies, plugins); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err = read.VerifyMinikubeISO(); err != nil {
+		t.Errorf("VerifyMinikubeISO() error = %v", err)
+	}
+}
+
+func TestLockVerifyMismatch(t *testing.T) {
+	server := newArtifactsServer(t)
+	dependencies, plugins := testDependencies(server.URL)
+	lock, err := NewLock("v1.35.0", "", dependencies, plugins)
+	if err != nil {
+		t.Fatalf("NewLock() error = %v", err)
+	}
+	tests := []struct {
+		name              string
+		kubernetesVersion string
+		update            func(*Dependencies, *HelmPlugins)
+		want              string
+	}{
+		{
+			name:              "kubernetes version",
+			kubernetesVersion: "v1.34.0",
+			want:              "kubernetes version is v1.34.0 instead of v1.35.0",
+		},
+		{
+			name:   "tool version",
+			update: func(d *Dependencies, p *HelmPlugins) { d.KubectlVersion = "v1.36.0" },
+			want:   "kubectl version is v1.36.0 instead of v1.35.0",
+		},
+		{
+			name:   "tool URL",
+			update: func(d *Dependencies, p *HelmPlugins) { d.HelmURL = "https://mirror.example.com/helm-%s.zip" },
+			want:   "helm URL is https://mirror.example.com/helm-v3.20.0.zip instead of " + server.URL + "/helm/helm-v3.20.0.zip",
+		},
+		{
+			name:   "helm plugin version",
+			update: func(d *Dependencies, p *HelmPlugins) { p.SprayVersion = "v4.0.14" },
+			want:   "helm-spray version is v4.0.14 instead of v4.0.13",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dependencies, plugins := testDependencies(server.URL)
+			if tt.update != nil {
+				tt.update(dependencies, plugins)
+			}
+			kubernetesVersion := tt.kubernetesVersion
+			if len(kubernetesVersion) == 0 {
+				kubernetesVersion = "v1.35.0"
+			}
+			err := lock.Verify(kubernetesVersion, dependencies, plugins)
+			if err == nil || !strings.Contains(err.Error(), "\n  "+tt.want) {
+				t.Errorf("Verify() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockVerifyMissingTool(t *testing.T) {
+	server := newArtifactsServer(t)
+	dependencies, plugins := testDependencies(server.URL)
+	lock, err := NewLock("v1.35.0", "", dependencies, plugins)
+	if err != nil {
+		t.Fatalf("NewLock() error = %v", err)
+	}
+	var tools []*LockedArtifact
+	for _, artifact := range lock.Tools {
+		if artifact.Name != "stern" {
+			tools = append(tools, artifact)
+		}
+	}
+	lock.Tools = tools
+	err = lock.Verify("v1.35.0", dependencies, plugins)
+	if err == nil || !strings.Contains(err.Error(), "stern is not locked") {
+		t.Errorf("Verify() error = %v, want stern is not locked", err)
+	}
+}
+
+func TestLockVerifyMinikubeISOMismatch(t *testing.T) {
+	server := newArtifactsServer(t)
+	lock := &Lock{MinikubeISO: &LockedArtifact{Name: "minikube-iso", URL: server.URL + "/iso/minikube-v1.38.0-amd64.iso", SHA256: checksum("previous ISO")}}
+	err := lock.VerifyMinikubeISO()
+	if err == nil || !strings.Contains(err.Error(), "does not match locked one") {
+		t.Errorf("VerifyMinikubeISO() error = %v, want checksum mismatch", err)
+	}
+}
+
+func TestIsoVersion(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://storage.googleapis.com/minikube/iso/minikube-v1.38.0-amd64.iso", "v1.38.0"},
+		{"https://storage.googleapis.com/minikube/iso/minikube-v1.38.0-arm64.iso", "v1.38.0"},
+		{"https://storage.googleapis.com/minikube/iso/minikube-v1.25.2.iso", "v1.25.2"},
+		{"https://mirror.example.com/minikube.iso", ""},
+	}
+	for _, tt := range tests {
+		if got := isoVersion(tt.url); got != tt.want {
+			t.Errorf("isoVersion(%s) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gokube
+
+import (
+	"fmt"
+
+	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/helm"
+	"github.com/gemalto/gokube/pkg/minikube"
+	"github.com/gemalto/gokube/pkg/tool"
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+// Tool is a gokube dependency which can be checked and upgraded on its own
+type Tool struct {
+	Name string
+	// Version is the target version of the tool
+	Version string
+	// URL is the download URL of the target version
+	URL string
+	// NoChecksum is set when the URL is not a downloaded artifact (e.g. a VCS repository)
+	NoChecksum       bool
+	InstalledVersion func() (string, error)
+	Upgrade          func() error
+}
+
+// ToolStatus holds the installed version of a tool compared to its target version
+type ToolStatus struct {
+	Tool      *Tool
+	Installed string
+	// Err is set when the installed version cannot be detected
+	Err error
+}
+
+// Descriptors returns the descriptors of the tools downloaded besides minikube, built-in ones first
+func (d *Dependencies) Descriptors() []*tool.Descriptor {
+	return append([]*tool.Descriptor{
+		tool.Builtin("helm", d.HelmURL, d.HelmVersion),
+		tool.Builtin("docker", d.DockerURL, d.DockerVersion),
+		tool.Builtin("kubectl", d.KubectlURL, d.KubectlVersion),
+		tool.Builtin("stern", d.SternURL, d.SternVersion),
+		tool.Builtin("k9s", d.K9sURL, d.K9sVersion),
+	}, d.Extra...)
+}
+
+// Tools returns gokube dependencies, in installation order
+func (d *Dependencies) Tools() []*Tool {
+	tools := []*Tool{
+		{Name: "minikube", Version: d.MinikubeVersion, URL: download.URL(d.MinikubeURL, d.MinikubeVersion), InstalledVersion: minikube.InstalledVersion, Upgrade: func() error {
+			return minikube.UpgradeExecutable(d.MinikubeURL, d.MinikubeVersion)
+		}},
+	}
+	for _, descriptor := range d.Descriptors() {
+		tools = append(tools, &Tool{
+			Name:             descriptor.Name,
+			Version:          descriptor.Version,
+			URL:              download.URL(descriptor.URL, descriptor.Version),
+			InstalledVersion: descriptor.InstalledVersion,
+			Upgrade:          descriptor.Upgrade,
+		})
+	}
+	return tools
+}
+
+// Plugins returns helm plugins, built-in ones first
+func (p *HelmPlugins) Plugins() []*helm.Plugin {
+	return append([]*helm.Plugin{
+		helm.BuiltinPlugin("helm-spray", p.SprayURL, p.SprayVersion),
+		helm.BuiltinPlugin("helm-image", p.ImageURL, p.ImageVersion),
+		helm.BuiltinPlugin("helm-push", p.PushURL, p.PushVersion),
+	}, p.Extra...)
+}
+
+// Tools returns helm plugins, which require helm to be installed first
+func (p *HelmPlugins) Tools() []*Tool {
+	var tools []*Tool
+	for _, plugin := range p.Plugins() {
+		tools = append(tools, PluginTool(plugin))
+	}
+	return tools
+}
+
+// PluginTool returns the tool installing the given helm plugin
+func PluginTool(plugin *helm.Plugin) *Tool {
+	return &Tool{
+		Name:             plugin.Name,
+		Version:          plugin.Version,
+		URL:              plugin.URL(),
+		NoChecksum:       !plugin.IsArchive(),
+		InstalledVersion: plugin.InstalledVersion,
+		Upgrade:          plugin.Install,
+	}
+}
+
+// IsUpToDate returns true if the tool is installed with its target version
+func (s *ToolStatus) IsUpToDate() bool {
+	return s.Err == nil && len(s.Installed) > 0 && utils.SameVersion(s.Installed, s.Tool.Version)
+}
+
+// Status returns a short description of the tool status
+func (s *ToolStatus) Status() string {
+	switch {
+	case s.Err != nil:
+		return "unknown"
+	case len(s.Installed) == 0:
+		return "missing"
+	case s.IsUpToDate():
+		return "up-to-date"
+	}
+	return "outdated"
+}
+
+// CheckTools detects the installed version of the given tools
+func CheckTools(tools []*Tool) []*ToolStatus {
+	var statuses []*ToolStatus
+	for _, tool := range tools {
+		installed, err := tool.InstalledVersion()
+		statuses = append(statuses, &ToolStatus{Tool: tool, Installed: installed, Err: err})
+	}
+	return statuses
+}
+
+// UpgradeTools replaces the tools whose installed version differs from their target version
+func UpgradeTools(tools []*Tool) error {
+	for _, status := range CheckTools(tools) {
+		if status.IsUpToDate() {
+			continue
+		}
+		if status.Err != nil {
+			fmt.Printf("Warning: cannot detect installed %s version: %s\n", status.Tool.Name, status.Err)
+		}
+		err := status.Tool.Upgrade()
+		if err != nil {
+			return fmt.Errorf("cannot download or upgrade %s: %w", status.Tool.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gemalto/gokube/pkg/certs"
+	"github.com/gemalto/gokube/pkg/httpclient"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+// httpGetter is a helm HTTP getter using gokube HTTP client configuration: proxy, CA bundle, timeouts, TLS settings
+// and basic authentication credentials
+type httpGetter struct {
+	options []getter.Option
+}
+
+// Get downloads the given URL with a new helm HTTP getter, as helm ones keep the options of their previous downloads
+func (g *httpGetter) Get(href string, options ...getter.Option) (*bytes.Buffer, error) {
+	all := append(append([]getter.Option{}, g.options...), options...)
+	all = append(all, getter.WithTransport(httpclient.Transport()))
+	if username, password, ok := httpclient.BasicAuth(href); ok {
+		all = append(all, getter.WithURL(href), getter.WithBasicAuth(username, password))
+	}
+	hg, err := getter.NewHTTPGetter(all...)
+	if err != nil {
+		return nil, err
+	}
+	return hg.Get(href)
+}
+
+// getters returns helm getters, HTTP ones using gokube HTTP client configuration
+func (c *Client) getters() getter.Providers {
+	providers := getter.Providers{{
+		Schemes: []string{"http", "https"},
+		New: func(options ...getter.Option) (getter.Getter, error) {
+			return &httpGetter{options: options}, nil
+		},
+	}}
+	for _, p := range getter.All(c.settings) {
+		if !p.Provides("http") && !p.Provides("https") {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+// locateChart returns the path of the given chart, downloading it into the repository cache with gokube getters
+// unless it is a local one
+func (c *Client) locateChart(chartName string, version string) (string, error) {
+	if _, err := os.Stat(chartName); err == nil {
+		return filepath.Abs(chartName)
+	}
+	err := os.MkdirAll(c.settings.RepositoryCache, 0755)
+	if err != nil {
+		return "", err
+	}
+	dl := downloader.ChartDownloader{
+		Out:              os.Stdout,
+		Getters:          c.getters(),
+		Options:          []getter.Option{getter.WithTLSClientConfig("", "", certs.BundleFile())},
+		RepositoryConfig: c.settings.RepositoryConfig,
+		RepositoryCache:  c.settings.RepositoryCache,
+	}
+	chartPath, _, err := dl.DownloadTo(chartName, version, c.settings.RepositoryCache)
+	if err != nil {
+		return "", fmt.Errorf("cannot download chart %s: %w", chartName, err)
+	}
+	return filepath.Abs(chartPath)
+}
@@ -15,107 +15,248 @@ limitations under the License.
 package helm
 
 import (
+	"errors"
 	"fmt"
-	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/certs"
+	"github.com/gemalto/gokube/pkg/runner"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"time"
 
 	"github.com/gemalto/gokube/pkg/utils"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/repo"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
-const (
-	DEFAULT_URL           = "https://get.helm.sh/helm-%s-windows-amd64.zip"
-	LOCAL_EXECUTABLE_NAME = "helm.exe"
-)
+// Client performs helm operations through the helm SDK
+type Client struct {
+	settings     *cli.EnvSettings
+	actionConfig func(namespace string) (*action.Configuration, error)
+}
+
+// NewClient creates a Client targeting the given kubeconfig context
+func NewClient(kubeContext string) *Client {
+	settings := cli.New()
+	settings.KubeContext = kubeContext
+	return &Client{
+		settings: settings,
+		actionConfig: func(namespace string) (*action.Configuration, error) {
+			actionConfig := new(action.Configuration)
+			err := actionConfig.Init(settings.RESTClientGetter(), namespace, os.Getenv("HELM_DRIVER"), debugLog)
+			if err != nil {
+				return nil, err
+			}
+			return actionConfig, nil
+		},
+	}
+}
 
-// Upgrade ...
-func Upgrade(chart string, version string, release string, namespace string, configuration string, valuesFile string) error {
-	var args = []string{"upgrade", "--install", "--devel", release, chart}
-	if len(version) > 0 {
-		args = append(args, "--version", version)
+// NewClientWithConfiguration creates a Client using the given action configuration (typically one backed by a fake cluster)
+func NewClientWithConfiguration(settings *cli.EnvSettings, actionConfig *action.Configuration) *Client {
+	return &Client{
+		settings: settings,
+		actionConfig: func(namespace string) (*action.Configuration, error) {
+			return actionConfig, nil
+		},
 	}
-	if len(namespace) > 0 {
-		args = append(args, "--namespace", namespace)
+}
+
+// Upgrade installs or upgrades the given release, waiting for its readiness when timeout is not zero
+func (c *Client) Upgrade(chartName string, version string, releaseName string, namespace string, configuration string, valuesFile string, timeout time.Duration) (*release.Release, error) {
+	fmt.Println("Starting " + chartName + " components...")
+	if runner.DryRun("helm upgrade --install %s %s --namespace %s --set %s", releaseName, chartName, namespace, configuration) {
+		return nil, nil
+	}
+	actionConfig, err := c.actionConfig(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize helm configuration: %w", err)
 	}
+	if len(version) == 0 {
+		// Same as --devel
+		version = ">0.0.0-0"
+	}
+	chartPathOptions := action.ChartPathOptions{Version: version, CaFile: certs.BundleFile()}
+	chartPath, err := c.locateChart(chartName, version)
+	if err != nil {
+		return nil, fmt.Errorf("cannot locate chart %s: %w", chartName, err)
+	}
+	loadedChart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load chart %s: %w", chartName, err)
+	}
+	valueOptions := &values.Options{}
 	if len(configuration) > 0 {
-		args = append(args, "--set", configuration)
+		valueOptions.Values = []string{configuration}
 	}
 	if len(valuesFile) > 0 {
-		args = append(args, "-f", valuesFile)
+		valueOptions.ValueFiles = []string{valuesFile}
+	}
+	vals, err := valueOptions.MergeValues(c.getters())
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse values for chart %s: %w", chartName, err)
 	}
-	fmt.Println("Starting " + chart + " components...")
-	cmd := exec.Command("helm", args...)
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
 
-// RepoAdd ...
-func RepoAdd(name string, repo string) error {
-	cmd := exec.Command("helm", "repo", "add", name, repo)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	history := action.NewHistory(actionConfig)
+	history.Max = 1
+	if _, err := history.Run(releaseName); errors.Is(err, driver.ErrReleaseNotFound) {
+		install := action.NewInstall(actionConfig)
+		install.ChartPathOptions = chartPathOptions
+		install.ReleaseName = releaseName
+		install.Namespace = namespace
+		install.Wait = timeout > 0
+		install.Timeout = timeout
+		rel, err := install.Run(loadedChart, vals)
+		if err != nil {
+			return rel, fmt.Errorf("cannot install release %s: %w", releaseName, err)
+		}
+		return rel, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot get history of release %s: %w", releaseName, err)
+	}
+
+	upgrade := action.NewUpgrade(actionConfig)
+	upgrade.ChartPathOptions = chartPathOptions
+	upgrade.Namespace = namespace
+	upgrade.Wait = timeout > 0
+	upgrade.Timeout = timeout
+	rel, err := upgrade.Run(releaseName, loadedChart, vals)
+	if err != nil {
+		return rel, fmt.Errorf("cannot upgrade release %s: %w", releaseName, err)
+	}
+	return rel, nil
 }
 
-// RepoUpdate ...
-func RepoUpdate() error {
-	cmd := exec.Command("helm", "repo", "update")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// Status ...
+func (c *Client) Status(releaseName string, namespace string) (*release.Release, error) {
+	actionConfig, err := c.actionConfig(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize helm configuration: %w", err)
+	}
+	rel, err := action.NewStatus(actionConfig).Run(releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get status of release %s: %w", releaseName, err)
+	}
+	return rel, nil
 }
 
-// Version ...
-func Version() error {
-	fmt.Print("helm version: ")
-	cmd := exec.Command("helm", "version", "--client", "--short")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// Uninstall ...
+func (c *Client) Uninstall(releaseName string, namespace string) error {
+	if runner.DryRun("helm uninstall %s --namespace %s", releaseName, namespace) {
+		return nil
+	}
+	actionConfig, err := c.actionConfig(namespace)
+	if err != nil {
+		return fmt.Errorf("cannot initialize helm configuration: %w", err)
+	}
+	_, err = action.NewUninstall(actionConfig).Run(releaseName)
+	if err != nil {
+		return fmt.Errorf("cannot uninstall release %s: %w", releaseName, err)
+	}
+	return nil
 }
 
-// PluginsVersion ...
-func PluginsVersion() error {
-	fmt.Println("helm plugins version:")
-	cmd := exec.Command("helm", "plugin", "list")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// RepoAdd ...
+func (c *Client) RepoAdd(name string, url string) error {
+	if runner.DryRun("helm repo add %s %s", name, url) {
+		return nil
+	}
+	repoFile := c.settings.RepositoryConfig
+	err := os.MkdirAll(filepath.Dir(repoFile), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := repo.LoadFile(repoFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("cannot load helm repositories file: %w", err)
+	}
+	if file == nil || errors.Is(err, fs.ErrNotExist) {
+		file = repo.NewFile()
+	}
+	entry := &repo.Entry{Name: name, URL: url, CAFile: certs.BundleFile()}
+	chartRepository, err := repo.NewChartRepository(entry, c.getters())
+	if err != nil {
+		return err
+	}
+	chartRepository.CachePath = c.settings.RepositoryCache
+	_, err = chartRepository.DownloadIndexFile()
+	if err != nil {
+		return fmt.Errorf("looks like %q is not a valid chart repository or cannot be reached: %w", url, err)
+	}
+	file.Update(entry)
+	err = file.WriteFile(repoFile, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot write helm repositories file: %w", err)
+	}
+	fmt.Printf("%q has been added to your repositories\n", name)
+	return nil
 }
 
-// DownloadExecutable ...
-func DownloadExecutable(helmURL string, helmVersion string) error {
-	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
-	if _, err := os.Stat(localFile); os.IsNotExist(err) {
-		fileMap := &download.FileMap{Src: "windows-amd64" + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME, Dst: LOCAL_EXECUTABLE_NAME}
-		_, err = download.FromUrl(helmURL, helmVersion, "helm", []*download.FileMap{fileMap}, filepath.Dir(localFile))
+// RepoUpdate ...
+func (c *Client) RepoUpdate() error {
+	if runner.DryRun("helm repo update") {
+		return nil
+	}
+	file, err := repo.LoadFile(c.settings.RepositoryConfig)
+	if err != nil {
+		return fmt.Errorf("cannot load helm repositories file: %w", err)
+	}
+	fmt.Println("Hang tight while we grab the latest from your chart repositories...")
+	var failed []string
+	for _, entry := range file.Repositories {
+		if len(entry.CAFile) == 0 {
+			entry.CAFile = certs.BundleFile()
+		}
+		chartRepository, err := repo.NewChartRepository(entry, c.getters())
+		if err != nil {
+			return err
+		}
+		chartRepository.CachePath = c.settings.RepositoryCache
+		_, err = chartRepository.DownloadIndexFile()
 		if err != nil {
-			return nil
+			fmt.Printf("...Unable to get an update from the %q chart repository (%s):\n\t%s\n", entry.Name, entry.URL, err)
+			failed = append(failed, entry.URL)
+		} else {
+			fmt.Printf("...Successfully got an update from the %q chart repository\n", entry.Name)
 		}
 	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to update the following repositories: %s", failed)
+	}
+	fmt.Println("Update Complete.")
 	return nil
 }
 
-// DeleteExecutable ...
-func DeleteExecutable() error {
-	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
-	return os.RemoveAll(localFile)
+func debugLog(format string, v ...interface{}) {
+	if os.Getenv("HELM_DEBUG") != "" {
+		fmt.Printf(format+"\n", v...)
+	}
+}
+
+// PluginsVersion ...
+func PluginsVersion() error {
+	fmt.Println("helm plugins version:")
+	return runner.Stream("helm", "plugin", "list")
 }
 
 // DeleteWorkingDirectory ...
 func DeleteWorkingDirectory() error {
 	// This directory contains helm plugins and repo definitions and caches
-	return os.RemoveAll(utils.GetAppDataHome() + string(os.PathSeparator) + "helm")
+	return utils.RemoveAll(utils.GetAppDataHome() + string(os.PathSeparator) + "helm")
 }
 
 // ResetWorkingDirectory ...
 func ResetWorkingDirectory() error {
-	err := os.RemoveAll(utils.GetAppDataHome() + string(os.PathSeparator) + "helm" + string(os.PathSeparator) + "repositories.yaml")
+	err := utils.RemoveAll(utils.GetAppDataHome() + string(os.PathSeparator) + "helm" + string(os.PathSeparator) + "repositories.yaml")
 	if err != nil {
 		return err
 	}
-	err = os.RemoveAll(utils.GetAppDataHome() + string(os.PathSeparator) + "helm" + string(os.PathSeparator) + "repositories.lock")
+	err = utils.RemoveAll(utils.GetAppDataHome() + string(os.PathSeparator) + "helm" + string(os.PathSeparator) + "repositories.lock")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,132 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// newFakeClient returns a client whose releases are stored in memory and whose resources are never sent to a cluster
+func newFakeClient(t *testing.T) *Client {
+	settings := cli.New()
+	settings.RepositoryConfig = filepath.Join(t.TempDir(), "repositories.yaml")
+	settings.RepositoryCache = filepath.Join(t.TempDir(), "cache")
+	return NewClientWithConfiguration(settings, &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(format string, v ...interface{}) {},
+	})
+}
+
+// writeChart writes a local chart holding a config map named after the name value
+func writeChart(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "demo")
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: demo\nversion: 1.0.0\n",
+		"values.yaml":              "name: default\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name }}\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return dir
+}
+
+func TestUpgrade(t *testing.T) {
+	client := newFakeClient(t)
+	chart := writeChart(t)
+	tests := []struct {
+		name          string
+		configuration string
+		version       int
+	}{
+		{"install", "name=first", 1},
+		{"upgrade", "name=second", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel, err := client.Upgrade(chart, "", "demo", "default", tt.configuration, "", 0)
+			if err != nil {
+				t.Fatalf("Upgrade() error = %v", err)
+			}
+			if rel.Version != tt.version {
+				t.Errorf("Upgrade() version = %d, want %d", rel.Version, tt.version)
+			}
+			if rel.Info.Status != release.StatusDeployed {
+				t.Errorf("Upgrade() status = %s, want %s", rel.Info.Status, release.StatusDeployed)
+			}
+			if got := rel.Config["name"]; got != tt.configuration[len("name="):] {
+				t.Errorf("Upgrade() name value = %v, want %s", got, tt.configuration[len("name="):])
+			}
+		})
+	}
+}
+
+func TestUpgradeMissingChart(t *testing.T) {
+	client := newFakeClient(t)
+	_, err := client.Upgrade(filepath.Join(t.TempDir(), "missing"), "", "demo", "default", "", "", 0)
+	if err == nil {
+		t.Errorf("Upgrade() error = nil, want missing chart error")
+	}
+}
+
+func TestStatusAndUninstall(t *testing.T) {
+	client := newFakeClient(t)
+	_, err := client.Status("demo", "default")
+	if err == nil {
+		t.Fatalf("Status() error = nil, want release not found")
+	}
+	_, err = client.Upgrade(writeChart(t), "", "demo", "default", "", "", 0)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	rel, err := client.Status("demo", "default")
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if rel.Name != "demo" || rel.Info.Status != release.StatusDeployed {
+		t.Errorf("Status() = %s %s, want demo %s", rel.Name, rel.Info.Status, release.StatusDeployed)
+	}
+	err = client.Uninstall("demo", "default")
+	if err != nil {
+		t.Fatalf("Uninstall() error = %v", err)
+	}
+	_, err = client.Status("demo", "default")
+	if err == nil {
+		t.Errorf("Status() error = nil after Uninstall(), want release not found")
+	}
+	err = client.Uninstall("demo", "default")
+	if err == nil {
+		t.Errorf("Uninstall() error = nil, want release not found")
+	}
+}
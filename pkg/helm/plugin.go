@@ -0,0 +1,338 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/extract"
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/utils"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/plugin"
+)
+
+const (
+	DEFAULT_SPRAY_URL = "https://github.com/ThalesGroup/helm-spray/releases/download/%s/helm-spray-windows-amd64.tar.gz"
+	DEFAULT_IMAGE_URL = "https://github.com/ThalesGroup/helm-image/releases/download/%s/helm-image-windows-amd64.tar.gz"
+	DEFAULT_PUSH_URL  = "https://github.com/chartmuseum/helm-push/releases/download/v%s/helm-push_%s_windows_amd64.tar.gz"
+)
+
+// Plugin declares a helm plugin installed with helm plugin install
+type Plugin struct {
+	Name string `mapstructure:"name"`
+	// PluginName is the name declared by the plugin itself in its plugin.yaml (name by default)
+	PluginName string `mapstructure:"plugin-name"`
+	// Source is either a plugin archive URL template, each %s being replaced by the version, or a VCS repository URL
+	Source  string `mapstructure:"source"`
+	Version string `mapstructure:"version"`
+	// SHA256 is the expected checksum of the plugin archive (optional)
+	SHA256 string `mapstructure:"sha256"`
+}
+
+// builtinPlugins are the plugins always installed by gokube, their source and version being provided by BuiltinPlugin callers
+var builtinPlugins = []*Plugin{
+	{Name: "helm-spray", PluginName: "spray"},
+	{Name: "helm-image", PluginName: "image"},
+	{Name: "helm-push", PluginName: "cm-push"},
+}
+
+// BuiltinPlugin returns the given built-in plugin with the given source and version, or nil if it does not exist
+func BuiltinPlugin(name string, source string, version string) *Plugin {
+	for _, p := range builtinPlugins {
+		if p.Name == name {
+			builtin := *p
+			builtin.Source = source
+			builtin.Version = version
+			return &builtin
+		}
+	}
+	return nil
+}
+
+// IsBuiltinPlugin returns true if the given name is the one of a built-in plugin
+func IsBuiltinPlugin(name string) bool {
+	return BuiltinPlugin(name, "", "") != nil
+}
+
+// Validate checks that the mandatory fields are set
+func (p *Plugin) Validate() error {
+	if len(p.Name) == 0 {
+		return errors.New("plugin name is missing")
+	}
+	if len(p.Source) == 0 {
+		return fmt.Errorf("%s source is missing", p.Name)
+	}
+	if len(p.Version) == 0 {
+		return fmt.Errorf("%s version is missing", p.Name)
+	}
+	return nil
+}
+
+// HelmName returns the name of the plugin for helm
+func (p *Plugin) HelmName() string {
+	if len(p.PluginName) > 0 {
+		return p.PluginName
+	}
+	return p.Name
+}
+
+// IsArchive returns true if the plugin source is an archive URL, otherwise it is a VCS repository installed with the plugin version
+func (p *Plugin) IsArchive() bool {
+	source := strings.ToLower(p.Source)
+	return strings.HasSuffix(source, ".tar.gz") || strings.HasSuffix(source, ".tgz")
+}
+
+// URL returns the plugin source for the plugin version
+func (p *Plugin) URL() string {
+	if p.IsArchive() {
+		return download.URL(p.Source, p.Version)
+	}
+	return p.Source
+}
+
+// PluginsDirectory returns the directory where helm installs plugins
+func PluginsDirectory() string {
+	return cli.New().PluginsDirectory
+}
+
+// InstalledPlugins returns the installed helm plugins by name
+func InstalledPlugins() (map[string]*plugin.Plugin, error) {
+	plugins, err := plugin.FindPlugins(PluginsDirectory())
+	if err != nil {
+		return nil, fmt.Errorf("cannot list helm plugins: %w", err)
+	}
+	installed := map[string]*plugin.Plugin{}
+	for _, p := range plugins {
+		installed[p.Metadata.Name] = p
+	}
+	return installed, nil
+}
+
+// InstalledVersion returns the version of the installed plugin, or an empty string if it is not installed
+func (p *Plugin) InstalledVersion() (string, error) {
+	installed, err := InstalledPlugins()
+	if err != nil {
+		return "", err
+	}
+	if i, ok := installed[p.HelmName()]; ok {
+		return i.Metadata.Version, nil
+	}
+	return "", nil
+}
+
+// expectedChecksum returns the expected checksum of the plugin archive, which is either configured or locked
+func (p *Plugin) expectedChecksum() (string, error) {
+	url := p.URL()
+	expected, err := download.ExpectedChecksum(url)
+	if err != nil {
+		return "", err
+	}
+	if len(p.SHA256) > 0 {
+		if len(expected) > 0 && !strings.EqualFold(expected, p.SHA256) {
+			return "", fmt.Errorf("configured SHA-256 of %s does not match locked one %s", url, expected)
+		}
+		expected = p.SHA256
+	}
+	return expected, nil
+}
+
+// sourcesDirectory returns the directory where plugin archives are extracted, helm linking installed plugins to them
+func sourcesDirectory() string {
+	return filepath.Join(filepath.Dir(PluginsDirectory()), "plugins-sources")
+}
+
+// download downloads the plugin archive, verifies its checksum and extracts it into the plugin sources directory,
+// so that helm installs exactly the verified content. It returns the extracted plugin directory.
+func (p *Plugin) download() (string, error) {
+	url := p.URL()
+	expected, err := p.expectedChecksum()
+	if err != nil {
+		return "", err
+	}
+	// Directory is named after the plugin, as helm names installed plugins after their source directory
+	pluginDir := filepath.Join(sourcesDirectory(), p.HelmName(), p.Version, p.HelmName())
+	if runner.DryRun("download %s and extract it to %s", url, pluginDir) {
+		return pluginDir, nil
+	}
+	err = os.MkdirAll(sourcesDirectory(), 0755)
+	if err != nil {
+		return "", err
+	}
+	// Temporary directory is on the same volume as the sources directory, so that the plugin can be moved there
+	tempDir, err := os.MkdirTemp(sourcesDirectory(), ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer utils.DeleteDir(tempDir)
+	archive, err := download.ToFile(url, p.Name, tempDir, expected)
+	if err != nil {
+		return "", err
+	}
+	extracted := filepath.Join(tempDir, "extracted")
+	_, err = extract.Extract(archive, extracted, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot extract %s: %w", url, err)
+	}
+	root, err := pluginRoot(extracted)
+	if err != nil {
+		return "", fmt.Errorf("invalid plugin archive %s: %w", url, err)
+	}
+	err = os.RemoveAll(pluginDir)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(filepath.Dir(pluginDir), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(root, pluginDir)
+	if err != nil {
+		return "", err
+	}
+	return pluginDir, nil
+}
+
+// pluginRoot returns the directory holding plugin.yaml, either the given one or its single subdirectory
+func pluginRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, plugin.PluginFileName)); err == nil {
+		return dir, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		subDir := filepath.Join(dir, entries[0].Name())
+		if _, err := os.Stat(filepath.Join(subDir, plugin.PluginFileName)); err == nil {
+			return subDir, nil
+		}
+	}
+	return "", fmt.Errorf("no %s found", plugin.PluginFileName)
+}
+
+// removeSources removes the extracted archives of the plugin, except the one of the given version
+func removeSources(helmName string, keptVersion string) error {
+	dir := filepath.Join(sourcesDirectory(), helmName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() != keptVersion {
+			err = utils.RemoveAll(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Install installs the plugin with helm plugin install, the installed version being only removed once the new one is installed.
+// Archives are downloaded and verified by gokube, helm installing the extracted plugin.
+func (p *Plugin) Install() error {
+	source := p.URL()
+	if p.IsArchive() {
+		var err error
+		source, err = p.download()
+		if err != nil {
+			return err
+		}
+	}
+	installed, err := InstalledPlugins()
+	if err != nil {
+		return err
+	}
+	var backupDir string
+	if i, ok := installed[p.HelmName()]; ok {
+		backupDir, err = backupPlugin(i.Dir)
+		if err != nil {
+			return err
+		}
+	}
+	args := []string{"plugin", "install", source}
+	if !p.IsArchive() {
+		args = append(args, "--version", p.Version)
+	}
+	err = runner.Stream("helm", args...)
+	if err != nil {
+		if len(backupDir) > 0 {
+			restoreErr := restorePlugin(backupDir)
+			if restoreErr != nil {
+				fmt.Printf("Warning: cannot restore previous %s plugin from %s: %s\n", p.Name, backupDir, restoreErr)
+			}
+		}
+		return err
+	}
+	if len(backupDir) > 0 {
+		err = utils.RemoveAll(backupDir)
+		if err != nil {
+			return err
+		}
+	}
+	keptVersion := ""
+	if p.IsArchive() {
+		keptVersion = p.Version
+	}
+	return removeSources(p.HelmName(), keptVersion)
+}
+
+// UninstallPlugin uninstalls the given helm plugin, and removes its extracted archive if any
+func UninstallPlugin(helmName string) error {
+	err := runner.Stream("helm", "plugin", "uninstall", helmName)
+	if err != nil {
+		return err
+	}
+	return removeSources(helmName, "")
+}
+
+// backupPlugin moves the given plugin directory out of helm plugins directory, so that helm does not see it anymore
+func backupPlugin(pluginDir string) (string, error) {
+	backupDir := filepath.Join(filepath.Dir(PluginsDirectory()), "plugins-backup", filepath.Base(pluginDir))
+	if runner.DryRun("move %s to %s", pluginDir, backupDir) {
+		return backupDir, nil
+	}
+	err := os.RemoveAll(backupDir)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(filepath.Dir(backupDir), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(pluginDir, backupDir)
+	if err != nil {
+		return "", fmt.Errorf("cannot backup %s: %w", pluginDir, err)
+	}
+	return backupDir, nil
+}
+
+func restorePlugin(backupDir string) error {
+	pluginDir := filepath.Join(PluginsDirectory(), filepath.Base(backupDir))
+	if runner.DryRun("move %s to %s", backupDir, pluginDir) {
+		return nil
+	}
+	_ = os.RemoveAll(pluginDir)
+	return os.Rename(backupDir, pluginDir)
+}
@@ -0,0 +1,296 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gemalto/gokube/pkg/certs"
+	"github.com/gemalto/gokube/pkg/proxy"
+)
+
+const (
+	DEFAULT_CONNECT_TIMEOUT         = 30 * time.Second
+	DEFAULT_RESPONSE_HEADER_TIMEOUT = 60 * time.Second
+)
+
+// TLS holds the TLS settings of gokube HTTP clients
+type TLS struct {
+	// MinVersion is the minimum TLS version, 1.2 or 1.3 (1.2 by default)
+	MinVersion         string `mapstructure:"min-version"`
+	InsecureSkipVerify bool   `mapstructure:"insecure-skip-verify"`
+	// ClientCert and ClientKey are the PEM files of the client certificate, for servers requiring mutual TLS
+	ClientCert string `mapstructure:"client-cert"`
+	ClientKey  string `mapstructure:"client-key"`
+}
+
+// Host holds the credentials and headers sent to the URLs starting with URL
+type Host struct {
+	URL      string `mapstructure:"url"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Token is sent as a bearer token, instead of username and password
+	Token   string            `mapstructure:"token"`
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// Config is the configuration of gokube HTTP clients, values of credentials and headers being expanded with environment variables
+type Config struct {
+	// Timeout limits the whole request, including the response body download (no limit by default)
+	Timeout               time.Duration     `mapstructure:"timeout"`
+	ConnectTimeout        time.Duration     `mapstructure:"connect-timeout"`
+	ResponseHeaderTimeout time.Duration     `mapstructure:"response-header-timeout"`
+	UserAgent             string            `mapstructure:"user-agent"`
+	Headers               map[string]string `mapstructure:"headers"`
+	// Netrc enables HTTPS basic authentication with the credentials of NetrcFile (NETRC, ~/_netrc or ~/.netrc by default)
+	Netrc     bool   `mapstructure:"netrc"`
+	NetrcFile string `mapstructure:"netrc-file"`
+	// ProxyUsername and ProxyPassword authenticate gokube to the proxy, unless its URL holds credentials
+	ProxyUsername string `mapstructure:"proxy-username"`
+	ProxyPassword string `mapstructure:"proxy-password"`
+	TLS           TLS    `mapstructure:"tls"`
+	Hosts         []Host `mapstructure:"hosts"`
+}
+
+// config is the configuration of the clients returned by New
+var config = &Config{}
+
+// SetConfig validates the given configuration and makes it the one of the clients returned by New
+func SetConfig(c *Config) error {
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+	config = c.expand()
+	return nil
+}
+
+// Validate checks the configuration consistency
+func (c *Config) Validate() error {
+	if _, err := c.minTLSVersion(); err != nil {
+		return err
+	}
+	if (len(c.TLS.ClientCert) == 0) != (len(c.TLS.ClientKey) == 0) {
+		return errors.New("TLS client certificate and key must be both set")
+	}
+	if len(c.TLS.ClientCert) > 0 {
+		if _, err := tls.LoadX509KeyPair(c.TLS.ClientCert, c.TLS.ClientKey); err != nil {
+			return fmt.Errorf("cannot load TLS client certificate: %w", err)
+		}
+	}
+	for _, h := range c.Hosts {
+		u, err := url.Parse(h.URL)
+		if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return fmt.Errorf("invalid host URL %q", h.URL)
+		}
+		if len(h.Token) > 0 && len(h.Username) > 0 {
+			return fmt.Errorf("%s cannot have both a token and a username", h.URL)
+		}
+	}
+	return nil
+}
+
+// expand returns a copy of the configuration with credentials and headers expanded with environment variables
+func (c *Config) expand() *Config {
+	expanded := *c
+	expanded.ProxyUsername = os.ExpandEnv(c.ProxyUsername)
+	expanded.ProxyPassword = os.ExpandEnv(c.ProxyPassword)
+	expanded.Headers = expandHeaders(c.Headers)
+	expanded.Hosts = nil
+	for _, h := range c.Hosts {
+		expanded.Hosts = append(expanded.Hosts, Host{
+			URL:      h.URL,
+			Username: os.ExpandEnv(h.Username),
+			Password: os.ExpandEnv(h.Password),
+			Token:    os.ExpandEnv(h.Token),
+			Headers:  expandHeaders(h.Headers),
+		})
+	}
+	return &expanded
+}
+
+func expandHeaders(headers map[string]string) map[string]string {
+	expanded := map[string]string{}
+	for name, value := range headers {
+		expanded[name] = os.ExpandEnv(value)
+	}
+	return expanded
+}
+
+func (c *Config) minTLSVersion() (uint16, error) {
+	switch c.TLS.MinVersion {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("invalid TLS minimum version %q, must be 1.2 or 1.3", c.TLS.MinVersion)
+}
+
+// New returns an HTTP client using gokube configuration: proxy, CA bundle, timeouts, TLS settings, credentials and headers
+func New() *http.Client {
+	return NewWithConfig(config)
+}
+
+// NewWithConfig returns an HTTP client using the given configuration, besides gokube proxy and CA bundle
+func NewWithConfig(c *Config) *http.Client {
+	return &http.Client{Timeout: c.Timeout, Transport: &authTransport{config: c, next: c.transport()}}
+}
+
+// Transport returns an HTTP transport using gokube proxy, CA bundle, timeouts and TLS settings, for clients which cannot
+// use the ones returned by New: credentials and headers are not added to its requests
+func Transport() *http.Transport {
+	return config.transport()
+}
+
+// BasicAuth returns the configured basic authentication credentials of the given URL, if any
+func BasicAuth(rawURL string) (string, string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", false
+	}
+	return config.basicAuth(u)
+}
+
+func (c *Config) transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: DEFAULT_CONNECT_TIMEOUT, KeepAlive: 30 * time.Second}
+	if c.ConnectTimeout > 0 {
+		dialer.Timeout = c.ConnectTimeout
+	}
+	transport.DialContext = dialer.DialContext
+	transport.ResponseHeaderTimeout = DEFAULT_RESPONSE_HEADER_TIMEOUT
+	if c.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = c.ResponseHeaderTimeout
+	}
+	transport.Proxy = c.proxyFunc()
+	transport.TLSClientConfig = c.tlsConfig()
+	return transport
+}
+
+// proxyFunc returns gokube proxy selection function, adding the configured proxy credentials
+func (c *Config) proxyFunc() func(*http.Request) (*url.URL, error) {
+	proxyFunc := proxy.Func()
+	if len(c.ProxyUsername) == 0 {
+		return proxyFunc
+	}
+	return func(request *http.Request) (*url.URL, error) {
+		proxyURL, err := proxyFunc(request)
+		if err != nil || proxyURL == nil || proxyURL.User != nil {
+			return proxyURL, err
+		}
+		authenticated := *proxyURL
+		authenticated.User = url.UserPassword(c.ProxyUsername, c.ProxyPassword)
+		return &authenticated, nil
+	}
+}
+
+// tlsConfig returns the TLS configuration trusting the gokube CA bundle in addition to system CA certificates
+func (c *Config) tlsConfig() *tls.Config {
+	minVersion, _ := c.minTLSVersion()
+	tlsConfig := &tls.Config{MinVersion: minVersion, InsecureSkipVerify: c.TLS.InsecureSkipVerify}
+	pool, err := certs.CertPool()
+	if err != nil {
+		fmt.Printf("Warning: cannot load CA bundle, using system CA certificates only: %s\n", err)
+	} else {
+		tlsConfig.RootCAs = pool
+	}
+	if len(c.TLS.ClientCert) > 0 {
+		cert, err := tls.LoadX509KeyPair(c.TLS.ClientCert, c.TLS.ClientKey)
+		if err != nil {
+			fmt.Printf("Warning: cannot load TLS client certificate: %s\n", err)
+		} else {
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+	return tlsConfig
+}
+
+// host returns the host configuration with the longest URL prefix of the given URL, or nil if none matches
+func (c *Config) host(u *url.URL) *Host {
+	var matched *Host
+	matchedLength := -1
+	for i, h := range c.Hosts {
+		prefix, err := url.Parse(h.URL)
+		if err != nil || !strings.EqualFold(prefix.Scheme, u.Scheme) || !strings.EqualFold(prefix.Host, u.Host) {
+			continue
+		}
+		path := strings.TrimSuffix(prefix.Path, "/")
+		if u.Path != path && !strings.HasPrefix(u.Path, path+"/") {
+			continue
+		}
+		if len(path) > matchedLength {
+			matched = &c.Hosts[i]
+			matchedLength = len(path)
+		}
+	}
+	return matched
+}
+
+// basicAuth returns the username and password of the host configuration of the given URL, or else of the netrc file
+// for HTTPS URLs
+func (c *Config) basicAuth(u *url.URL) (string, string, bool) {
+	if h := c.host(u); h != nil {
+		return h.Username, h.Password, len(h.Username) > 0
+	}
+	if c.Netrc && u.Scheme == "https" {
+		return netrcCredentials(c.NetrcFile, u.Hostname())
+	}
+	return "", "", false
+}
+
+// authTransport adds the configured headers and credentials to each request, redirections included,
+// credentials being only sent to their host
+type authTransport struct {
+	config *Config
+	next   http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	c := t.config
+	request = request.Clone(request.Context())
+	if len(c.UserAgent) > 0 && len(request.Header.Get("User-Agent")) == 0 {
+		request.Header.Set("User-Agent", c.UserAgent)
+	}
+	setHeaders(request, c.Headers)
+	h := c.host(request.URL)
+	if h != nil {
+		setHeaders(request, h.Headers)
+	}
+	if len(request.Header.Get("Authorization")) == 0 {
+		if h != nil && len(h.Token) > 0 {
+			request.Header.Set("Authorization", "Bearer "+h.Token)
+		} else if username, password, ok := c.basicAuth(request.URL); ok {
+			request.SetBasicAuth(username, password)
+		}
+	}
+	return t.next.RoundTrip(request)
+}
+
+func setHeaders(request *http.Request, headers map[string]string) {
+	for name, value := range headers {
+		if len(request.Header.Get(name)) == 0 {
+			request.Header.Set(name, value)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newHeadersServer returns a server recording the headers of the last request, redirecting /redirect to target
+func newHeadersServer(t *testing.T, tls bool, target string) (*httptest.Server, *http.Header) {
+	headers := &http.Header{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*headers = r.Header.Clone()
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, target, http.StatusFound)
+		}
+	})
+	var server *httptest.Server
+	if tls {
+		server = httptest.NewTLSServer(handler)
+	} else {
+		server = httptest.NewServer(handler)
+	}
+	t.Cleanup(server.Close)
+	return server, headers
+}
+
+func get(t *testing.T, c *Config, url string) {
+	response, err := NewWithConfig(c).Get(url)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = response.Body.Close()
+}
+
+func TestNewWithConfig(t *testing.T) {
+	other, otherHeaders := newHeadersServer(t, false, "")
+	server, headers := newHeadersServer(t, false, other.URL+"/other")
+	t.Setenv("GOKUBE_TEST_PASSWORD", "secret")
+	c := (&Config{
+		UserAgent: "gokube-test",
+		Headers:   map[string]string{"X-Global": "global"},
+		Hosts: []Host{
+			{URL: server.URL, Username: "user", Password: "${GOKUBE_TEST_PASSWORD}", Headers: map[string]string{"X-Host": "host"}},
+			{URL: server.URL + "/token/", Token: "token"},
+		},
+	}).expand()
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+	}{
+		{"basic", "/charts/index.yaml", "Basic dXNlcjpzZWNyZXQ="},
+		{"longest prefix", "/token/index.yaml", "Bearer token"},
+		{"prefix at path boundary", "/tokens", "Basic dXNlcjpzZWNyZXQ="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			get(t, c, server.URL+tt.path)
+			if got := headers.Get("Authorization"); got != tt.authorization {
+				t.Errorf("Authorization = %q, want %q", got, tt.authorization)
+			}
+			if got := headers.Get("User-Agent"); got != "gokube-test" {
+				t.Errorf("User-Agent = %q, want gokube-test", got)
+			}
+			if got := headers.Get("X-Global"); got != "global" {
+				t.Errorf("X-Global = %q, want global", got)
+			}
+		})
+	}
+	if got := headers.Get("X-Host"); got != "host" {
+		t.Errorf("X-Host = %q, want host", got)
+	}
+
+	get(t, c, server.URL+"/redirect")
+	if got := otherHeaders.Get("Authorization"); len(got) > 0 {
+		t.Errorf("Authorization sent to redirected host = %q, want none", got)
+	}
+	if got := otherHeaders.Get("X-Host"); len(got) > 0 {
+		t.Errorf("X-Host sent to redirected host = %q, want none", got)
+	}
+	if got := otherHeaders.Get("X-Global"); got != "global" {
+		t.Errorf("X-Global sent to redirected host = %q, want global", got)
+	}
+}
+
+func TestNewWithConfigNetrc(t *testing.T) {
+	server, headers := newHeadersServer(t, false, "")
+	tlsServer, tlsHeaders := newHeadersServer(t, true, "")
+	netrc := filepath.Join(t.TempDir(), ".netrc")
+	content := "machine 127.0.0.1 login user password secret\n"
+	if err := os.WriteFile(netrc, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	c := &Config{Netrc: true, NetrcFile: netrc, TLS: TLS{InsecureSkipVerify: true}}
+
+	get(t, c, tlsServer.URL)
+	if got := tlsHeaders.Get("Authorization"); got != "Basic dXNlcjpzZWNyZXQ=" {
+		t.Errorf("HTTPS Authorization = %q, want netrc credentials", got)
+	}
+	get(t, c, server.URL)
+	if got := headers.Get("Authorization"); len(got) > 0 {
+		t.Errorf("HTTP Authorization = %q, want none", got)
+	}
+
+	// Configured hosts take precedence over netrc
+	c.Hosts = []Host{{URL: tlsServer.URL, Token: "token"}}
+	get(t, c, tlsServer.URL)
+	if got := tlsHeaders.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("HTTPS Authorization = %q, want Bearer token", got)
+	}
+	c.Netrc = false
+	c.Hosts = nil
+	get(t, c, tlsServer.URL)
+	if got := tlsHeaders.Get("Authorization"); len(got) > 0 {
+		t.Errorf("Authorization with netrc disabled = %q, want none", got)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	previous := config
+	t.Cleanup(func() { config = previous })
+	config = &Config{Hosts: []Host{
+		{URL: "https://charts.example.com/private", Username: "user", Password: "secret"},
+		{URL: "https://registry.example.com", Token: "token"},
+	}}
+	tests := []struct {
+		url      string
+		username string
+		ok       bool
+	}{
+		{"https://charts.example.com/private/index.yaml", "user", true},
+		{"https://charts.example.com/public/index.yaml", "", false},
+		{"http://charts.example.com/private/index.yaml", "", false},
+		{"https://registry.example.com/v2/", "", false},
+	}
+	for _, tt := range tests {
+		username, _, ok := BasicAuth(tt.url)
+		if username != tt.username || ok != tt.ok {
+			t.Errorf("BasicAuth(%s) = %q, %v, want %q, %v", tt.url, username, ok, tt.username, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+// netrcFile returns the given netrc file, or else NETRC, ~/_netrc or ~/.netrc
+func netrcFile(file string) string {
+	if len(file) > 0 {
+		return file
+	}
+	if env := os.Getenv("NETRC"); len(env) > 0 {
+		return env
+	}
+	file = filepath.Join(utils.GetUserHome(), "_netrc")
+	if _, err := os.Stat(file); err == nil {
+		return file
+	}
+	return filepath.Join(utils.GetUserHome(), ".netrc")
+}
+
+// netrcCredentials returns the login and password of the given machine in the netrc file, or of its default entry
+func netrcCredentials(file string, machine string) (string, string, bool) {
+	content, err := os.ReadFile(netrcFile(file))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Warning: cannot read netrc file: %s\n", err)
+		}
+		return "", "", false
+	}
+	var login, password string
+	var found, inMachine, inMacro bool
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Macro definitions end with an empty line
+		if inMacro {
+			inMacro = len(strings.TrimSpace(line)) > 0
+			continue
+		}
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			switch fields[i] {
+			case "machine", "default":
+				if found {
+					return login, password, true
+				}
+				inMachine = fields[i] == "default"
+				if !inMachine && i+1 < len(fields) {
+					i++
+					inMachine = strings.EqualFold(fields[i], machine)
+				}
+				found = inMachine
+				login, password = "", ""
+			case "login", "password", "account":
+				if i+1 >= len(fields) {
+					continue
+				}
+				i++
+				if inMachine && fields[i-1] == "login" {
+					login = fields[i]
+				} else if inMachine && fields[i-1] == "password" {
+					password = fields[i]
+				}
+			case "macdef":
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+	return login, password, found
+}
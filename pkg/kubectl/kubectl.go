@@ -15,78 +15,155 @@ limitations under the License.
 package kubectl
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"syscall"
+	"time"
 
-	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/runner"
 	"github.com/gemalto/gokube/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
-	DEFAULT_URL           = "https://dl.k8s.io/%s/bin/windows/amd64/kubectl.exe"
-	LOCAL_EXECUTABLE_NAME = "kubectl.exe"
+	requestTimeout    = 10 * time.Second
+	minikubeEntryName = "minikube"
 )
 
-// Get ...
-func Get(namespace string, resourceType string, resourceName string, jsonPath string) (string, error) {
-	var args = []string{"--namespace", namespace, "get", resourceType, resourceName}
-	if len(jsonPath) > 0 {
-		args = append(args, "-o", "jsonpath="+jsonPath)
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrConnectionRefused = errors.New("connection refused")
+)
+
+// wsaeconnrefused is the Windows socket error of a refused connection, which is not syscall.ECONNREFUSED
+const wsaeconnrefused = syscall.Errno(10061)
+
+// Client communicates with the kubernetes API server through client-go
+type Client struct {
+	clientset kubernetes.Interface
+}
+
+// RestConfig loads the REST client configuration of the given kubeconfig context
+func RestConfig(contextName string) (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load kubeconfig for context %s: %w", contextName, err)
+	}
+	return config, nil
+}
+
+// NewClient creates a Client for the given kubeconfig context
+func NewClient(contextName string) (*Client, error) {
+	config, err := RestConfig(contextName)
+	if err != nil {
+		return nil, err
 	}
-	output, err := exec.Command("kubectl", args...).Output()
+	config.Timeout = requestTimeout
+	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("cannot create kubernetes client: %w", err)
 	}
-	return string(output), nil
+	return &Client{clientset: clientset}, nil
 }
 
-// ConfigUseContext ...
-func ConfigUseContext(context string) error {
-	cmd := exec.Command("kubectl", "config", "use-context", context)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// NewClientFromClientset creates a Client on top of an existing clientset (typically a fake one)
+func NewClientFromClientset(clientset kubernetes.Interface) *Client {
+	return &Client{clientset: clientset}
 }
 
-// Patch ...
-func Patch(namespace string, resourceType string, resourceName string, patch string) error {
-	cmd := exec.Command("kubectl", "--namespace", namespace, "patch", resourceType, resourceName, "-p", patch)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// GetService ...
+func (c *Client) GetService(namespace string, name string) (*corev1.Service, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	service, err := c.clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return service, nil
 }
 
-// Version ...
-func Version() error {
-	fmt.Println("kubectl version: ")
-	cmd := exec.Command("kubectl", "version")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// PatchService applies a strategic merge patch to the given service
+func (c *Client) PatchService(namespace string, name string, patch string) error {
+	if runner.DryRun("patch service %s/%s with %s", namespace, name, patch) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err := c.clientset.CoreV1().Services(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return wrapError(err)
+	}
+	return nil
 }
 
-// DownloadExecutable ...
-func DownloadExecutable(kubectlURL string, kubectlVersion string) error {
-	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
-	if _, err := os.Stat(localFile); os.IsNotExist(err) {
-		fileMap := &download.FileMap{Src: LOCAL_EXECUTABLE_NAME, Dst: LOCAL_EXECUTABLE_NAME}
-		_, err = download.FromUrl(kubectlURL, kubectlVersion, "kubectl", []*download.FileMap{fileMap}, filepath.Dir(localFile))
-		if err != nil {
-			return err
-		}
+// ConfigUseContext ...
+func ConfigUseContext(context string) error {
+	if runner.DryRun("switch kubeconfig current context to %s", context) {
+		return nil
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	config, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		return fmt.Errorf("cannot load kubeconfig: %w", err)
+	}
+	if _, ok := config.Contexts[context]; !ok {
+		return fmt.Errorf("context %s: %w", context, ErrNotFound)
 	}
+	config.CurrentContext = context
+	err = clientcmd.ModifyConfig(pathOptions, *config, true)
+	if err != nil {
+		return fmt.Errorf("cannot write kubeconfig: %w", err)
+	}
+	fmt.Printf("Switched to context %q.\n", context)
 	return nil
 }
 
-// DeleteExecutable ...
-func DeleteExecutable() error {
-	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
-	return os.RemoveAll(localFile)
+// wrapError maps client-go errors to the errors exposed by this package
+func wrapError(err error) error {
+	switch {
+	case apierrors.IsNotFound(err):
+		return fmt.Errorf("%w: %s", ErrNotFound, err)
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, wsaeconnrefused):
+		return fmt.Errorf("%w: %s", ErrConnectionRefused, err)
+	default:
+		return err
+	}
+}
+
+// DeleteMinikubeConfig removes minikube cluster, context and user from kubeconfig, keeping other entries as they are
+func DeleteMinikubeConfig() error {
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	config, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		return fmt.Errorf("cannot load kubeconfig: %w", err)
+	}
+	if runner.DryRun("remove minikube cluster, context and user from kubeconfig %s", pathOptions.GetDefaultFilename()) {
+		return nil
+	}
+	delete(config.Clusters, minikubeEntryName)
+	delete(config.Contexts, minikubeEntryName)
+	delete(config.AuthInfos, minikubeEntryName)
+	if config.CurrentContext == minikubeEntryName {
+		config.CurrentContext = ""
+	}
+	err = clientcmd.ModifyConfig(pathOptions, *config, true)
+	if err != nil {
+		return fmt.Errorf("cannot write kubeconfig: %w", err)
+	}
+	return nil
 }
 
-// DeleteWorkingDirectory ...
-func DeleteWorkingDirectory() error {
-	return utils.CleanDir(utils.GetUserHome() + "/.kube")
+// GetWorkingDirectory ...
+func GetWorkingDirectory() string {
+	return utils.GetUserHome() + string(os.PathSeparator) + ".kube"
 }
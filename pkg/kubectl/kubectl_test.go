@@ -0,0 +1,82 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetService(t *testing.T) {
+	client := NewClientFromClientset(fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "dashboard"},
+	}))
+	service, err := client.GetService("kube-system", "dashboard")
+	if err != nil {
+		t.Fatalf("GetService() error = %v", err)
+	}
+	if service.Name != "dashboard" {
+		t.Errorf("GetService() name = %s, want dashboard", service.Name)
+	}
+	_, err = client.GetService("kube-system", "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetService() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPatchService(t *testing.T) {
+	client := NewClientFromClientset(fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "dashboard"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+	}))
+	err := client.PatchService("kube-system", "dashboard", `{"spec":{"type":"NodePort"}}`)
+	if err != nil {
+		t.Fatalf("PatchService() error = %v", err)
+	}
+	service, _ := client.GetService("kube-system", "dashboard")
+	if service.Spec.Type != corev1.ServiceTypeNodePort {
+		t.Errorf("PatchService() type = %s, want NodePort", service.Spec.Type)
+	}
+	err = client.PatchService("kube-system", "missing", `{}`)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("PatchService() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestWrapErrorConnectionRefused(t *testing.T) {
+	tests := []struct {
+		name  string
+		errno syscall.Errno
+	}{
+		{"posix", syscall.ECONNREFUSED},
+		{"windows", wsaeconnrefused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("Get https://192.168.99.100:8443: %w", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connectex", tt.errno)})
+			if !errors.Is(wrapError(err), ErrConnectionRefused) {
+				t.Errorf("wrapError() = %v, want ErrConnectionRefused", wrapError(err))
+			}
+		})
+	}
+}
@@ -15,13 +15,14 @@ limitations under the License.
 package minikube
 
 import (
+	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/runner"
 	"github.com/gemalto/gokube/pkg/utils"
 )
 
@@ -31,8 +32,8 @@ const (
 )
 
 // Start ...
-func Start(memory int16, cpus int16, diskSize string, httpProxy string, httpsProxy string, noProxy string, insecureRegistry string, kubernetesVersion string, cache bool, dnsProxy bool, hostDNSResolver bool, dnsDomain string, containerRuntime string, force bool, verbose bool) error {
-	var args = []string{"start", "--kubernetes-version", kubernetesVersion, "--insecure-registry", insecureRegistry, "--memory", strconv.FormatInt(int64(memory), 10), "--cpus", strconv.FormatInt(int64(cpus), 10), "--disk-size", diskSize, "--driver=virtualbox", "--host-only-cidr=192.168.99.1/24"}
+func Start(memory int16, cpus int16, diskSize string, hostOnlyCIDR string, httpProxy string, httpsProxy string, noProxy string, insecureRegistry string, kubernetesVersion string, isoURL string, cache bool, dnsProxy bool, hostDNSResolver bool, dnsDomain string, containerRuntime string, force bool, verbose bool) error {
+	var args = []string{"start", "--kubernetes-version", kubernetesVersion, "--insecure-registry", insecureRegistry, "--memory", strconv.FormatInt(int64(memory), 10), "--cpus", strconv.FormatInt(int64(cpus), 10), "--disk-size", diskSize, "--driver=virtualbox", "--host-only-cidr=" + hostOnlyCIDR}
 	if len(httpProxy) > 0 {
 		args = append(args, "--docker-env=http_proxy="+httpProxy)
 	}
@@ -42,6 +43,9 @@ func Start(memory int16, cpus int16, diskSize string, httpProxy string, httpsPro
 	if len(noProxy) > 0 {
 		args = append(args, "--docker-env=no_proxy="+noProxy)
 	}
+	if len(isoURL) > 0 {
+		args = append(args, "--iso-url="+isoURL)
+	}
 	if !cache {
 		args = append(args, "--cache-images=false")
 	}
@@ -63,10 +67,7 @@ func Start(memory int16, cpus int16, diskSize string, httpProxy string, httpsPro
 	if verbose {
 		args = append(args, "--alsologtostderr", "--v=1")
 	}
-	cmd := exec.Command("minikube", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runner.Stream("minikube", args...)
 }
 
 // Restart ...
@@ -81,78 +82,103 @@ func Restart(kubernetesVersion string, containerRuntime string, force bool, verb
 	if verbose {
 		args = append(args, "--alsologtostderr", "--v=1")
 	}
-	cmd := exec.Command("minikube", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runner.Stream("minikube", args...)
 }
 
 // Stop ...
 func Stop() error {
-	cmd := exec.Command("minikube", "stop")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runner.Stream("minikube", "stop")
 }
 
 // Delete ...
 func Delete() error {
-	cmd := exec.Command("minikube", "delete")
-	//	cmd.Stdout = os.Stdout
-	//	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runner.Quiet("minikube", "delete")
 }
 
 // AddonsEnable ...
 func AddonsEnable(addon string) error {
-	cmd := exec.Command("minikube", "addons", "enable", addon)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runner.Stream("minikube", "addons", "enable", addon)
 }
 
 // ConfigSet ...
 func ConfigSet(key string, value string) error {
-	cmd := exec.Command("minikube", "config", "set", key, value)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runner.Stream("minikube", "config", "set", key, value)
 }
 
 // Version ...
 func Version() error {
-	cmd := exec.Command("minikube", "version")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runner.Stream("minikube", "version")
 }
 
 // Ip ...
 func Ip() (string, error) {
-	out, err := exec.Command("minikube", "ip").Output()
+	out, err := runner.Inspect("minikube", "ip")
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimRight(string(out), "\r\n"), nil
+	return strings.TrimRight(out, "\r\n"), nil
+}
+
+// Ssh runs the given command inside minikube VM
+func Ssh(command string) error {
+	return runner.Quiet("minikube", "ssh", command)
+}
+
+// SshOutput runs the given command inside minikube VM and returns its output
+func SshOutput(command string) (string, error) {
+	return runner.Output("minikube", "ssh", command)
+}
+
+// GrowFilesystem extends minikube VM data partition and filesystem to the whole disk
+func GrowFilesystem() error {
+	growCmds := []string{
+		"echo ', +' | sudo sfdisk --no-reread --force -N 1 /dev/sda",
+		"sudo partx -u /dev/sda",
+		"sudo resize2fs /dev/sda1",
+	}
+	for _, cmd := range growCmds {
+		err := Ssh(cmd)
+		if err != nil {
+			return fmt.Errorf("error running command '%s': %w", cmd, err)
+		}
+	}
+	return nil
 }
 
 // DownloadExecutable ...
 func DownloadExecutable(minikubeURL string, minikubeVersion string) error {
 	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
 	if _, err := os.Stat(localFile); os.IsNotExist(err) {
-		fileMap := &download.FileMap{Src: "minikube-windows-amd64.exe", Dst: LOCAL_EXECUTABLE_NAME}
-		_, err = download.FromUrl(minikubeURL, minikubeVersion, "minikube", []*download.FileMap{fileMap}, filepath.Dir(localFile))
-		if err != nil {
-			return err
-		}
+		return UpgradeExecutable(minikubeURL, minikubeVersion)
 	}
 	return nil
 }
 
+// UpgradeExecutable downloads the given minikube version, replacing the installed executable only once the download succeeded
+func UpgradeExecutable(minikubeURL string, minikubeVersion string) error {
+	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
+	fileMap := &download.FileMap{Src: "minikube-windows-amd64.exe", Dst: LOCAL_EXECUTABLE_NAME}
+	_, err := download.FromUrl(minikubeURL, minikubeVersion, "minikube", []*download.FileMap{fileMap}, filepath.Dir(localFile), nil)
+	return err
+}
+
+// InstalledVersion returns the version of the installed minikube executable, or an empty string if it is not installed
+func InstalledVersion() (string, error) {
+	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
+	if _, err := os.Stat(localFile); os.IsNotExist(err) {
+		return "", nil
+	}
+	out, err := runner.Inspect(localFile, "version", "--short")
+	if err != nil {
+		return "", err
+	}
+	return utils.ParseVersion(out), nil
+}
+
 // DeleteExecutable ...
 func DeleteExecutable() error {
 	localFile := utils.GetBinDir("gokube") + string(os.PathSeparator) + LOCAL_EXECUTABLE_NAME
-	return os.RemoveAll(localFile)
+	return utils.RemoveAll(localFile)
 }
 
 // DeleteWorkingDirectory ...
@@ -0,0 +1,50 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minikube
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MountSharedFolder mounts the given VirtualBox shared folder on the guest path (nothing is done if already mounted)
+func MountSharedFolder(name string, guestPath string) error {
+	mountCmds := []string{
+		"sudo mkdir -p " + shellQuote(guestPath),
+		"mountpoint -q " + shellQuote(guestPath) + " || sudo mount -t vboxsf -o uid=$(id -u),gid=$(id -g) " + shellQuote(name) + " " + shellQuote(guestPath),
+	}
+	for _, cmd := range mountCmds {
+		err := Ssh(cmd)
+		if err != nil {
+			return fmt.Errorf("error running command '%s': %w", cmd, err)
+		}
+	}
+	return nil
+}
+
+// UnmountSharedFolder unmounts the given guest path (nothing is done if not mounted)
+func UnmountSharedFolder(guestPath string) error {
+	cmd := "! mountpoint -q " + shellQuote(guestPath) + " || sudo umount " + shellQuote(guestPath)
+	err := Ssh(cmd)
+	if err != nil {
+		return fmt.Errorf("error running command '%s': %w", cmd, err)
+	}
+	return nil
+}
+
+// shellQuote quotes the given value as a single word of minikube VM shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,117 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minikube
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/gemalto/gokube/pkg/runner"
+)
+
+// ssh replays the given minikube VM commands and returns the recorder, restoring the default runner at the end of the test
+func ssh(t *testing.T, responses map[string]*runner.Response) *runner.Recorder {
+	t.Helper()
+	recorded := map[string]*runner.Response{}
+	for cmd, response := range responses {
+		recorded[(&runner.Command{Name: "minikube", Args: []string{"ssh", cmd}}).String()] = response
+	}
+	recorder := &runner.Recorder{Responses: recorded}
+	previous := runner.Default()
+	runner.SetDefault(recorder)
+	t.Cleanup(func() { runner.SetDefault(previous) })
+	return recorder
+}
+
+// sshCommands returns the commands run inside minikube VM
+func sshCommands(recorder *runner.Recorder) []string {
+	var cmds []string
+	for _, record := range recorder.Records {
+		cmds = append(cmds, record.Command.Args[1])
+	}
+	return cmds
+}
+
+func TestMountSharedFolder(t *testing.T) {
+	want := []string{
+		`sudo mkdir -p '/mnt/Jean O'\''Brien/My Projects'`,
+		`mountpoint -q '/mnt/Jean O'\''Brien/My Projects' || sudo mount -t vboxsf -o uid=$(id -u),gid=$(id -g) 'my projects' '/mnt/Jean O'\''Brien/My Projects'`,
+	}
+	recorder := ssh(t, map[string]*runner.Response{
+		want[0]: {Result: &runner.Result{}},
+		want[1]: {Result: &runner.Result{}},
+	})
+	err := MountSharedFolder("my projects", "/mnt/Jean O'Brien/My Projects")
+	if err != nil {
+		t.Fatalf("MountSharedFolder() error = %v", err)
+	}
+	if got := sshCommands(recorder); !reflect.DeepEqual(got, want) {
+		t.Errorf("MountSharedFolder() ran %q, want %q", got, want)
+	}
+}
+
+func TestMountSharedFolderError(t *testing.T) {
+	errMkdir := errors.New("exit status 1")
+	recorder := ssh(t, map[string]*runner.Response{
+		"sudo mkdir -p '/data'": {Err: errMkdir},
+	})
+	err := MountSharedFolder("data", "/data")
+	if !errors.Is(err, errMkdir) {
+		t.Errorf("MountSharedFolder() error = %v, want %v", err, errMkdir)
+	}
+	if got := sshCommands(recorder); len(got) != 1 {
+		t.Errorf("MountSharedFolder() ran %q after mkdir failure, want no mount", got)
+	}
+}
+
+func TestUnmountSharedFolder(t *testing.T) {
+	want := `! mountpoint -q '/mnt/My "Projects"' || sudo umount '/mnt/My "Projects"'`
+	recorder := ssh(t, map[string]*runner.Response{
+		want: {Result: &runner.Result{}},
+	})
+	err := UnmountSharedFolder(`/mnt/My "Projects"`)
+	if err != nil {
+		t.Fatalf("UnmountSharedFolder() error = %v", err)
+	}
+	if got := sshCommands(recorder); !reflect.DeepEqual(got, []string{want}) {
+		t.Errorf("UnmountSharedFolder() ran %q, want %q", got, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no POSIX shell available")
+	}
+	values := []string{
+		"/data",
+		"/mnt/My Projects",
+		"/mnt/Jean O'Brien",
+		`/mnt/My "Projects"`,
+		"/mnt/$(id -u) `id` ; rm -rf *",
+		"''",
+	}
+	for _, value := range values {
+		out, err := exec.Command(sh, "-c", "printf %s "+shellQuote(value)).Output()
+		if err != nil {
+			t.Fatalf("sh -c printf %s error = %v", shellQuote(value), err)
+		}
+		if string(out) != value {
+			t.Errorf("shellQuote(%q) is read by the shell as %q", value, out)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minikube
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+// SetHostOnlyCIDR updates the host-only CIDR stored in minikube profile & machine configurations,
+// otherwise minikube would switch back to the previous host-only network on next start
+func SetHostOnlyCIDR(hostOnlyCIDR string) error {
+	if runner.DryRun("set host-only CIDR %s in minikube profile & machine configurations", hostOnlyCIDR) {
+		return nil
+	}
+	minikubeHome := filepath.Join(utils.GetUserHome(), ".minikube")
+	err := updateJSONFile(filepath.Join(minikubeHome, "profiles", "minikube", "config.json"), func(config map[string]interface{}) {
+		config["HostOnlyCIDR"] = hostOnlyCIDR
+	})
+	if err != nil {
+		return err
+	}
+	return updateJSONFile(filepath.Join(minikubeHome, "machines", "minikube", "config.json"), func(config map[string]interface{}) {
+		if driver, ok := config["Driver"].(map[string]interface{}); ok {
+			driver["HostOnlyCIDR"] = hostOnlyCIDR
+		}
+	})
+}
+
+// SetResources updates the memory (MB), CPUs and disk size (MB) stored in minikube profile & machine configurations,
+// a zero value leaving the stored one unchanged, otherwise minikube would report the resources the VM was created with
+func SetResources(memory int, cpus int, diskSize int) error {
+	if runner.DryRun("set memory %dMB, %d CPUs and disk size %dMB in minikube profile & machine configurations", memory, cpus, diskSize) {
+		return nil
+	}
+	minikubeHome := filepath.Join(utils.GetUserHome(), ".minikube")
+	err := updateJSONFile(filepath.Join(minikubeHome, "profiles", "minikube", "config.json"), func(config map[string]interface{}) {
+		setPositive(config, "Memory", memory)
+		setPositive(config, "CPUs", cpus)
+		setPositive(config, "DiskSize", diskSize)
+	})
+	if err != nil {
+		return err
+	}
+	return updateJSONFile(filepath.Join(minikubeHome, "machines", "minikube", "config.json"), func(config map[string]interface{}) {
+		if driver, ok := config["Driver"].(map[string]interface{}); ok {
+			setPositive(driver, "Memory", memory)
+			setPositive(driver, "CPU", cpus)
+			setPositive(driver, "DiskSize", diskSize)
+		}
+	})
+}
+
+func setPositive(config map[string]interface{}, key string, value int) {
+	if value > 0 {
+		config[key] = value
+	}
+}
+
+// ISOURL returns the URL of the ISO used by minikube VM, or an empty string if there is no minikube VM
+func ISOURL() (string, error) {
+	path := filepath.Join(utils.GetUserHome(), ".minikube", "profiles", "minikube", "config.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	var config struct {
+		MinikubeISO string
+	}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	return config.MinikubeISO, nil
+}
+
+func updateJSONFile(path string, update func(map[string]interface{})) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	var config map[string]interface{}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	update(config)
+	data, err = json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+	return nil
+}
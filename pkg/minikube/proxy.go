@@ -0,0 +1,63 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minikube
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// ConfigureRuntimeProxy sets the given proxy environment variables to containerd or cri-o service through a systemd drop-in
+// (docker daemon is configured by minikube itself through --docker-env). Service is only restarted when its configuration changes
+func ConfigureRuntimeProxy(containerRuntime string, env []string) error {
+	var service string
+	switch containerRuntime {
+	case "containerd":
+		service = "containerd"
+	case "cri-o", "crio":
+		service = "crio"
+	default:
+		return nil
+	}
+	dropIn := "/etc/systemd/system/" + service + ".service.d/gokube-proxy.conf"
+	var content strings.Builder
+	content.WriteString("[Service]\n")
+	for _, e := range env {
+		content.WriteString("Environment=" + systemdQuote(e) + "\n")
+	}
+	current, _ := SshOutput("sudo cat " + dropIn + " 2>/dev/null")
+	if strings.TrimSpace(current) == strings.TrimSpace(content.String()) || (len(env) == 0 && len(strings.TrimSpace(current)) == 0) {
+		return nil
+	}
+	proxyCmds := []string{
+		"sudo mkdir -p /etc/systemd/system/" + service + ".service.d",
+		"echo " + base64.StdEncoding.EncodeToString([]byte(content.String())) + " | base64 -d | sudo tee " + dropIn + " > /dev/null",
+		"sudo systemctl daemon-reload",
+		"sudo systemctl restart " + service,
+	}
+	for _, cmd := range proxyCmds {
+		err := Ssh(cmd)
+		if err != nil {
+			return fmt.Errorf("error running command '%s': %w", cmd, err)
+		}
+	}
+	return nil
+}
+
+// systemdQuote quotes the given value for a systemd unit setting, escaping specifiers (e.g. %-encoded proxy credentials)
+func systemdQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(value) + `"`
+}
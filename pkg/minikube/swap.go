@@ -0,0 +1,81 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minikube
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	SWAP_DEVICE = "/dev/sdb"
+)
+
+// SwapStatus returns the output of swapon for the swap device (empty if swap is not active)
+func SwapStatus() (string, error) {
+	out, err := SshOutput("swapon --show --noheadings")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, SWAP_DEVICE+" ") {
+			return strings.TrimSpace(line), nil
+		}
+	}
+	return "", nil
+}
+
+// EnableSwap formats (when needed), activates and registers the swap device in /etc/fstab
+func EnableSwap() error {
+	active, err := SwapStatus()
+	if err != nil {
+		return fmt.Errorf("cannot get swap status: %w", err)
+	}
+	swapCmds := []string{
+		// Only format device when it does not contain a swap signature yet
+		"sudo blkid -t TYPE=swap " + SWAP_DEVICE + " || sudo mkswap " + SWAP_DEVICE,
+		"grep -q '^" + SWAP_DEVICE + " ' /etc/fstab || echo '" + SWAP_DEVICE + " none swap defaults 0 0' | sudo tee -a /etc/fstab",
+	}
+	if len(active) == 0 {
+		swapCmds = append(swapCmds, "sudo swapon "+SWAP_DEVICE)
+	}
+	for _, cmd := range swapCmds {
+		err := Ssh(cmd)
+		if err != nil {
+			return fmt.Errorf("error running command '%s': %w", cmd, err)
+		}
+	}
+	return nil
+}
+
+// DisableSwap deactivates and unregisters the swap device from /etc/fstab
+func DisableSwap() error {
+	active, err := SwapStatus()
+	if err != nil {
+		return fmt.Errorf("cannot get swap status: %w", err)
+	}
+	var swapCmds []string
+	if len(active) > 0 {
+		swapCmds = append(swapCmds, "sudo swapoff "+SWAP_DEVICE)
+	}
+	swapCmds = append(swapCmds, "sudo sed -i '\\#^"+SWAP_DEVICE+" #d' /etc/fstab")
+	for _, cmd := range swapCmds {
+		err := Ssh(cmd)
+		if err != nil {
+			return fmt.Errorf("error running command '%s': %w", cmd, err)
+		}
+	}
+	return nil
+}
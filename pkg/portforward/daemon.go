@@ -0,0 +1,145 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+// LogFile returns the path of the port-forward daemon log file
+func LogFile() string {
+	return filepath.Join(utils.GetUserHome(), ".gokube", "port-forward.log")
+}
+
+func pidFile() string {
+	return filepath.Join(utils.GetUserHome(), ".gokube", "port-forward.pid")
+}
+
+func statusFile() string {
+	return filepath.Join(utils.GetUserHome(), ".gokube", "port-forward.status")
+}
+
+// StartDaemon starts the given command (typically gokube itself) as a detached background process logging to LogFile
+func StartDaemon(executable string, args ...string) error {
+	if runner.DryRun("start port-forward daemon %s %s", executable, strings.Join(args, " ")) {
+		return nil
+	}
+	logFile, err := os.OpenFile(LogFile(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot open port-forward log file: %w", err)
+	}
+	defer logFile.Close()
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachedProcessAttributes()
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("cannot start port-forward daemon: %w", err)
+	}
+	// Start time is kept along with the PID, which the system may reuse for another process once the daemon has exited
+	startTime, err := processStartTime(cmd.Process.Pid)
+	if err != nil {
+		return fmt.Errorf("cannot get port-forward daemon start time: %w", err)
+	}
+	err = os.WriteFile(pidFile(), []byte(fmt.Sprintf("%d %d", cmd.Process.Pid, startTime)), 0600)
+	if err != nil {
+		return fmt.Errorf("cannot write port-forward daemon PID file: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+// StopDaemon stops the background port-forward daemon if it is running
+func StopDaemon() error {
+	pid := DaemonPID()
+	if pid == 0 {
+		return nil
+	}
+	if runner.DryRun("stop port-forward daemon (PID %d)", pid) {
+		return nil
+	}
+	process, err := os.FindProcess(pid)
+	if err == nil {
+		err = process.Kill()
+		if err != nil && !strings.Contains(err.Error(), "process already finished") {
+			return fmt.Errorf("cannot stop port-forward daemon: %w", err)
+		}
+	}
+	_ = os.Remove(statusFile())
+	return os.Remove(pidFile())
+}
+
+// DaemonPID returns the PID of the running port-forward daemon, or 0 if it is not running
+// (a process having the same PID but another start time is not the daemon)
+func DaemonPID() int {
+	data, err := os.ReadFile(pidFile())
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return 0
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0
+	}
+	expectedStartTime, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	startTime, err := processStartTime(pid)
+	if err != nil || startTime != expectedStartTime {
+		return 0
+	}
+	return pid
+}
+
+// WriteStatus persists forwards status so that other gokube processes can display it
+func WriteStatus(status map[int]string) error {
+	return writeStatus(statusFile(), status)
+}
+
+func writeStatus(path string, status map[int]string) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// ReadStatus returns forwards status written by the running port-forward daemon
+func ReadStatus() map[int]string {
+	return readStatus(statusFile())
+}
+
+func readStatus(path string) map[int]string {
+	status := map[int]string{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return status
+	}
+	_ = json.Unmarshal(data, &status)
+	return status
+}
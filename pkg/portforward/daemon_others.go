@@ -0,0 +1,54 @@
+//go:build !windows
+
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// detachedProcessAttributes starts the daemon in its own session out of Windows
+func detachedProcessAttributes() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// processStartTime returns the start time of the given process in clock ticks since boot, or an error if it is not running.
+// Without procfs, the start time is unknown and always 0.
+func processStartTime(pid int) (int64, error) {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+	err = process.Signal(syscall.Signal(0))
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, nil
+	}
+	// Command name may hold spaces, fields are counted after it from the process state (third field)
+	fields := strings.Fields(string(data[bytes.LastIndexByte(data, ')')+1:]))
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("unexpected process %d stat format", pid)
+	}
+	return strconv.ParseInt(fields[19], 10, 64)
+}
@@ -0,0 +1,334 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const (
+	requestTimeout  = 10 * time.Second
+	podPollInterval = 5 * time.Second
+	minBackoff      = 1 * time.Second
+	maxBackoff      = 30 * time.Second
+)
+
+// Forward is a managed port-forward from a localhost port to a port of a pod, service or deployment
+type Forward struct {
+	Namespace  string `mapstructure:"namespace"`
+	Resource   string `mapstructure:"resource"`
+	LocalPort  int    `mapstructure:"local-port"`
+	RemotePort string `mapstructure:"remote-port"`
+}
+
+func (f Forward) String() string {
+	return fmt.Sprintf("localhost:%d -> %s/%s:%s", f.LocalPort, f.Namespace, f.Resource, f.RemotePort)
+}
+
+// ParseResource splits a resource given as [pod|svc|service|deploy|deployment]/name, pods being the default kind
+func ParseResource(resource string) (string, string, error) {
+	kind, name := "pod", resource
+	if i := strings.Index(resource, "/"); i >= 0 {
+		kind, name = resource[:i], resource[i+1:]
+	}
+	switch kind {
+	case "pod", "pods", "po":
+		kind = "pod"
+	case "service", "services", "svc":
+		kind = "service"
+	case "deployment", "deployments", "deploy":
+		kind = "deployment"
+	default:
+		return "", "", fmt.Errorf("unsupported resource kind %q (pod, service or deployment expected)", kind)
+	}
+	if len(name) == 0 {
+		return "", "", fmt.Errorf("missing resource name in %q", resource)
+	}
+	return kind, name, nil
+}
+
+// Manager runs forwards, reconnecting them whenever their connection is lost or their pod changes
+type Manager struct {
+	config    *rest.Config
+	clientset kubernetes.Interface
+	logger    *log.Logger
+	onStatus  func(map[int]string)
+	dialer    func(pod *corev1.Pod) (httpstream.Dialer, error)
+	wait      func(ctx context.Context, d time.Duration)
+	mu        sync.Mutex
+	status    map[int]string
+}
+
+// NewManager creates a Manager on top of the given REST configuration, logging to the given writer and reporting
+// forwards status changes to onStatus (which may be nil)
+func NewManager(config *rest.Config, out io.Writer, onStatus func(map[int]string)) (*Manager, error) {
+	requestConfig := rest.CopyConfig(config)
+	requestConfig.Timeout = requestTimeout
+	clientset, err := kubernetes.NewForConfig(requestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create kubernetes client: %w", err)
+	}
+	m := &Manager{
+		config:    config,
+		clientset: clientset,
+		logger:    log.New(out, "", log.LstdFlags),
+		onStatus:  onStatus,
+		wait:      wait,
+		status:    map[int]string{},
+	}
+	m.dialer = m.spdyDialer
+	return m, nil
+}
+
+// Run runs the given forwards until the context is cancelled
+func (m *Manager) Run(ctx context.Context, forwards []Forward) {
+	var wg sync.WaitGroup
+	for _, f := range forwards {
+		wg.Add(1)
+		go func(f Forward) {
+			defer wg.Done()
+			m.run(ctx, f)
+		}(f)
+	}
+	wg.Wait()
+}
+
+func (m *Manager) run(ctx context.Context, f Forward) {
+	backoff := minBackoff
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := m.forward(ctx, f)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			m.setStatus(f, fmt.Sprintf("reconnecting (%s)", err))
+			m.logger.Printf("%s: %s, reconnecting in %s", f, err, backoff)
+		} else {
+			m.setStatus(f, "reconnecting")
+			m.logger.Printf("%s: connection closed, reconnecting in %s", f, backoff)
+		}
+		// A forward which stayed connected for a while is not considered as failing
+		if time.Since(started) > maxBackoff {
+			backoff = minBackoff
+		}
+		m.wait(ctx, backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	m.setStatus(f, "stopped")
+}
+
+// forward runs a single port-forward session, which ends when the target pod is no longer running or the connection is lost
+func (m *Manager) forward(ctx context.Context, f Forward) error {
+	pod, port, err := m.resolve(ctx, f)
+	if err != nil {
+		return err
+	}
+	dialer, err := m.dialer(pod)
+	if err != nil {
+		return err
+	}
+	stopChan := make(chan struct{})
+	readyChan := make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("%d:%d", f.LocalPort, port)}, stopChan, readyChan, io.Discard, io.Discard)
+	if err != nil {
+		return err
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-readyChan:
+			m.setStatus(f, fmt.Sprintf("forwarding to pod %s port %d", pod.Name, port))
+			m.logger.Printf("%s: forwarding to pod %s port %d", f, pod.Name, port)
+		case <-watchCtx.Done():
+		}
+	}()
+	go func() {
+		m.watchPod(watchCtx, pod)
+		close(stopChan)
+	}()
+	return fw.ForwardPorts()
+}
+
+// spdyDialer returns a dialer to the port-forward subresource of the pod
+func (m *Manager) spdyDialer(pod *corev1.Pod) (httpstream.Dialer, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(m.config)
+	if err != nil {
+		return nil, err
+	}
+	url := m.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("portforward").URL()
+	return spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url), nil
+}
+
+// wait returns after the given duration or when the context is cancelled
+func wait(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// watchPod returns when the context is cancelled or the pod is no longer running
+func (m *Manager) watchPod(ctx context.Context, pod *corev1.Pod) {
+	ticker := time.NewTicker(podPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current, err := m.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+			if err != nil {
+				// API server may be temporarily unavailable (e.g. laptop sleep), connection loss is detected by the forwarder
+				continue
+			}
+			if current.UID != pod.UID || !isRunning(current) {
+				m.logger.Printf("pod %s/%s is no longer running", pod.Namespace, pod.Name)
+				return
+			}
+		}
+	}
+}
+
+// resolve returns the pod and the pod port targeted by the forward
+func (m *Manager) resolve(ctx context.Context, f Forward) (*corev1.Pod, int, error) {
+	kind, name, err := ParseResource(f.Resource)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch kind {
+	case "service":
+		service, err := m.clientset.CoreV1().Services(f.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+		pod, err := m.selectPod(ctx, f.Namespace, labels.SelectorFromSet(service.Spec.Selector))
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, p := range service.Spec.Ports {
+			if f.RemotePort == p.Name || f.RemotePort == strconv.Itoa(int(p.Port)) {
+				port, err := containerPort(pod, p.TargetPort.String())
+				return pod, port, err
+			}
+		}
+		port, err := containerPort(pod, f.RemotePort)
+		return pod, port, err
+	case "deployment":
+		deployment, err := m.clientset.AppsV1().Deployments(f.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+		selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return nil, 0, err
+		}
+		pod, err := m.selectPod(ctx, f.Namespace, selector)
+		if err != nil {
+			return nil, 0, err
+		}
+		port, err := containerPort(pod, f.RemotePort)
+		return pod, port, err
+	default:
+		pod, err := m.clientset.CoreV1().Pods(f.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+		if !isRunning(pod) {
+			return nil, 0, fmt.Errorf("pod %s/%s is not running", f.Namespace, name)
+		}
+		port, err := containerPort(pod, f.RemotePort)
+		return pod, port, err
+	}
+}
+
+// selectPod returns a running pod matching the selector
+func (m *Manager) selectPod(ctx context.Context, namespace string, selector labels.Selector) (*corev1.Pod, error) {
+	if selector.Empty() {
+		return nil, fmt.Errorf("resource has no pod selector")
+	}
+	pods, err := m.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		if isRunning(&pods.Items[i]) {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no running pod matching %s", selector)
+}
+
+func (m *Manager) setStatus(f Forward, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status[f.LocalPort] = status
+	if m.onStatus != nil {
+		snapshot := make(map[int]string, len(m.status))
+		for port, s := range m.status {
+			snapshot[port] = s
+		}
+		m.onStatus(snapshot)
+	}
+}
+
+func isRunning(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// containerPort returns the pod port given as a number or as a container port name
+func containerPort(pod *corev1.Pod, port string) (int, error) {
+	if n, err := strconv.Atoi(port); err == nil {
+		return n, nil
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == port {
+				return int(p.ContainerPort), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s/%s has no port named %q", pod.Namespace, pod.Name, port)
+}
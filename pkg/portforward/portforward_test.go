@@ -0,0 +1,128 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type failingDialer struct{}
+
+func (failingDialer) Dial(protocols ...string) (httpstream.Connection, string, error) {
+	return nil, "", errors.New("connection refused")
+}
+
+func TestRunBackoff(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var waits []time.Duration
+	var statuses []string
+	m := &Manager{
+		clientset: fake.NewSimpleClientset(pod),
+		logger:    log.New(io.Discard, "", 0),
+		onStatus: func(status map[int]string) {
+			statuses = append(statuses, status[8080])
+		},
+		dialer: func(p *corev1.Pod) (httpstream.Dialer, error) {
+			if p.Name != "web" {
+				t.Errorf("dialer() pod = %s, want web", p.Name)
+			}
+			return failingDialer{}, nil
+		},
+		wait: func(ctx context.Context, d time.Duration) {
+			waits = append(waits, d)
+			if len(waits) == 8 {
+				cancel()
+			}
+		},
+		status: map[int]string{},
+	}
+	m.run(ctx, Forward{Namespace: "default", Resource: "pod/web", LocalPort: 8080, RemotePort: "80"})
+
+	want := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second, 30 * time.Second}
+	if !reflect.DeepEqual(waits, want) {
+		t.Errorf("run() backoff = %v, want %v", waits, want)
+	}
+	if len(statuses) != len(want)+1 {
+		t.Fatalf("run() reported %d statuses, want %d", len(statuses), len(want)+1)
+	}
+	for _, status := range statuses[:len(want)] {
+		if !strings.HasPrefix(status, "reconnecting (") || !strings.Contains(status, "connection refused") {
+			t.Errorf("run() status = %q, want reconnecting on connection refused", status)
+		}
+	}
+	if statuses[len(want)] != "stopped" {
+		t.Errorf("run() final status = %q, want stopped", statuses[len(want)])
+	}
+}
+
+func TestForwardPodNotRunning(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	m := &Manager{
+		clientset: fake.NewSimpleClientset(pod),
+		logger:    log.New(io.Discard, "", 0),
+		dialer: func(p *corev1.Pod) (httpstream.Dialer, error) {
+			t.Errorf("dialer() called for pod %s which is not running", p.Name)
+			return failingDialer{}, nil
+		},
+		status: map[int]string{},
+	}
+	err := m.forward(context.Background(), Forward{Namespace: "default", Resource: "web", LocalPort: 8080, RemotePort: "80"})
+	if err == nil || !strings.Contains(err.Error(), "is not running") {
+		t.Errorf("forward() error = %v, want pod not running", err)
+	}
+}
+
+func TestStatusRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "port-forward.status")
+	if status := readStatus(path); len(status) != 0 {
+		t.Errorf("readStatus() = %v, want empty status when the file is missing", status)
+	}
+	status := map[int]string{
+		8080: "forwarding to pod web-1 port 80",
+		9090: "reconnecting (pod default/metrics is not running)",
+	}
+	err := writeStatus(path, status)
+	if err != nil {
+		t.Fatalf("writeStatus() error = %v", err)
+	}
+	got := readStatus(path)
+	if !reflect.DeepEqual(got, status) {
+		t.Errorf("readStatus() = %v, want %v", got, status)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	DEFAULT_SERVICE_CIDR = "10.96.0.0/12"
+	DEFAULT_POD_CIDR     = "10.244.0.0/16"
+	pacTimeout           = 10 * time.Second
+	internetSettingsKey  = `Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+)
+
+var (
+	rePACProxy = regexp.MustCompile(`PROXY\s+([A-Za-z0-9.\-]+:\d+)`)
+
+	current = &Config{}
+	mu      sync.RWMutex
+)
+
+// Config is the proxy configuration used by gokube, its child processes and minikube VM container runtime
+type Config struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// IsEmpty returns true when no proxy is configured
+func (c *Config) IsEmpty() bool {
+	return len(c.HTTPProxy) == 0 && len(c.HTTPSProxy) == 0
+}
+
+// Detect returns the proxy configuration from the given PAC URL if any, else from HTTP(S)_PROXY/NO_PROXY environment
+// variables, else from Windows internet settings (including their PAC URL)
+func Detect(pacURL string) (*Config, error) {
+	if len(pacURL) > 0 {
+		proxy, err := FromPAC(pacURL)
+		if err != nil {
+			return nil, err
+		}
+		return &Config{HTTPProxy: proxy, HTTPSProxy: proxy, NoProxy: getEnv("NO_PROXY")}, nil
+	}
+	config := &Config{
+		HTTPProxy:  getEnv("HTTP_PROXY"),
+		HTTPSProxy: getEnv("HTTPS_PROXY"),
+		NoProxy:    getEnv("NO_PROXY"),
+	}
+	if !config.IsEmpty() {
+		return config, nil
+	}
+	return fromWindowsSettings()
+}
+
+// FromPAC returns the first proxy declared in the given PAC file (http(s) URL or local file)
+func FromPAC(pacURL string) (string, error) {
+	var content []byte
+	var err error
+	if strings.HasPrefix(pacURL, "http://") || strings.HasPrefix(pacURL, "https://") {
+		// PAC file is usually served by an internal server which must not be reached through a proxy
+		client := &http.Client{Timeout: pacTimeout, Transport: &http.Transport{Proxy: nil}}
+		var response *http.Response
+		response, err = client.Get(pacURL)
+		if err != nil {
+			return "", fmt.Errorf("cannot download PAC file %s: %w", pacURL, err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("cannot download PAC file %s: %s", pacURL, response.Status)
+		}
+		content, err = io.ReadAll(response.Body)
+	} else {
+		content, err = os.ReadFile(strings.TrimPrefix(pacURL, "file://"))
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot read PAC file %s: %w", pacURL, err)
+	}
+	res := rePACProxy.FindSubmatch(content)
+	if res == nil {
+		return "", fmt.Errorf("no proxy found in PAC file %s", pacURL)
+	}
+	return "http://" + string(res[1]), nil
+}
+
+// Complete returns a copy of the configuration where NO_PROXY also contains the entries needed to reach minikube VM and cluster
+func (c *Config) Complete(vmIP string, hostOnlyCIDR string, dnsDomain string) *Config {
+	if c.IsEmpty() {
+		return &Config{NoProxy: c.NoProxy}
+	}
+	entries := []string{"localhost", "127.0.0.1", "minikube"}
+	if len(vmIP) > 0 {
+		entries = append(entries, vmIP)
+	}
+	if _, network, err := net.ParseCIDR(hostOnlyCIDR); err == nil {
+		entries = append(entries, network.String())
+	}
+	entries = append(entries, DEFAULT_SERVICE_CIDR, DEFAULT_POD_CIDR, ".svc")
+	if len(dnsDomain) > 0 {
+		entries = append(entries, "."+dnsDomain)
+	}
+	return &Config{HTTPProxy: c.HTTPProxy, HTTPSProxy: c.HTTPSProxy, NoProxy: mergeNoProxy(c.NoProxy, entries)}
+}
+
+// Env returns the configuration as environment variables (upper and lower case, as tools do not agree on it)
+func (c *Config) Env() []string {
+	var env []string
+	for _, v := range c.variables() {
+		name, value := v[0], v[1]
+		if len(value) > 0 {
+			env = append(env, name+"="+value, strings.ToLower(name)+"="+value)
+		}
+	}
+	return env
+}
+
+func (c *Config) variables() [][2]string {
+	return [][2]string{{"HTTP_PROXY", c.HTTPProxy}, {"HTTPS_PROXY", c.HTTPSProxy}, {"NO_PROXY", c.NoProxy}}
+}
+
+// Apply makes the configuration the current one, used by gokube HTTP clients and inherited by child processes (helm, kubectl, minikube...)
+func Apply(c *Config) error {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range c.variables() {
+		name, value := v[0], v[1]
+		for _, n := range []string{name, strings.ToLower(name)} {
+			var err error
+			if len(value) > 0 {
+				err = os.Setenv(n, value)
+			} else {
+				err = os.Unsetenv(n)
+			}
+			if err != nil {
+				return fmt.Errorf("cannot set %s environment variable: %w", n, err)
+			}
+		}
+	}
+	current = &Config{HTTPProxy: c.HTTPProxy, HTTPSProxy: c.HTTPSProxy, NoProxy: c.NoProxy}
+	return nil
+}
+
+// Current returns the applied configuration, or the environment one if none was applied
+func Current() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	if current.IsEmpty() && len(current.NoProxy) == 0 {
+		return &Config{HTTPProxy: getEnv("HTTP_PROXY"), HTTPSProxy: getEnv("HTTPS_PROXY"), NoProxy: getEnv("NO_PROXY")}
+	}
+	return current
+}
+
+// Func returns the proxy selection function of HTTP transports, based on current configuration
+func Func() func(*http.Request) (*url.URL, error) {
+	return func(request *http.Request) (*url.URL, error) {
+		c := Current()
+		config := &httpproxy.Config{HTTPProxy: c.HTTPProxy, HTTPSProxy: c.HTTPSProxy, NoProxy: c.NoProxy}
+		return config.ProxyFunc()(request.URL)
+	}
+}
+
+func withScheme(address string) string {
+	if len(address) == 0 || strings.Contains(address, "://") {
+		return address
+	}
+	return "http://" + address
+}
+
+func mergeNoProxy(noProxy string, entries []string) string {
+	var merged []string
+	seen := map[string]bool{}
+	for _, entry := range append(strings.Split(noProxy, ","), entries...) {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 && !seen[entry] {
+			seen[entry] = true
+			merged = append(merged, entry)
+		}
+	}
+	return strings.Join(merged, ",")
+}
+
+func getEnv(name string) string {
+	if value := os.Getenv(name); len(value) > 0 {
+		return value
+	}
+	return os.Getenv(strings.ToLower(name))
+}
@@ -0,0 +1,85 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   Config
+	}{
+		{
+			name:   "no proxy",
+			config: Config{NoProxy: "corp.example.com"},
+			want:   Config{NoProxy: "corp.example.com"},
+		},
+		{
+			name:   "proxy",
+			config: Config{HTTPProxy: "http://proxy:3128", HTTPSProxy: "http://proxy:3128"},
+			want: Config{
+				HTTPProxy:  "http://proxy:3128",
+				HTTPSProxy: "http://proxy:3128",
+				NoProxy:    "localhost,127.0.0.1,minikube,192.168.99.100,192.168.99.0/24,10.96.0.0/12,10.244.0.0/16,.svc,.cluster.local",
+			},
+		},
+		{
+			name:   "user entries kept first and not duplicated",
+			config: Config{HTTPSProxy: "http://proxy:3128", NoProxy: " corp.example.com, localhost,,.svc ,corp.example.com"},
+			want: Config{
+				HTTPSProxy: "http://proxy:3128",
+				NoProxy:    "corp.example.com,localhost,.svc,127.0.0.1,minikube,192.168.99.100,192.168.99.0/24,10.96.0.0/12,10.244.0.0/16,.cluster.local",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.Complete("192.168.99.100", "192.168.99.1/24", "cluster.local")
+			if *got != tt.want {
+				t.Errorf("Complete() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteWithoutVMIP(t *testing.T) {
+	config := &Config{HTTPProxy: "http://proxy:3128"}
+	got := config.Complete("", "invalid", "")
+	want := "localhost,127.0.0.1,minikube,10.96.0.0/12,10.244.0.0/16,.svc"
+	if got.NoProxy != want {
+		t.Errorf("Complete() NoProxy = %s, want %s", got.NoProxy, want)
+	}
+}
+
+func TestMergeNoProxy(t *testing.T) {
+	tests := []struct {
+		noProxy string
+		entries []string
+		want    string
+	}{
+		{"", nil, ""},
+		{"", []string{"localhost", "localhost"}, "localhost"},
+		{"a.example.com,b.example.com", []string{"b.example.com", "c.example.com"}, "a.example.com,b.example.com,c.example.com"},
+		{" a.example.com , ,a.example.com", []string{" .svc"}, "a.example.com,.svc"},
+	}
+	for _, tt := range tests {
+		if got := mergeNoProxy(tt.noProxy, tt.entries); got != tt.want {
+			t.Errorf("mergeNoProxy(%q, %q) = %q, want %q", tt.noProxy, tt.entries, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func fromWindowsSettings() (*Config, error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, internetSettingsKey, registry.QUERY_VALUE)
+	if err != nil {
+		return &Config{}, nil
+	}
+	defer key.Close()
+	config := &Config{}
+	if override, _, err := key.GetStringValue("ProxyOverride"); err == nil {
+		var entries []string
+		for _, entry := range strings.Split(override, ";") {
+			entry = strings.TrimSpace(entry)
+			if len(entry) > 0 && entry != "<local>" {
+				entries = append(entries, strings.TrimPrefix(entry, "*"))
+			}
+		}
+		config.NoProxy = strings.Join(entries, ",")
+	}
+	if pacURL, _, err := key.GetStringValue("AutoConfigURL"); err == nil && len(pacURL) > 0 {
+		proxy, err := FromPAC(pacURL)
+		if err != nil {
+			return nil, err
+		}
+		config.HTTPProxy = proxy
+		config.HTTPSProxy = proxy
+		return config, nil
+	}
+	if enabled, _, err := key.GetIntegerValue("ProxyEnable"); err != nil || enabled == 0 {
+		return config, nil
+	}
+	server, _, err := key.GetStringValue("ProxyServer")
+	if err != nil {
+		return config, nil
+	}
+	// ProxyServer is either host:port or a list of protocol=host:port
+	for _, entry := range strings.Split(server, ";") {
+		protocol, address, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			config.HTTPProxy = withScheme(protocol)
+			config.HTTPSProxy = withScheme(protocol)
+			break
+		}
+		switch protocol {
+		case "http":
+			config.HTTPProxy = withScheme(address)
+		case "https":
+			config.HTTPSProxy = withScheme(address)
+		}
+	}
+	return config, nil
+}
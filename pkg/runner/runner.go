@@ -0,0 +1,294 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Command describes an external command to run
+type Command struct {
+	Name string
+	Args []string
+	// Env is appended to the current process environment
+	Env   []string
+	Stdin io.Reader
+	// Stdout and Stderr receive the command output in addition to it being captured (nil means captured only)
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Result holds the captured output of a command
+type Result struct {
+	Stdout string
+	Stderr string
+}
+
+// Runner defines the interface to run external commands
+type Runner interface {
+	Run(ctx context.Context, cmd *Command) (*Result, error)
+}
+
+// Error is returned when a command fails
+type Error struct {
+	Command string
+	Stderr  string
+	Err     error
+}
+
+// ExecRunner runs commands with os/exec
+type ExecRunner struct{}
+
+// DryRunRunner prints commands instead of running them
+type DryRunRunner struct {
+	Out io.Writer
+}
+
+// Recorder records commands, delegating them to Runner or replaying Responses when Runner is nil.
+// Responses are keyed by command line, the executable being given either as run or by its base name without extension.
+type Recorder struct {
+	Runner    Runner
+	Responses map[string]*Response
+	Records   []*Record
+	mu        sync.Mutex
+}
+
+// Response is a canned answer replayed by a Recorder
+type Response struct {
+	Result *Result
+	Err    error
+}
+
+// Record is a command seen by a Recorder
+type Record struct {
+	Command *Command
+	Result  *Result
+	Err     error
+}
+
+var (
+	defaultRunner Runner = &ExecRunner{}
+	dryRun        bool
+	mu            sync.RWMutex
+)
+
+// Default returns the runner used by all external tool wrappers
+func Default() Runner {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultRunner
+}
+
+// SetDefault replaces the runner used by all external tool wrappers
+func SetDefault(r Runner) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultRunner = r
+}
+
+// SetDryRun switches the default runner to a DryRunRunner (or back to an ExecRunner)
+func SetDryRun(enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	dryRun = enabled
+	if enabled {
+		defaultRunner = &DryRunRunner{}
+	} else {
+		defaultRunner = &ExecRunner{}
+	}
+}
+
+// IsDryRun returns true when dry-run mode is enabled
+func IsDryRun() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return dryRun
+}
+
+// DryRun prints the described action and returns true when dry-run mode is enabled, meaning the caller shall skip it
+func DryRun(format string, a ...interface{}) bool {
+	if !IsDryRun() {
+		return false
+	}
+	fmt.Printf("[dry-run] "+format+"\n", a...)
+	return true
+}
+
+// Stream runs the command with the default runner, displaying its output
+func Stream(name string, args ...string) error {
+	_, err := Default().Run(context.Background(), &Command{Name: name, Args: args, Stdout: os.Stdout, Stderr: os.Stderr})
+	return err
+}
+
+// Quiet runs the command with the default runner, discarding its output
+func Quiet(name string, args ...string) error {
+	_, err := Default().Run(context.Background(), &Command{Name: name, Args: args})
+	return err
+}
+
+// Output runs the command with the default runner and returns its standard output
+func Output(name string, args ...string) (string, error) {
+	result, err := Default().Run(context.Background(), &Command{Name: name, Args: args})
+	if result == nil {
+		return "", err
+	}
+	return result.Stdout, err
+}
+
+// Inspector returns the runner of read-only commands: the default one, except in dry-run mode where they are still run
+func Inspector() Runner {
+	r := Default()
+	if _, ok := r.(*DryRunRunner); ok {
+		return &ExecRunner{}
+	}
+	return r
+}
+
+// Inspect runs a read-only command and returns its standard output, even in dry-run mode
+func Inspect(name string, args ...string) (string, error) {
+	result, err := Inspector().Run(context.Background(), &Command{Name: name, Args: args})
+	if result == nil {
+		return "", err
+	}
+	return result.Stdout, err
+}
+
+// String returns the command line
+func (c *Command) String() string {
+	tokens := []string{c.Name}
+	for _, arg := range c.Args {
+		if strings.ContainsAny(arg, " \t\"'") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		tokens = append(tokens, arg)
+	}
+	return strings.Join(tokens, " ")
+}
+
+func (e *Error) Error() string {
+	stderr := strings.TrimSpace(e.Stderr)
+	if len(stderr) > 0 {
+		return fmt.Sprintf("%s failed: %s: %s", e.Command, e.Err, stderr)
+	}
+	return fmt.Sprintf("%s failed: %s", e.Command, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Run ...
+func (r *ExecRunner) Run(ctx context.Context, c *Command) (*Result, error) {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Stdin = c.Stdin
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if c.Stdout != nil {
+		cmd.Stdout = io.MultiWriter(&stdout, c.Stdout)
+	}
+	if c.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, c.Stderr)
+	}
+	err := cmd.Run()
+	result := &Result{Stdout: stdout.String(), Stderr: stderr.String()}
+	if err != nil {
+		runErr := &Error{Command: c.String(), Err: err}
+		// Stderr is only part of the error message when it has not already been displayed
+		if c.Stderr == nil {
+			runErr.Stderr = result.Stderr
+		}
+		return result, runErr
+	}
+	return result, nil
+}
+
+// Run ...
+func (r *DryRunRunner) Run(ctx context.Context, c *Command) (*Result, error) {
+	out := r.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	_, _ = fmt.Fprintf(out, "[dry-run] %s\n", c.String())
+	return &Result{}, nil
+}
+
+// Run ...
+func (r *Recorder) Run(ctx context.Context, c *Command) (*Result, error) {
+	var result *Result
+	var err error
+	if r.Runner != nil {
+		result, err = r.Runner.Run(ctx, c)
+	} else if response, ok := r.response(c); ok {
+		result, err = response.Result, response.Err
+	} else {
+		err = fmt.Errorf("no recorded response for %s", c.String())
+	}
+	if result == nil {
+		result = &Result{}
+	}
+	if r.Runner == nil {
+		if c.Stdout != nil {
+			_, _ = io.WriteString(c.Stdout, result.Stdout)
+		}
+		if c.Stderr != nil {
+			_, _ = io.WriteString(c.Stderr, result.Stderr)
+		}
+	}
+	r.mu.Lock()
+	r.Records = append(r.Records, &Record{Command: c, Result: result, Err: err})
+	r.mu.Unlock()
+	return result, err
+}
+
+func (r *Recorder) response(c *Command) (*Response, bool) {
+	if response, ok := r.Responses[c.String()]; ok {
+		return response, true
+	}
+	// Windows paths are handled whatever the OS running the tests
+	name := path.Base(strings.ReplaceAll(c.Name, `\`, "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	response, ok := r.Responses[(&Command{Name: name, Args: c.Args}).String()]
+	return response, ok
+}
+
+// Commands returns the command lines recorded so far
+func (r *Recorder) Commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var commands []string
+	for _, record := range r.Records {
+		commands = append(commands, record.Command.String())
+	}
+	return commands
+}
+
+// IsNotFound returns true if the error comes from a command which is not installed
+func IsNotFound(err error) bool {
+	return errors.Is(err, exec.ErrNotFound)
+}
@@ -0,0 +1,124 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selfupdate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+const (
+	CHANNEL_STABLE     = "stable"
+	CHANNEL_PRERELEASE = "prerelease"
+)
+
+// CheckResult is the latest release found by an update check
+type CheckResult struct {
+	CheckedAt time.Time `json:"checked-at"`
+	Channel   string    `json:"channel"`
+	Latest    string    `json:"latest"`
+}
+
+// LatestRelease returns the latest release of the given channel, the prerelease channel including stable releases too
+func LatestRelease(apiURL string, channel string) (*Release, error) {
+	switch channel {
+	case CHANNEL_STABLE, "":
+		return FindRelease(apiURL, "")
+	case CHANNEL_PRERELEASE:
+	default:
+		return nil, fmt.Errorf("unknown update channel %q (%s or %s expected)", channel, CHANNEL_STABLE, CHANNEL_PRERELEASE)
+	}
+	url := strings.TrimSuffix(apiURL, "/") + "/releases?per_page=30"
+	body, err := get(url, requestTimeout, true)
+	if err != nil {
+		return nil, err
+	}
+	var releases []Release
+	err = json.Unmarshal(body, &releases)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse releases from %s: %w", url, err)
+	}
+	var latest *Release
+	var latestVersion *semver.Version
+	for i := range releases {
+		v, err := semver.NewVersion(releases[i].Version())
+		if err != nil {
+			continue
+		}
+		if latestVersion == nil || latestVersion.LessThan(*v) {
+			latest, latestVersion = &releases[i], v
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no release found")
+	}
+	return latest, nil
+}
+
+// Check looks for the latest release of the given channel within the given timeout
+func Check(apiURL string, channel string, timeout time.Duration) (*CheckResult, error) {
+	result := make(chan *CheckResult, 1)
+	errs := make(chan error, 1)
+	go func() {
+		release, err := LatestRelease(apiURL, channel)
+		if err != nil {
+			errs <- err
+			return
+		}
+		result <- &CheckResult{CheckedAt: time.Now(), Channel: channel, Latest: release.Version()}
+	}()
+	select {
+	case r := <-result:
+		return r, nil
+	case err := <-errs:
+		return nil, err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("no answer from %s after %s", apiURL, timeout)
+	}
+}
+
+func checkCacheFile() string {
+	return filepath.Join(utils.GetUserHome(), ".gokube", "update-check.json")
+}
+
+// ReadCheckCache returns the cached update check result if it was done for the given channel, whatever its age
+func ReadCheckCache(channel string) *CheckResult {
+	data, err := os.ReadFile(checkCacheFile())
+	if err != nil {
+		return nil
+	}
+	var result CheckResult
+	err = json.Unmarshal(data, &result)
+	if err != nil || result.Channel != channel {
+		return nil
+	}
+	return &result
+}
+
+// WriteCheckCache caches the update check result
+func WriteCheckCache(result *CheckResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(checkCacheFile(), data, 0644)
+}
@@ -0,0 +1,209 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selfupdate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/httpclient"
+	"github.com/gemalto/gokube/pkg/runner"
+)
+
+const (
+	DEFAULT_API_URL = "https://api.github.com/repos/ThalesGroup/gokube"
+	ASSET_NAME      = "gokube-windows-amd64.exe"
+	checksumSuffix  = ".sha256"
+	digestPrefix    = "sha256:"
+	newSuffix       = ".new"
+	oldSuffix       = ".old"
+	requestTimeout  = 30 * time.Second
+	githubAPIHost   = "api.github.com"
+)
+
+var ErrNoChecksum = errors.New("no checksum published for release asset")
+
+// Release is a gokube release, as described by the GitHub releases API
+type Release struct {
+	TagName    string  `json:"tag_name"`
+	Prerelease bool    `json:"prerelease"`
+	Assets     []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	Digest             string `json:"digest"`
+}
+
+// Version returns the release version without its "v" prefix
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+func (r *Release) asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// FindRelease returns the given release (latest one if version is empty) from the releases API
+func FindRelease(apiURL string, version string) (*Release, error) {
+	url := strings.TrimSuffix(apiURL, "/") + "/releases/latest"
+	if len(version) > 0 {
+		url = strings.TrimSuffix(apiURL, "/") + "/releases/tags/v" + strings.TrimPrefix(version, "v")
+	}
+	body, err := get(url, requestTimeout, true)
+	if err != nil {
+		return nil, err
+	}
+	var release Release
+	err = json.Unmarshal(body, &release)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse release from %s: %w", url, err)
+	}
+	return &release, nil
+}
+
+// Checksum returns the expected SHA-256 of the release asset, from the asset digest or from the published checksum file
+func (r *Release) Checksum(name string) (string, error) {
+	asset := r.asset(name)
+	if asset == nil {
+		return "", fmt.Errorf("no asset %s in release %s", name, r.TagName)
+	}
+	if strings.HasPrefix(asset.Digest, digestPrefix) {
+		return strings.TrimPrefix(asset.Digest, digestPrefix), nil
+	}
+	checksumAsset := r.asset(name + checksumSuffix)
+	if checksumAsset == nil {
+		return "", ErrNoChecksum
+	}
+	body, err := get(checksumAsset.BrowserDownloadURL, requestTimeout, false)
+	if err != nil {
+		return "", err
+	}
+	// Checksum file format is "<sha256>  <file name>"
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file %s", checksumAsset.Name)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// Update downloads the release asset next to the given executable, verifies its checksum and replaces the executable.
+// As a running executable cannot be deleted on Windows, it is renamed aside (and removed by next update)
+func Update(release *Release, executable string) error {
+	asset := release.asset(ASSET_NAME)
+	if asset == nil {
+		return fmt.Errorf("no asset %s in release %s", ASSET_NAME, release.TagName)
+	}
+	checksum, err := release.Checksum(ASSET_NAME)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(executable)
+	newExecutable := executable + newSuffix
+	oldExecutable := executable + oldSuffix
+	fileMap := &download.FileMap{Src: ASSET_NAME, Dst: filepath.Base(newExecutable)}
+	_, err = download.FromUrl(asset.BrowserDownloadURL, release.Version(), "gokube", []*download.FileMap{fileMap}, dir, nil)
+	if err != nil {
+		return fmt.Errorf("cannot download %s: %w", asset.BrowserDownloadURL, err)
+	}
+	if runner.DryRun("verify checksum of %s and replace %s", newExecutable, executable) {
+		return nil
+	}
+	err = verifyChecksum(newExecutable, checksum)
+	if err != nil {
+		_ = os.Remove(newExecutable)
+		return err
+	}
+	err = os.Remove(oldExecutable)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove previous executable %s: %w", oldExecutable, err)
+	}
+	err = os.Rename(executable, oldExecutable)
+	if err != nil {
+		_ = os.Remove(newExecutable)
+		return fmt.Errorf("cannot move current executable aside: %w", err)
+	}
+	err = os.Rename(newExecutable, executable)
+	if err != nil {
+		// Put back current executable
+		_ = os.Rename(oldExecutable, executable)
+		_ = os.Remove(newExecutable)
+		return fmt.Errorf("cannot replace current executable: %w", err)
+	}
+	return nil
+}
+
+// CleanOldExecutable removes the executable left aside by a previous update
+func CleanOldExecutable(executable string) {
+	_ = os.Remove(executable + oldSuffix)
+}
+
+func verifyChecksum(file string, expected string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", filepath.Base(file), expected, actual)
+	}
+	return nil
+}
+
+// get fetches the given URL, authenticating GitHub API requests with GITHUB_TOKEN (if set) to avoid anonymous rate limits.
+// The token is never sent to other hosts, such as mirrors or GitHub Enterprise given by GOKUBE_RELEASES_API_URL.
+func get(url string, timeout time.Duration, api bool) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); len(token) > 0 && api && request.URL.Scheme == "https" && request.URL.Host == githubAPIHost {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := httpclient.New()
+	client.Timeout = timeout
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot get %s: %s", url, response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
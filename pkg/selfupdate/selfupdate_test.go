@@ -0,0 +1,226 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selfupdate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const newExecutableContent = "new gokube"
+
+// newReleasesServer serves a fake GitHub releases API, with the new executable as asset of each release.
+// The executable checksum is published as the given digest, or as a checksum file if the digest is empty.
+func newReleasesServer(t *testing.T, digest string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	release := func(tag string, prerelease bool) Release {
+		r := Release{TagName: tag, Prerelease: prerelease, Assets: []Asset{
+			{Name: ASSET_NAME, BrowserDownloadURL: server.URL + "/download/" + tag + "/" + ASSET_NAME, Digest: digest},
+		}}
+		if len(digest) == 0 {
+			r.Assets = append(r.Assets, Asset{Name: ASSET_NAME + checksumSuffix, BrowserDownloadURL: server.URL + "/download/" + tag + "/" + ASSET_NAME + checksumSuffix})
+		}
+		return r
+	}
+	writeJSON := func(w http.ResponseWriter, value interface{}) {
+		if err := json.NewEncoder(w).Encode(value); err != nil {
+			t.Errorf("cannot encode %v: %v", value, err)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, release("v1.2.0", false))
+	})
+	mux.HandleFunc("/releases/tags/v1.1.0", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, release("v1.1.0", false))
+	})
+	mux.HandleFunc("/releases", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []Release{release("v1.2.0", false), release("v1.3.0-rc.1", true), release("nightly", true)})
+	})
+	mux.HandleFunc("/download/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, checksumSuffix) {
+			sum := sha256.Sum256([]byte(newExecutableContent))
+			_, _ = w.Write([]byte(hex.EncodeToString(sum[:]) + "  " + ASSET_NAME + "\n"))
+			return
+		}
+		_, _ = w.Write([]byte(newExecutableContent))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFindRelease(t *testing.T) {
+	server := newReleasesServer(t, "")
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"", "1.2.0"},
+		{"1.1.0", "1.1.0"},
+		{"v1.1.0", "1.1.0"},
+	}
+	for _, tt := range tests {
+		release, err := FindRelease(server.URL, tt.version)
+		if err != nil {
+			t.Fatalf("FindRelease(%q) error = %v", tt.version, err)
+		}
+		if release.Version() != tt.want {
+			t.Errorf("FindRelease(%q) version = %s, want %s", tt.version, release.Version(), tt.want)
+		}
+	}
+	if _, err := FindRelease(server.URL, "9.9.9"); err == nil {
+		t.Error("FindRelease(9.9.9) error = nil, want not found")
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	server := newReleasesServer(t, "")
+	tests := []struct {
+		channel string
+		want    string
+	}{
+		{CHANNEL_STABLE, "1.2.0"},
+		{CHANNEL_PRERELEASE, "1.3.0-rc.1"},
+	}
+	for _, tt := range tests {
+		release, err := LatestRelease(server.URL, tt.channel)
+		if err != nil {
+			t.Fatalf("LatestRelease(%s) error = %v", tt.channel, err)
+		}
+		if release.Version() != tt.want {
+			t.Errorf("LatestRelease(%s) version = %s, want %s", tt.channel, release.Version(), tt.want)
+		}
+	}
+	if _, err := LatestRelease(server.URL, "beta"); err == nil {
+		t.Error("LatestRelease(beta) error = nil, want unknown channel")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte(newExecutableContent))
+	want := hex.EncodeToString(sum[:])
+	tests := []struct {
+		name   string
+		digest string
+	}{
+		{"digest", digestPrefix + want},
+		{"checksum file", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release, err := FindRelease(newReleasesServer(t, tt.digest).URL, "")
+			if err != nil {
+				t.Fatalf("FindRelease() error = %v", err)
+			}
+			checksum, err := release.Checksum(ASSET_NAME)
+			if err != nil {
+				t.Fatalf("Checksum() error = %v", err)
+			}
+			if checksum != want {
+				t.Errorf("Checksum() = %s, want %s", checksum, want)
+			}
+		})
+	}
+	release := &Release{TagName: "v1.0.0", Assets: []Asset{{Name: ASSET_NAME}}}
+	if _, err := release.Checksum(ASSET_NAME); err != ErrNoChecksum {
+		t.Errorf("Checksum() error = %v, want ErrNoChecksum", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	release, err := FindRelease(newReleasesServer(t, "").URL, "")
+	if err != nil {
+		t.Fatalf("FindRelease() error = %v", err)
+	}
+	executable := filepath.Join(t.TempDir(), "gokube.exe")
+	writeFile(t, executable, "current gokube")
+	writeFile(t, executable+oldSuffix, "previous gokube")
+	err = Update(release, executable)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	assertContent(t, executable, newExecutableContent)
+	// Running executable is renamed aside, replacing the one left by previous update
+	assertContent(t, executable+oldSuffix, "current gokube")
+	if _, err := os.Stat(executable + newSuffix); !os.IsNotExist(err) {
+		t.Errorf("Update() left %s", executable+newSuffix)
+	}
+	CleanOldExecutable(executable)
+	if _, err := os.Stat(executable + oldSuffix); !os.IsNotExist(err) {
+		t.Errorf("CleanOldExecutable() left %s", executable+oldSuffix)
+	}
+}
+
+func TestUpdateChecksumMismatch(t *testing.T) {
+	release, err := FindRelease(newReleasesServer(t, digestPrefix+strings.Repeat("0", 64)).URL, "")
+	if err != nil {
+		t.Fatalf("FindRelease() error = %v", err)
+	}
+	executable := filepath.Join(t.TempDir(), "gokube.exe")
+	writeFile(t, executable, "current gokube")
+	err = Update(release, executable)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Update() error = %v, want checksum mismatch", err)
+	}
+	assertContent(t, executable, "current gokube")
+	for _, suffix := range []string{newSuffix, oldSuffix} {
+		if _, err := os.Stat(executable + suffix); !os.IsNotExist(err) {
+			t.Errorf("Update() left %s", executable+suffix)
+		}
+	}
+}
+
+func writeFile(t *testing.T, file string, content string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertContent(t *testing.T, file string, want string) {
+	t.Helper()
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != want {
+		t.Errorf("%s content = %q, want %q", filepath.Base(file), content, want)
+	}
+}
+
+func TestGetSendsTokenOnlyToGitHub(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "secret")
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	_, err := get(server.URL+"/releases/latest", requestTimeout, true)
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if len(authorization) > 0 {
+		t.Errorf("get() sent Authorization %q to %s", authorization, server.URL)
+	}
+}
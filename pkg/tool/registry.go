@@ -0,0 +1,105 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tool
+
+import (
+	"fmt"
+
+	"github.com/gemalto/gokube/pkg/trust"
+)
+
+const (
+	DEFAULT_HELM_URL    = "https://get.helm.sh/helm-%s-windows-amd64.zip"
+	DEFAULT_DOCKER_URL  = "https://download.docker.com/win/static/stable/x86_64/docker-%s.zip"
+	DEFAULT_KUBECTL_URL = "https://dl.k8s.io/%s/bin/windows/amd64/kubectl.exe"
+	DEFAULT_STERN_URL   = "https://github.com/stern/stern/releases/download/v%s/stern_%s_windows_amd64.tar.gz"
+	DEFAULT_K9S_URL     = "https://github.com/derailed/k9s/releases/download/v%s/k9s_Windows_amd64.zip"
+)
+
+// builtins are the tools always installed by gokube, their URL and version being provided by Builtin callers
+var builtins = []*Descriptor{
+	{Name: "helm", ArchivePath: "windows-amd64/helm.exe", VersionArgs: []string{"version", "--short"},
+		Signature: &trust.Signature{Type: trust.TYPE_GPG, Key: "helm.asc"}},
+	{Name: "docker", ArchivePath: "docker/docker.exe", VersionArgs: []string{"--version"}},
+	{Name: "kubectl", VersionArgs: []string{"version", "--client"},
+		Signature: &trust.Signature{Type: trust.TYPE_COSIGN, Certificate: ".cert", Identity: "krel-trust@k8s-releng-prod.iam.gserviceaccount.com", Issuer: "https://accounts.google.com"}},
+	{Name: "stern", VersionArgs: []string{"--version"}},
+	{Name: "k9s", VersionArgs: []string{"version", "--short"}},
+}
+
+// reserved are the names which cannot be used by user-defined tools, besides built-in ones
+var reserved = []string{"minikube", "gokube"}
+
+// registered are the user-defined tools
+var registered []*Descriptor
+
+// Builtin returns the descriptor of the given built-in tool with the given URL template and version, or nil if it does not exist
+func Builtin(name string, url string, version string) *Descriptor {
+	for _, d := range builtins {
+		if d.Name == name {
+			builtin := *d
+			builtin.URL = url
+			builtin.Version = version
+			return &builtin
+		}
+	}
+	return nil
+}
+
+// IsReserved returns true if the name is used by a built-in tool or by gokube itself
+func IsReserved(name string) bool {
+	for _, d := range builtins {
+		if d.Name == name {
+			return true
+		}
+	}
+	for _, r := range reserved {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Register adds a user-defined tool to the registry
+func Register(d *Descriptor) error {
+	err := d.Validate()
+	if err != nil {
+		return err
+	}
+	if IsReserved(d.Name) {
+		return fmt.Errorf("tool name %s is reserved", d.Name)
+	}
+	if Lookup(d.Name) != nil {
+		return fmt.Errorf("tool %s is already registered", d.Name)
+	}
+	registered = append(registered, d)
+	return nil
+}
+
+// Lookup returns the registered user-defined tool with the given name, or nil if it does not exist
+func Lookup(name string) *Descriptor {
+	for _, d := range registered {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// Registered returns the user-defined tools, in registration order
+func Registered() []*Descriptor {
+	return append([]*Descriptor{}, registered...)
+}
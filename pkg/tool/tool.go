@@ -0,0 +1,143 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/download"
+	"github.com/gemalto/gokube/pkg/runner"
+	"github.com/gemalto/gokube/pkg/trust"
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+// Descriptor declares how a tool executable is downloaded into gokube bin directory and how its version is displayed
+type Descriptor struct {
+	Name string `mapstructure:"name"`
+	// URL is the download URL template, each %s being replaced by the version
+	URL     string `mapstructure:"url"`
+	Version string `mapstructure:"version"`
+	// Executable is the name of the installed executable (<name>.exe by default)
+	Executable string `mapstructure:"executable"`
+	// ArchivePath is the path of the executable within the downloaded archive, each %s being replaced by the version
+	// (executable name by default, or the downloaded file name if it is an executable)
+	ArchivePath string `mapstructure:"archive-path"`
+	// VersionArgs are the arguments making the executable display its version (--version by default)
+	VersionArgs []string `mapstructure:"version-args"`
+	// Signature declares how the downloaded release is verified, if its signature is published
+	Signature *trust.Signature `mapstructure:"signature"`
+}
+
+// Validate checks that the mandatory fields are set
+func (d *Descriptor) Validate() error {
+	if len(d.Name) == 0 {
+		return errors.New("tool name is missing")
+	}
+	if strings.ContainsAny(d.Name, `/\:`) {
+		return fmt.Errorf("invalid tool name %q", d.Name)
+	}
+	if len(d.URL) == 0 {
+		return fmt.Errorf("%s URL is missing", d.Name)
+	}
+	if len(d.Version) == 0 {
+		return fmt.Errorf("%s version is missing", d.Name)
+	}
+	if d.Signature != nil {
+		if err := d.Signature.Validate(); err != nil {
+			return fmt.Errorf("invalid %s signature: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// ExecutableName returns the name of the installed executable
+func (d *Descriptor) ExecutableName() string {
+	if len(d.Executable) > 0 {
+		return d.Executable
+	}
+	return d.Name + ".exe"
+}
+
+// LocalFile returns the path of the installed executable
+func (d *Descriptor) LocalFile() string {
+	return utils.GetBinDir("gokube") + string(os.PathSeparator) + d.ExecutableName()
+}
+
+func (d *Descriptor) archivePath() string {
+	if len(d.ArchivePath) > 0 {
+		return filepath.FromSlash(strings.Replace(d.ArchivePath, "%s", d.Version, -1))
+	}
+	url := download.URL(d.URL, d.Version)
+	if strings.HasSuffix(strings.ToLower(url), ".exe") {
+		tokens := strings.Split(url, "/")
+		return tokens[len(tokens)-1]
+	}
+	return d.ExecutableName()
+}
+
+// Download downloads the executable if it is not installed yet
+func (d *Descriptor) Download() error {
+	if _, err := os.Stat(d.LocalFile()); os.IsNotExist(err) {
+		return d.Upgrade()
+	}
+	return nil
+}
+
+// Upgrade downloads the executable, replacing the installed one only once the download succeeded
+func (d *Descriptor) Upgrade() error {
+	fileMap := &download.FileMap{Src: d.archivePath(), Dst: d.ExecutableName()}
+	_, err := download.FromUrl(d.URL, d.Version, d.Name, []*download.FileMap{fileMap}, filepath.Dir(d.LocalFile()), d.Signature)
+	return err
+}
+
+// Delete removes the installed executable
+func (d *Descriptor) Delete() error {
+	return utils.RemoveAll(d.LocalFile())
+}
+
+func (d *Descriptor) versionArgs() []string {
+	if len(d.VersionArgs) > 0 {
+		return d.VersionArgs
+	}
+	return []string{"--version"}
+}
+
+// InstalledVersion returns the version of the installed executable, or an empty string if it is not installed
+func (d *Descriptor) InstalledVersion() (string, error) {
+	localFile := d.LocalFile()
+	if _, err := os.Stat(localFile); os.IsNotExist(err) {
+		return "", nil
+	}
+	out, err := runner.Inspect(localFile, d.versionArgs()...)
+	if err != nil {
+		return "", err
+	}
+	return utils.ParseVersion(out), nil
+}
+
+// PrintVersion displays the version of the installed executable
+func (d *Descriptor) PrintVersion() error {
+	fmt.Printf("%s version: ", d.Name)
+	localFile := d.LocalFile()
+	if _, err := os.Stat(localFile); os.IsNotExist(err) {
+		fmt.Println("not installed")
+		return nil
+	}
+	return runner.Stream(localFile, d.versionArgs()...)
+}
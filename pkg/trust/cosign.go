@@ -0,0 +1,295 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trust
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+// Fulcio certificate extensions holding the OIDC issuer of the signer identity
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// cosignBundle covers both cosign legacy bundles and Sigstore message signature bundles
+type cosignBundle struct {
+	Base64Signature  string `json:"base64Signature"`
+	Cert             string `json:"cert"`
+	MessageSignature *struct {
+		Signature string `json:"signature"`
+	} `json:"messageSignature"`
+	VerificationMaterial *struct {
+		Certificate *struct {
+			RawBytes string `json:"rawBytes"`
+		} `json:"certificate"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes string `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain"`
+	} `json:"verificationMaterial"`
+}
+
+func isBundle(content []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(content), []byte("{"))
+}
+
+// parseBundle returns the signature of a bundle, and its certificate if it was signed keyless
+func parseBundle(content []byte) ([]byte, *x509.Certificate, error) {
+	var b cosignBundle
+	err := json.Unmarshal(content, &b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if len(b.Base64Signature) > 0 {
+		sig, err := base64.StdEncoding.DecodeString(b.Base64Signature)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid bundle signature: %w", err)
+		}
+		if len(b.Cert) == 0 {
+			return sig, nil, nil
+		}
+		cert, err := parseCertificate([]byte(b.Cert))
+		return sig, cert, err
+	}
+	if b.MessageSignature == nil {
+		return nil, nil, errors.New("bundle holds no message signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(b.MessageSignature.Signature)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bundle signature: %w", err)
+	}
+	var raw string
+	if m := b.VerificationMaterial; m != nil && m.Certificate != nil {
+		raw = m.Certificate.RawBytes
+	} else if m != nil && m.X509CertificateChain != nil && len(m.X509CertificateChain.Certificates) > 0 {
+		raw = m.X509CertificateChain.Certificates[0].RawBytes
+	} else {
+		return sig, nil, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bundle certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bundle certificate: %w", err)
+	}
+	return sig, cert, nil
+}
+
+// parseCertificate parses a PEM certificate, which may be base64 encoded as published by cosign
+func parseCertificate(content []byte) (*x509.Certificate, error) {
+	content = bytes.TrimSpace(content)
+	if !bytes.HasPrefix(content, []byte("-----BEGIN")) {
+		decoded, err := base64.StdEncoding.DecodeString(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		content = decoded
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("invalid certificate: no PEM block found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// verifyCosign verifies a detached base64 signature or a bundle, either with the public key of the trust store,
+// or with the Fulcio certificate of the signer identity. Transparency log entries are not verified.
+func (s *Signature) verifyCosign(file string, content []byte, certificate []byte) error {
+	var sig []byte
+	var cert *x509.Certificate
+	var err error
+	if isBundle(content) {
+		sig, cert, err = parseBundle(content)
+	} else {
+		sig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		if err == nil && certificate != nil {
+			cert, err = parseCertificate(certificate)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	var publicKey crypto.PublicKey
+	if len(s.Key) > 0 {
+		publicKey, err = s.publicKey()
+	} else if cert == nil {
+		err = errors.New("no certificate is published for keyless signature")
+	} else {
+		err = s.verifyCertificate(cert)
+		publicKey = cert.PublicKey
+	}
+	if err != nil {
+		return err
+	}
+	return verifyBlob(publicKey, file, sig)
+}
+
+func (s *Signature) publicKey() (crypto.PublicKey, error) {
+	content, err := os.ReadFile(filepath.Join(Dir(), s.Key))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read public key: %w", err)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key %s: no PEM block found", s.Key)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", s.Key, err)
+	}
+	return publicKey, nil
+}
+
+// verifyCertificate checks that the certificate was issued by Fulcio to the expected signer identity
+func (s *Signature) verifyCertificate(cert *x509.Certificate) error {
+	roots, intermediates, err := fulcioCertificates()
+	if err != nil {
+		return err
+	}
+	// Fulcio certificates are short-lived, they must only have been valid when the signature was made. The signing time
+	// is not proven without transparency log entry, which is why keyless signatures cannot be required.
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   cert.NotBefore,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return fmt.Errorf("certificate is not issued by Fulcio: %w", err)
+	}
+	issuer, err := certificateIssuer(cert)
+	if err != nil {
+		return err
+	}
+	if issuer != s.Issuer {
+		return fmt.Errorf("certificate issuer %s does not match %s", issuer, s.Issuer)
+	}
+	identities := cert.EmailAddresses
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	for _, identity := range identities {
+		if len(s.Identity) > 0 && identity == s.Identity {
+			return nil
+		}
+		if len(s.IdentityRegexp) > 0 {
+			matched, err := regexp.MatchString(s.IdentityRegexp, identity)
+			if err != nil {
+				return fmt.Errorf("invalid identity regexp: %w", err)
+			}
+			if matched {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("certificate identities %v do not match expected one", identities)
+}
+
+func certificateIssuer(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV2) {
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err != nil {
+				return "", fmt.Errorf("invalid certificate issuer: %w", err)
+			}
+			return issuer, nil
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV1) {
+			return string(ext.Value), nil
+		}
+	}
+	return "", errors.New("certificate has no OIDC issuer")
+}
+
+// fulcioCertificates returns the Fulcio root and intermediate certificates of the trust store
+func fulcioCertificates() (*x509.CertPool, *x509.CertPool, error) {
+	content, err := os.ReadFile(filepath.Join(Dir(), FULCIO_FILE))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read Fulcio certificates: %w", err)
+	}
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid Fulcio certificate: %w", err)
+		}
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			roots.AddCert(cert)
+		} else {
+			intermediates.AddCert(cert)
+		}
+	}
+	return roots, intermediates, nil
+}
+
+// verifyBlob verifies the signature of the SHA-256 of file (or of file itself with ed25519 keys)
+func verifyBlob(publicKey crypto.PublicKey, file string, sig []byte) error {
+	if key, ok := publicKey.(ed25519.PublicKey); ok {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(key, content, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseFile(f)
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return err
+	}
+	digest := hash.Sum(nil)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig)
+	}
+	return fmt.Errorf("unsupported public key type %T", publicKey)
+}
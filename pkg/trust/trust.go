@@ -0,0 +1,248 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trust
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/httpclient"
+	"github.com/gemalto/gokube/pkg/utils"
+)
+
+const (
+	POLICY_REQUIRE = "require"
+	POLICY_WARN    = "warn"
+	POLICY_OFF     = "off"
+	TYPE_COSIGN    = "cosign"
+	TYPE_GPG       = "gpg"
+	FULCIO_FILE    = "fulcio.pem"
+)
+
+// Signature declares where the signature of a downloaded release is published and how it is verified
+type Signature struct {
+	// Type is the signature type, cosign or gpg (no type meaning that no signature is published)
+	Type string `mapstructure:"type"`
+	// URL is the signature URL template, each %s being replaced by the version, or a suffix appended to the download URL
+	// if it starts with a dot (.sig for cosign and .asc for gpg by default). A cosign signature may be a bundle.
+	URL string `mapstructure:"url"`
+	// Certificate is the certificate URL of a detached keyless cosign signature, following URL rules
+	Certificate string `mapstructure:"certificate"`
+	// Key is the trust store file holding the public key (cosign) or the keyring (gpg) of the signer
+	Key string `mapstructure:"key"`
+	// Identity or IdentityRegexp match the signer identity of keyless cosign signatures
+	Identity       string `mapstructure:"identity"`
+	IdentityRegexp string `mapstructure:"identity-regexp"`
+	// Issuer is the OIDC issuer of the signer identity of keyless cosign signatures
+	Issuer string `mapstructure:"issuer"`
+	// Policy overrides the global verification policy
+	Policy string `mapstructure:"policy"`
+}
+
+// policy is the verification policy of tools which do not override it
+var policy = POLICY_OFF
+
+// signatures are the configured signatures by tool name, overriding the ones declared by tools
+var signatures map[string]*Signature
+
+// home returns the user home directory, holding the trust store and provenance evidence
+var home = utils.GetUserHome
+
+// Dir returns the trust store directory, holding signer keys and Fulcio certificates
+func Dir() string {
+	return filepath.Join(home(), ".gokube", "trust")
+}
+
+// evidenceDir returns the directory where the verified signatures of the given release are kept as provenance evidence
+func evidenceDir(name string, version string) string {
+	return filepath.Join(home(), ".gokube", "provenance", name+"-"+version)
+}
+
+// ValidatePolicy checks that the given verification policy exists
+func ValidatePolicy(p string) error {
+	switch p {
+	case POLICY_REQUIRE, POLICY_WARN, POLICY_OFF:
+		return nil
+	}
+	return fmt.Errorf("invalid signature policy %q, must be %s, %s or %s", p, POLICY_REQUIRE, POLICY_WARN, POLICY_OFF)
+}
+
+// SetPolicy sets the verification policy of tools which do not override it
+func SetPolicy(p string) error {
+	err := ValidatePolicy(p)
+	if err != nil {
+		return err
+	}
+	policy = p
+	return nil
+}
+
+// SetSignatures overrides the signatures declared by tools with the given ones, by tool name
+func SetSignatures(s map[string]*Signature) {
+	signatures = s
+}
+
+// Validate checks that the signature can be verified
+func (s *Signature) Validate() error {
+	if len(s.Policy) > 0 {
+		err := ValidatePolicy(s.Policy)
+		if err != nil {
+			return err
+		}
+	}
+	if len(s.Key) > 0 && filepath.Base(s.Key) != s.Key {
+		return fmt.Errorf("key %s must be a file name of the trust store", s.Key)
+	}
+	switch s.Type {
+	case "":
+		return nil
+	case TYPE_GPG:
+		if len(s.Key) == 0 {
+			return errors.New("gpg signature key is missing")
+		}
+		return nil
+	case TYPE_COSIGN:
+		if len(s.Key) == 0 && (len(s.Issuer) == 0 || len(s.Identity) == 0 && len(s.IdentityRegexp) == 0) {
+			return errors.New("cosign signature requires either a key or an identity and its issuer")
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid signature type %q, must be %s or %s", s.Type, TYPE_COSIGN, TYPE_GPG)
+}
+
+// Verify verifies the signature of the given file downloaded from the given URL, according to the verification policy:
+// with require, a missing or invalid signature is an error, with warn, it is only displayed. Keyless cosign signatures
+// can only be verified with warn, as their transparency log entry is not.
+func Verify(name string, version string, url string, file string, s *Signature) error {
+	if configured, ok := signatures[name]; ok {
+		s = configured
+	}
+	p := policy
+	if s != nil && len(s.Policy) > 0 {
+		p = s.Policy
+	}
+	if p == POLICY_OFF {
+		return nil
+	}
+	var err error
+	if s == nil || len(s.Type) == 0 {
+		err = fmt.Errorf("no signature is published for %s", name)
+	} else if s.isKeyless() && p == POLICY_REQUIRE {
+		// Without transparency log or timestamp, nothing proves that the short-lived certificate was valid when signing
+		err = fmt.Errorf("keyless signature of %s cannot be required as its transparency log entry is not verified, "+
+			"configure the key of the signer or the %s policy", name, POLICY_WARN)
+	} else {
+		if s.isKeyless() {
+			fmt.Printf("Warning: transparency log entry of %s keyless signature is not verified\n", name)
+		}
+		err = s.verify(name, version, url, file)
+	}
+	if err != nil && p == POLICY_WARN {
+		fmt.Printf("Warning: %s\n", err)
+		return nil
+	}
+	return err
+}
+
+// isKeyless returns true if the signature is a cosign one verified with a Fulcio certificate instead of a key
+func (s *Signature) isKeyless() bool {
+	return s.Type == TYPE_COSIGN && len(s.Key) == 0
+}
+
+func (s *Signature) verify(name string, version string, url string, file string) error {
+	err := s.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid %s signature: %w", name, err)
+	}
+	evidence := map[string][]byte{}
+	suffix := ".sig"
+	if s.Type == TYPE_GPG {
+		suffix = ".asc"
+	}
+	sigURL := s.url(s.URL, suffix, url, version)
+	content, err := fetch(sigURL)
+	if err != nil {
+		return fmt.Errorf("cannot verify signature of %s: %w", url, err)
+	}
+	evidence[sigURL] = content
+	switch s.Type {
+	case TYPE_GPG:
+		err = verifyGPG(file, content, s.Key)
+	case TYPE_COSIGN:
+		var certificate []byte
+		if len(s.Key) == 0 && len(s.Certificate) > 0 && !isBundle(content) {
+			certURL := s.url(s.Certificate, "", url, version)
+			certificate, err = fetch(certURL)
+			if err != nil {
+				break
+			}
+			evidence[certURL] = certificate
+		}
+		err = s.verifyCosign(file, content, certificate)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot verify signature of %s: %w", url, err)
+	}
+	fmt.Printf("Verified %s signature of %s\n", s.Type, url)
+	return writeEvidence(name, version, evidence)
+}
+
+// url returns the URL of the given template, being a suffix of the download URL if it starts with a dot
+func (s *Signature) url(tpl string, defaultSuffix string, url string, version string) string {
+	if len(tpl) == 0 {
+		tpl = defaultSuffix
+	}
+	if strings.HasPrefix(tpl, ".") {
+		return url + tpl
+	}
+	return strings.Replace(tpl, "%s", version, -1)
+}
+
+func fetch(url string) ([]byte, error) {
+	response, err := httpclient.New().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.Close(response.Body)
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("cannot download %s: %s", url, response.Status)
+	}
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download %s: %w", url, err)
+	}
+	return content, nil
+}
+
+// writeEvidence keeps the verified signatures and certificates of a release
+func writeEvidence(name string, version string, evidence map[string][]byte) error {
+	dir := evidenceDir(name, version)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("cannot create provenance directory %s: %w", dir, err)
+	}
+	for url, content := range evidence {
+		tokens := strings.Split(url, "/")
+		err = os.WriteFile(filepath.Join(dir, tokens[len(tokens)-1]), content, 0644)
+		if err != nil {
+			return fmt.Errorf("cannot write provenance evidence of %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trust
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const releaseContent = "gokube release"
+
+// setHome makes a temporary directory the user home, with an empty trust store
+func setHome(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	previous := home
+	home = func() string { return dir }
+	t.Cleanup(func() { home = previous })
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+}
+
+// newSigner generates a gpg key whose public keyring is written to the given trust store file
+func newSigner(t *testing.T, key string) *openpgp.Entity {
+	t.Helper()
+	entity, err := openpgp.NewEntity("gokube", "test", "gokube@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatalf("NewEntity() error = %v", err)
+	}
+	var keyring bytes.Buffer
+	w, err := armor.Encode(&keyring, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("armor.Encode() error = %v", err)
+	}
+	if err = entity.Serialize(w); err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(Dir(), key), keyring.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return entity
+}
+
+func sign(t *testing.T, signer *openpgp.Entity, content string, armored bool) []byte {
+	t.Helper()
+	var signature bytes.Buffer
+	var err error
+	if armored {
+		err = openpgp.ArmoredDetachSign(&signature, signer, strings.NewReader(content), nil)
+	} else {
+		err = openpgp.DetachSign(&signature, signer, strings.NewReader(content), nil)
+	}
+	if err != nil {
+		t.Fatalf("DetachSign() error = %v", err)
+	}
+	return signature.Bytes()
+}
+
+// writeRelease writes a downloaded release and returns its path
+func writeRelease(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "release.zip")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return file
+}
+
+// newSignaturesServer serves the given signatures by path, any other path being not found
+func newSignaturesServer(t *testing.T, signatures map[string][]byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := signatures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		tpl           string
+		defaultSuffix string
+		want          string
+	}{
+		{"default suffix", "", ".sig", "https://example.com/v1.2.0/tool.zip.sig"},
+		{"suffix", ".asc", ".sig", "https://example.com/v1.2.0/tool.zip.asc"},
+		{"bundle suffix", ".sigstore.json", ".sig", "https://example.com/v1.2.0/tool.zip.sigstore.json"},
+		{"template", "https://example.com/%s/SHA256SUMS-%s.sig", ".sig", "https://example.com/v1.2.0/SHA256SUMS-v1.2.0.sig"},
+		{"template without version", "https://example.com/latest.sig", ".sig", "https://example.com/latest.sig"},
+		{"no template", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Signature{}
+			if got := s.url(tt.tpl, tt.defaultSuffix, "https://example.com/v1.2.0/tool.zip", "v1.2.0"); got != tt.want {
+				t.Errorf("url() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyGPG(t *testing.T) {
+	setHome(t)
+	signer := newSigner(t, "signer.asc")
+	file := writeRelease(t, releaseContent)
+	for _, armored := range []bool{true, false} {
+		if err := verifyGPG(file, sign(t, signer, releaseContent, armored), "signer.asc"); err != nil {
+			t.Errorf("verifyGPG() armored %v error = %v", armored, err)
+		}
+	}
+	if err := verifyGPG(file, sign(t, signer, "tampered release", true), "signer.asc"); err == nil {
+		t.Errorf("verifyGPG() of a tampered release error = nil, want invalid signature")
+	}
+	other := newSigner(t, "other.asc")
+	if err := verifyGPG(file, sign(t, other, releaseContent, true), "signer.asc"); err == nil {
+		t.Errorf("verifyGPG() signed by another key error = nil, want unknown signer")
+	}
+	if err := verifyGPG(file, sign(t, signer, releaseContent, true), "missing.asc"); err == nil || !strings.Contains(err.Error(), "cannot read keyring") {
+		t.Errorf("verifyGPG() error = %v, want missing keyring", err)
+	}
+}
+
+func TestVerifyPolicy(t *testing.T) {
+	setHome(t)
+	signer := newSigner(t, "signer.asc")
+	file := writeRelease(t, releaseContent)
+	server := newSignaturesServer(t, map[string][]byte{
+		"/v1.2.0/tool.zip.asc":     sign(t, signer, releaseContent, true),
+		"/v1.2.0/tampered.zip.asc": sign(t, signer, "tampered release", true),
+	})
+	keyless := &Signature{Type: TYPE_COSIGN, Issuer: "https://token.actions.githubusercontent.com", Identity: "https://github.com/example/tool/.github/workflows/release.yml@refs/heads/main"}
+	keyed := &Signature{Type: TYPE_GPG, Key: "signer.asc"}
+	tests := []struct {
+		policy    string
+		signature *Signature
+		url       string
+		wantErr   string
+	}{
+		{POLICY_OFF, nil, "/v1.2.0/tool.zip", ""},
+		{POLICY_OFF, keyless, "/v1.2.0/tool.zip", ""},
+		{POLICY_OFF, keyed, "/v1.2.0/tampered.zip", ""},
+		{POLICY_WARN, nil, "/v1.2.0/tool.zip", ""},
+		{POLICY_WARN, &Signature{}, "/v1.2.0/tool.zip", ""},
+		{POLICY_WARN, keyless, "/v1.2.0/tool.zip", ""},
+		{POLICY_WARN, keyed, "/v1.2.0/tool.zip", ""},
+		{POLICY_WARN, keyed, "/v1.2.0/tampered.zip", ""},
+		{POLICY_REQUIRE, nil, "/v1.2.0/tool.zip", "no signature is published for tool"},
+		{POLICY_REQUIRE, &Signature{}, "/v1.2.0/tool.zip", "no signature is published for tool"},
+		{POLICY_REQUIRE, keyless, "/v1.2.0/tool.zip", "keyless signature of tool cannot be required"},
+		{POLICY_REQUIRE, keyed, "/v1.2.0/tool.zip", ""},
+		{POLICY_REQUIRE, keyed, "/v1.2.0/tampered.zip", "cannot verify signature of " + server.URL + "/v1.2.0/tampered.zip"},
+		{POLICY_REQUIRE, keyed, "/v1.2.0/unsigned.zip", "404 Not Found"},
+	}
+	for _, tt := range tests {
+		name := tt.policy + " " + tt.url
+		if tt.signature != nil {
+			name += " " + tt.signature.Type + " " + tt.signature.Key
+		}
+		t.Run(name, func(t *testing.T) {
+			if err := SetPolicy(tt.policy); err != nil {
+				t.Fatalf("SetPolicy() error = %v", err)
+			}
+			t.Cleanup(func() { policy = POLICY_OFF })
+			err := Verify("tool", "v1.2.0", server.URL+tt.url, file, tt.signature)
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Errorf("Verify() error = %v, want nil", err)
+			} else if len(tt.wantErr) > 0 && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Verify() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyOverrides(t *testing.T) {
+	setHome(t)
+	signer := newSigner(t, "signer.asc")
+	file := writeRelease(t, releaseContent)
+	server := newSignaturesServer(t, map[string][]byte{
+		"/v1.2.0/tool.zip.asc": sign(t, signer, releaseContent, true),
+	})
+	t.Cleanup(func() { policy, signatures = POLICY_OFF, nil })
+
+	// A signature policy overrides the global one
+	err := Verify("tool", "v1.2.0", server.URL+"/v1.2.0/tool.zip", file, &Signature{Policy: POLICY_REQUIRE})
+	if err == nil || !strings.Contains(err.Error(), "no signature is published") {
+		t.Errorf("Verify() error = %v, want missing signature required by the tool policy", err)
+	}
+	// A configured signature overrides the one declared by the tool
+	SetSignatures(map[string]*Signature{"tool": {Type: TYPE_GPG, Key: "signer.asc", Policy: POLICY_REQUIRE}})
+	err = Verify("tool", "v1.2.0", server.URL+"/v1.2.0/tool.zip", file, nil)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	evidence, err := os.ReadFile(filepath.Join(evidenceDir("tool", "v1.2.0"), "tool.zip.asc"))
+	if err != nil {
+		t.Fatalf("Verify() did not keep the signature as provenance evidence: %v", err)
+	}
+	if !bytes.HasPrefix(evidence, []byte("-----BEGIN PGP SIGNATURE-----")) {
+		t.Errorf("Verify() evidence = %s, want the verified signature", evidence)
+	}
+}
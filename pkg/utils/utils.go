@@ -15,12 +15,9 @@ limitations under the License.
 package utils
 
 import (
-	"archive/tar"
-	"archive/zip"
-	"bufio"
-	"compress/gzip"
 	"errors"
 	"fmt"
+	"github.com/gemalto/gokube/pkg/runner"
 	"gopkg.in/cheggaaa/pb.v2"
 	"io"
 	"os"
@@ -28,9 +25,12 @@ import (
 	"os/user"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
+var reVersion = regexp.MustCompile(`v?(\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?)`)
+
 // GetAppDataHome ...
 func GetAppDataHome() string {
 	return os.Getenv("APPDATA")
@@ -79,6 +79,9 @@ func CreateDirs(dirPath string) error {
 
 // CleanDir ...
 func CleanDir(dirPath string) error {
+	if runner.DryRun("clean directory %s", dirPath) {
+		return nil
+	}
 	entries, err := os.ReadDir(dirPath)
 	if err == nil {
 		for _, e := range entries {
@@ -91,6 +94,68 @@ func CleanDir(dirPath string) error {
 	return nil
 }
 
+// RemoveAll removes the given path, unless in dry-run mode
+func RemoveAll(path string) error {
+	if runner.DryRun("remove %s", path) {
+		return nil
+	}
+	return os.RemoveAll(path)
+}
+
+// CopyDir recursively copies the content of src directory into dst directory, src being resolved first if it is a symlink
+func CopyDir(src string, dst string) error {
+	src, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(src, func(srcPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, srcPath)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dst, relPath)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(dstPath, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(srcPath)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, dstPath)
+		case info.Mode().IsRegular():
+			return CopyFile(srcPath, dstPath, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// CopyFile copies src file content into dst file, created with the given permissions
+func CopyFile(src string, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer CloseFile(in)
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		CloseFile(out)
+		return err
+	}
+	return out.Close()
+}
+
 // DeleteDir ...
 func DeleteDir(dirPath string) {
 	err := os.RemoveAll(dirPath)
@@ -117,24 +182,6 @@ func CloseFile(file *os.File) {
 	}
 }
 
-func CloseGZipReader(reader *gzip.Reader) {
-	if reader != nil {
-		err := reader.Close()
-		if err != nil {
-			fmt.Printf("Warning: cannot close reader %s: %s\n", reader.Name, err)
-		}
-	}
-}
-
-func CloseZipReader(reader *zip.ReadCloser) {
-	if reader != nil {
-		err := reader.Close()
-		if err != nil {
-			fmt.Printf("Warning: cannot close reader: %s\n", err)
-		}
-	}
-}
-
 func ClosePBReader(reader *pb.Reader) {
 	if reader != nil {
 		err := reader.Close()
@@ -144,124 +191,6 @@ func ClosePBReader(reader *pb.Reader) {
 	}
 }
 
-// Untar takes a destination path and a reader; a tar reader loops over the tarfile
-// creating the file structure at 'dst' along the way, and writing any files
-func Untar(src string, dst string) error {
-
-	file, err := os.Open(src)
-	defer CloseFile(file)
-	if err != nil {
-		panic(err)
-	}
-	gzr, err := gzip.NewReader(bufio.NewReader(file))
-	defer CloseGZipReader(gzr)
-	if err != nil {
-		return err
-	}
-
-	tr := tar.NewReader(gzr)
-
-	for {
-		header, err := tr.Next()
-
-		switch {
-		// if no more files are found return
-		case err == io.EOF:
-			return nil
-		// return any other error
-		case err != nil:
-			return err
-		// if the header is nil, just skip it (not sure how this happens)
-		case header == nil:
-			continue
-		}
-
-		// the target location where the dir/file should be created
-		target := filepath.Join(dst, header.Name)
-
-		// check the file type
-		switch header.Typeflag {
-		// if it's a dir and it doesn't exist create it
-		case tar.TypeDir:
-			if _, err := os.Stat(target); err != nil {
-				if err := os.MkdirAll(target, 0755); err != nil {
-					return err
-				}
-			}
-		// if it's a file create it
-		case tar.TypeReg:
-			if _, err := os.Stat(filepath.Dir(target)); err != nil {
-				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
-					return err
-				}
-			}
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
-			if err != nil {
-				return err
-			}
-			// copy over contents
-			if _, err := io.Copy(f, tr); err != nil {
-				return err
-			}
-			err = f.Close()
-			if err != nil {
-				return err
-			}
-		}
-	}
-}
-
-// Unzip ...
-func Unzip(src string, dest string) error {
-
-	var fileNames []string
-
-	r, err := zip.OpenReader(src)
-	defer CloseZipReader(r)
-	if err != nil {
-		return err
-	}
-
-	for _, f := range r.File {
-
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-
-		// Store filename/path for returning and using later on
-		fileName := filepath.Join(dest, f.Name)
-		fileNames = append(fileNames, fileName)
-
-		if f.FileInfo().IsDir() {
-			// Make Folder
-			err = os.MkdirAll(fileName, 0755)
-			if err != nil {
-				return err
-			}
-		} else {
-			// Make File
-			if err = os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
-				return err
-			}
-			outFile, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				return err
-			}
-			_, err = io.Copy(outFile, rc)
-			err = outFile.Close()
-			if err != nil {
-				return err
-			}
-		}
-		err = rc.Close()
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 // GetValueFromEnv ...
 func GetValueFromEnv(envVar string, defaultValue string) string {
 	var value = os.Getenv(envVar)
@@ -272,3 +201,17 @@ func GetValueFromEnv(envVar string, defaultValue string) string {
 	}
 	return value
 }
+
+// ParseVersion returns the first version number found in the given command output (without "v" prefix), or an empty string
+func ParseVersion(output string) string {
+	m := reVersion.FindStringSubmatch(output)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// SameVersion returns true if both versions are equal, ignoring any "v" prefix
+func SameVersion(v1 string, v2 string) bool {
+	return strings.TrimPrefix(v1, "v") == strings.TrimPrefix(v2, "v")
+}
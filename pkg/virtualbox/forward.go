@@ -0,0 +1,108 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualbox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NATPortForward describes a port-forwarding rule of minikube VM NAT network interface
+type NATPortForward struct {
+	Name      string
+	Protocol  string
+	HostIP    string
+	HostPort  int
+	GuestIP   string
+	GuestPort int
+}
+
+func (r *NATPortForward) String() string {
+	return fmt.Sprintf("%s,%s,%s,%d,%s,%d", r.Name, r.Protocol, r.HostIP, r.HostPort, r.GuestIP, r.GuestPort)
+}
+
+// ListNATPortForwards returns the port-forwarding rules of minikube VM NAT network interface
+func ListNATPortForwards() ([]*NATPortForward, error) {
+	info, err := vboxManager.vbmQuery("showvminfo", "minikube", "--machinereadable")
+	if err != nil {
+		return nil, fmt.Errorf("not able to get VM info: %w", err)
+	}
+	var rules []*NATPortForward
+	err = parseKeyValues(info, reMachineReadableLine, func(key, val string) error {
+		if !strings.HasPrefix(key, "Forwarding(") {
+			return nil
+		}
+		fields := strings.Split(val, ",")
+		if len(fields) != 6 {
+			return fmt.Errorf("unexpected port-forwarding rule %q", val)
+		}
+		hostPort, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return fmt.Errorf("invalid host port of port-forwarding rule %q: %w", val, err)
+		}
+		guestPort, err := strconv.Atoi(fields[5])
+		if err != nil {
+			return fmt.Errorf("invalid guest port of port-forwarding rule %q: %w", val, err)
+		}
+		rules = append(rules, &NATPortForward{
+			Name:      fields[0],
+			Protocol:  fields[1],
+			HostIP:    fields[2],
+			HostPort:  hostPort,
+			GuestIP:   fields[4],
+			GuestPort: guestPort,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// AddNATPortForward adds a port-forwarding rule to minikube VM NAT network interface (whether VM is running or not)
+func AddNATPortForward(rule *NATPortForward) error {
+	running, err := IsRunning()
+	if err != nil {
+		return err
+	}
+	if running {
+		err = vboxManager.vbm("controlvm", "minikube", "natpf1", rule.String())
+	} else {
+		err = vboxManager.vbm("modifyvm", "minikube", "--natpf1", rule.String())
+	}
+	if err != nil {
+		return fmt.Errorf("not able to add port-forwarding rule %s: %w", rule.Name, err)
+	}
+	return nil
+}
+
+// RemoveNATPortForward removes a port-forwarding rule from minikube VM NAT network interface (whether VM is running or not)
+func RemoveNATPortForward(name string) error {
+	running, err := IsRunning()
+	if err != nil {
+		return err
+	}
+	if running {
+		err = vboxManager.vbm("controlvm", "minikube", "natpf1", "delete", name)
+	} else {
+		err = vboxManager.vbm("modifyvm", "minikube", "--natpf1", "delete", name)
+	}
+	if err != nil {
+		return fmt.Errorf("not able to remove port-forwarding rule %s: %w", name, err)
+	}
+	return nil
+}
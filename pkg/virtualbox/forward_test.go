@@ -0,0 +1,70 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualbox
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gemalto/gokube/pkg/runner"
+)
+
+func TestListNATPortForwards(t *testing.T) {
+	record(t, map[string]*runner.Response{
+		"VBoxManage showvminfo minikube --machinereadable": stdout(minikubeInfo + `Forwarding(0)="ssh,tcp,127.0.0.1,50022,,22"
+Forwarding(1)="gokube-30080,tcp,,8080,,30080"
+Forwarding(2)="dns,udp,127.0.0.1,5353,10.0.2.15,53"
+`),
+	})
+	rules, err := ListNATPortForwards()
+	if err != nil {
+		t.Fatalf("ListNATPortForwards() error = %v", err)
+	}
+	want := []*NATPortForward{
+		{Name: "ssh", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: 50022, GuestPort: 22},
+		{Name: "gokube-30080", Protocol: "tcp", HostPort: 8080, GuestPort: 30080},
+		{Name: "dns", Protocol: "udp", HostIP: "127.0.0.1", HostPort: 5353, GuestIP: "10.0.2.15", GuestPort: 53},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("ListNATPortForwards() = %v, want %v", rules, want)
+	}
+	if got := rules[1].String(); got != "gokube-30080,tcp,,8080,,30080" {
+		t.Errorf("String() = %s, want gokube-30080,tcp,,8080,,30080", got)
+	}
+}
+
+func TestListNATPortForwardsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr string
+	}{
+		{"missing field", `Forwarding(0)="ssh,tcp,127.0.0.1,50022,22"`, "unexpected port-forwarding rule"},
+		{"invalid host port", `Forwarding(0)="ssh,tcp,127.0.0.1,http,,22"`, "invalid host port"},
+		{"invalid guest port", `Forwarding(0)="ssh,tcp,127.0.0.1,50022,,"`, "invalid guest port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record(t, map[string]*runner.Response{
+				"VBoxManage showvminfo minikube --machinereadable": stdout(minikubeInfo + tt.rule + "\n"),
+			})
+			_, err := ListNATPortForwards()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ListNATPortForwards() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,485 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualbox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gemalto/gokube/pkg/runner"
+)
+
+var (
+	ErrCidrTooSmall = errors.New("host-only CIDR prefix length must be 24 or lower for the VM to get a predictable IP address")
+)
+
+// ExpectedVMIP returns the IP address the DHCP server of the host-only network (as configured by minikube) leases first
+func ExpectedVMIP(hostOnlyCIDR string) (string, error) {
+	_, network, err := parseAndValidateCIDR(hostOnlyCIDR)
+	if err != nil {
+		return "", err
+	}
+	ones, _ := network.Mask.Size()
+	if ones > 24 {
+		return "", ErrCidrTooSmall
+	}
+	// minikube DHCP range for /24 (and larger) networks starts at x.x.x.100
+	ip := network.IP.To4()
+	return net.IPv4(ip[0], ip[1], ip[2], 100).String(), nil
+}
+
+// ValidateHostOnlyCIDR checks that the host-only network does not overlap with host interfaces or routes (other than VirtualBox host-only ones)
+func ValidateHostOnlyCIDR(hostOnlyCIDR string) error {
+	_, network, err := parseAndValidateCIDR(hostOnlyCIDR)
+	if err != nil {
+		return err
+	}
+	if _, err := ExpectedVMIP(hostOnlyCIDR); err != nil {
+		return err
+	}
+	hostOnlyIPs := map[string]bool{}
+	nets, err := listHostOnlyAdapters(vboxManager)
+	if err != nil {
+		return fmt.Errorf("not able to list host-only network interfaces: %w", err)
+	}
+	for _, n := range nets {
+		hostOnlyIPs[n.IPv4.IP.String()] = true
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return fmt.Errorf("not able to list host network interfaces: %w", err)
+	}
+	for _, addr := range addrs {
+		ip, ipNet, err := net.ParseCIDR(addr.String())
+		if err != nil || ip.To4() == nil || ip.IsLoopback() || hostOnlyIPs[ip.String()] {
+			continue
+		}
+		if overlaps(network, ipNet) {
+			return fmt.Errorf("host-only network %s overlaps with host network interface address %s", network, addr)
+		}
+	}
+
+	routes, err := listRoutes()
+	if err != nil {
+		return fmt.Errorf("not able to list host routes: %w", err)
+	}
+	for _, route := range routes {
+		if hostOnlyIPs[route.iface] {
+			continue
+		}
+		if overlaps(network, route.destination) {
+			return fmt.Errorf("host-only network %s overlaps with host route to %s (through interface %s)", network, route.destination, route.iface)
+		}
+	}
+	return nil
+}
+
+// ReserveVMIP configures the DHCP server of the host-only network to always lease the given IP to minikube VM, and returns the VM MAC address
+func ReserveVMIP(hostOnlyCIDR string, vmIP string) (string, error) {
+	// In dry-run mode, minikube VM or its host-only network may not have been created yet
+	if runner.DryRun("reserve IP %s for minikube VM in DHCP server of host-only network %s", vmIP, hostOnlyCIDR) {
+		return "", nil
+	}
+	hostOnlyNet, err := findHostOnlyAdapter(hostOnlyCIDR)
+	if err != nil {
+		return "", err
+	}
+	mac, err := getHostOnlyMAC(hostOnlyNet)
+	if err != nil {
+		return "", err
+	}
+	err = vboxManager.vbm("dhcpserver", "modify", "--network="+hostOnlyNet.NetworkName, "--mac-address="+mac, "--fixed-address="+vmIP)
+	if err != nil {
+		return "", fmt.Errorf("not able to reserve IP %s for MAC address %s: %w", vmIP, mac, err)
+	}
+	err = vboxManager.vbm("dhcpserver", "restart", "--network="+hostOnlyNet.NetworkName)
+	if err != nil {
+		return "", fmt.Errorf("not able to restart DHCP server of host-only network: %w", err)
+	}
+	return mac, nil
+}
+
+// ReleaseVMIP removes the DHCP reservation previously made for the given MAC address
+func ReleaseVMIP(hostOnlyCIDR string, mac string) error {
+	hostOnlyNet, err := findHostOnlyAdapter(hostOnlyCIDR)
+	if err != nil {
+		return err
+	}
+	err = vboxManager.vbm("dhcpserver", "modify", "--network="+hostOnlyNet.NetworkName, "--mac-address="+mac, "--remove-config")
+	if err != nil {
+		return fmt.Errorf("not able to remove DHCP reservation for MAC address %s: %w", mac, err)
+	}
+	return nil
+}
+
+// findHostOnlyAdapter returns the host-only network matching the given CIDR
+func findHostOnlyAdapter(hostOnlyCIDR string) (*hostOnlyNetwork, error) {
+	nets, err := listHostOnlyAdapters(vboxManager)
+	if err != nil {
+		return nil, fmt.Errorf("not able to list host-only network interfaces: %w", err)
+	}
+	ip, network, err := parseAndValidateCIDR(hostOnlyCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("not able to parse CIDR to find host-only network interface: %w", err)
+	}
+	hostOnlyNet := getHostOnlyAdapter(nets, ip, network.Mask)
+	if hostOnlyNet == nil {
+		return nil, fmt.Errorf("no host-only network interface matching %s", hostOnlyCIDR)
+	}
+	return hostOnlyNet, nil
+}
+
+// getHostOnlyMAC returns the MAC address of minikube VM NIC attached to the given host-only network
+func getHostOnlyMAC(hostOnlyNet *hostOnlyNetwork) (string, error) {
+	info, err := vboxManager.vbmQuery("showvminfo", "minikube", "--machinereadable")
+	if err != nil {
+		return "", fmt.Errorf("not able to get VM info: %w", err)
+	}
+	props := map[string]string{}
+	err = parseKeyValues(info, reMachineReadableLine, func(key, val string) error {
+		props[key] = val
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	for nic := 1; nic <= 8; nic++ {
+		if props[fmt.Sprintf("nic%d", nic)] == "hostonly" && props[fmt.Sprintf("hostonlyadapter%d", nic)] == hostOnlyNet.Name {
+			mac, err := net.ParseMAC(formatMAC(props[fmt.Sprintf("macaddress%d", nic)]))
+			if err != nil {
+				return "", fmt.Errorf("not able to parse VM MAC address: %w", err)
+			}
+			return mac.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no VM network interface attached to host-only network %s", hostOnlyNet.Name)
+}
+
+// formatMAC converts VBox MAC address format (080027AABBCC) to a colon separated one
+func formatMAC(mac string) string {
+	if len(mac) != 12 {
+		return mac
+	}
+	var parts []string
+	for i := 0; i < 12; i += 2 {
+		parts = append(parts, mac[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+type route struct {
+	destination *net.IPNet
+	iface       string
+}
+
+// listRoutes parses the IPv4 active routes displayed by Windows route command
+func listRoutes() ([]*route, error) {
+	out, err := runner.Inspect("route", "print", "-4")
+	if err != nil {
+		return nil, err
+	}
+	var routes []*route
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 5 {
+			continue
+		}
+		destination := net.ParseIP(fields[0]).To4()
+		mask := net.ParseIP(fields[1]).To4()
+		if destination == nil || mask == nil {
+			continue
+		}
+		network := &net.IPNet{IP: destination.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+		ones, _ := network.Mask.Size()
+		// Default, loopback, multicast and broadcast routes are not relevant
+		if ones == 0 || destination.IsLoopback() || destination.IsMulticast() || destination.Equal(net.IPv4bcast) {
+			continue
+		}
+		routes = append(routes, &route{destination: network, iface: fields[3]})
+	}
+	return routes, nil
+}
+
+func overlaps(n1 *net.IPNet, n2 *net.IPNet) bool {
+	return n1.Contains(n2.IP) || n2.Contains(n1.IP)
+}
+
+var (
+	reVMLine             = regexp.MustCompile(`^"(.+)" \{(.+)\}$`)
+	reHostOnlyIfCreated  = regexp.MustCompile(`Interface '(.+)' was successfully created`)
+	reHostOnlyAdapterKey = regexp.MustCompile(`^hostonlyadapter\d+$`)
+)
+
+// HostOnlyNetwork describes a host-only network, its DHCP server and the VMs using it
+type HostOnlyNetwork struct {
+	Name        string
+	NetworkName string
+	IPv4        net.IPNet
+	DHCPServer  *DHCPServer
+	VMs         []string
+}
+
+// DHCPServer describes a VirtualBox DHCP server
+type DHCPServer struct {
+	NetworkName string
+	IP          net.IP
+	Mask        net.IPMask
+	LowerIP     net.IP
+	UpperIP     net.IP
+	Enabled     bool
+}
+
+// ListHostOnlyNetworks returns host-only networks and DHCP servers which are not bound to any host-only network
+func ListHostOnlyNetworks() ([]*HostOnlyNetwork, []*DHCPServer, error) {
+	nets, err := listHostOnlyAdapters(vboxManager)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not able to list host-only network interfaces: %w", err)
+	}
+	dhcpServers, err := listDHCPServers()
+	if err != nil {
+		return nil, nil, fmt.Errorf("not able to list DHCP servers: %w", err)
+	}
+	usage, err := listHostOnlyAdaptersUsage()
+	if err != nil {
+		return nil, nil, fmt.Errorf("not able to list VMs network interfaces: %w", err)
+	}
+	var networks []*HostOnlyNetwork
+	for _, n := range nets {
+		network := &HostOnlyNetwork{
+			Name:        n.Name,
+			NetworkName: n.NetworkName,
+			IPv4:        n.IPv4,
+			DHCPServer:  dhcpServers[n.NetworkName],
+			VMs:         usage[n.Name],
+		}
+		delete(dhcpServers, n.NetworkName)
+		networks = append(networks, network)
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	var orphanDHCPServers []*DHCPServer
+	for _, dhcp := range dhcpServers {
+		// Only DHCP servers of host-only networks are relevant
+		if strings.HasPrefix(dhcp.NetworkName, "HostInterfaceNetworking-") {
+			orphanDHCPServers = append(orphanDHCPServers, dhcp)
+		}
+	}
+	sort.Slice(orphanDHCPServers, func(i, j int) bool { return orphanDHCPServers[i].NetworkName < orphanDHCPServers[j].NetworkName })
+	return networks, orphanDHCPServers, nil
+}
+
+// IsOwned returns true if the host-only network is the one used (or to be used) by minikube VM
+func (n *HostOnlyNetwork) IsOwned(hostOnlyCIDR string) bool {
+	for _, vm := range n.VMs {
+		if vm == "minikube" {
+			return true
+		}
+	}
+	ip, network, err := parseAndValidateCIDR(hostOnlyCIDR)
+	if err != nil {
+		return false
+	}
+	return ip.Equal(n.IPv4.IP) && (network.Mask.String() == n.IPv4.Mask.String() || n.IPv4.Mask.String() == buggyNetmask)
+}
+
+// RemoveHostOnlyNetwork removes the host-only network interface and its DHCP server
+func RemoveHostOnlyNetwork(n *HostOnlyNetwork) error {
+	if n.DHCPServer != nil {
+		err := RemoveDHCPServer(n.DHCPServer)
+		if err != nil {
+			return err
+		}
+	}
+	err := vboxManager.vbm("hostonlyif", "remove", n.Name)
+	if err != nil {
+		return fmt.Errorf("not able to remove host-only network interface %s: %w", n.Name, err)
+	}
+	return nil
+}
+
+// RemoveDHCPServer removes the given DHCP server
+func RemoveDHCPServer(dhcp *DHCPServer) error {
+	err := vboxManager.vbm("dhcpserver", "remove", "--network="+dhcp.NetworkName)
+	if err != nil {
+		return fmt.Errorf("not able to remove DHCP server %s: %w", dhcp.NetworkName, err)
+	}
+	return nil
+}
+
+// RecreateHostOnlyNetwork creates a new host-only network for the given CIDR and attaches it to minikube VM (which must be stopped), replacing the previous one
+func RecreateHostOnlyNetwork(hostOnlyCIDR string) (string, error) {
+	ip, network, err := parseAndValidateCIDR(hostOnlyCIDR)
+	if err != nil {
+		return "", err
+	}
+	lowerIP, err := ExpectedVMIP(hostOnlyCIDR)
+	if err != nil {
+		return "", err
+	}
+	networks, _, err := ListHostOnlyNetworks()
+	if err != nil {
+		return "", err
+	}
+	nic, err := getHostOnlyNIC()
+	if err != nil {
+		return "", err
+	}
+	// Nothing is removed unless all previous networks can be
+	var owned []*HostOnlyNetwork
+	for _, n := range networks {
+		if n.IsOwned(hostOnlyCIDR) {
+			for _, vm := range n.VMs {
+				if vm != "minikube" {
+					return "", fmt.Errorf("host-only network interface %s is also used by VM %s", n.Name, vm)
+				}
+			}
+			owned = append(owned, n)
+		}
+	}
+	for _, n := range owned {
+		err = RemoveHostOnlyNetwork(n)
+		if err != nil {
+			return "", err
+		}
+	}
+	if runner.DryRun("create host-only network interface %s with its DHCP server and attach it to minikube VM NIC %d", hostOnlyCIDR, nic) {
+		return "", nil
+	}
+	out, err := vboxManager.vbmOut("hostonlyif", "create")
+	if err != nil {
+		return "", fmt.Errorf("not able to create host-only network interface: %w", err)
+	}
+	res := reHostOnlyIfCreated.FindStringSubmatch(out)
+	if res == nil {
+		return "", fmt.Errorf("not able to find created host-only network interface name in %q", out)
+	}
+	name := res[1]
+	netmask := net.IP(network.Mask).String()
+	err = vboxManager.vbm("hostonlyif", "ipconfig", name, "--ip", ip.String(), "--netmask", netmask)
+	if err != nil {
+		return "", fmt.Errorf("not able to configure host-only network interface %s: %w", name, err)
+	}
+	networkIP := network.IP.To4()
+	serverIP := net.IPv4(networkIP[0], networkIP[1], networkIP[2], networkIP[3]+2).String()
+	upperIP := net.IPv4(networkIP[0], networkIP[1], networkIP[2], 254).String()
+	err = vboxManager.vbm("dhcpserver", "add", "--network=HostInterfaceNetworking-"+name, "--server-ip="+serverIP, "--netmask="+netmask,
+		"--lower-ip="+lowerIP, "--upper-ip="+upperIP, "--enable")
+	if err != nil {
+		return "", fmt.Errorf("not able to create DHCP server of host-only network interface %s: %w", name, err)
+	}
+	err = Update(fmt.Sprintf("--hostonlyadapter%d", nic), name)
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// getHostOnlyNIC returns the index of minikube VM NIC attached to a host-only network
+func getHostOnlyNIC() (int, error) {
+	info, err := vboxManager.vbmQuery("showvminfo", "minikube", "--machinereadable")
+	if err != nil {
+		return 0, fmt.Errorf("not able to get VM info: %w", err)
+	}
+	nic := 0
+	err = parseKeyValues(info, reMachineReadableLine, func(key, val string) error {
+		if nic == 0 && strings.HasPrefix(key, "nic") && val == "hostonly" {
+			nic, _ = strconv.Atoi(strings.TrimPrefix(key, "nic"))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if nic == 0 {
+		return 0, fmt.Errorf("no minikube VM network interface attached to a host-only network")
+	}
+	return nic, nil
+}
+
+func listDHCPServers() (map[string]*DHCPServer, error) {
+	out, err := vboxManager.vbmQuery("list", "dhcpservers")
+	if err != nil {
+		return nil, err
+	}
+	servers := map[string]*DHCPServer{}
+	var dhcp *DHCPServer
+	err = parseKeyValues(out, reColonLine, func(key, val string) error {
+		switch strings.TrimSpace(key) {
+		case "NetworkName":
+			dhcp = &DHCPServer{NetworkName: val}
+			servers[val] = dhcp
+		case "Dhcpd IP", "IP":
+			if dhcp != nil {
+				dhcp.IP = net.ParseIP(val)
+			}
+		case "NetworkMask":
+			if dhcp != nil {
+				dhcp.Mask = parseIPv4Mask(val)
+			}
+		case "LowerIPAddress", "lowerIPAddress":
+			if dhcp != nil {
+				dhcp.LowerIP = net.ParseIP(val)
+			}
+		case "UpperIPAddress", "upperIPAddress":
+			if dhcp != nil {
+				dhcp.UpperIP = net.ParseIP(val)
+			}
+		case "Enabled":
+			if dhcp != nil {
+				dhcp.Enabled = val == "Yes"
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// listHostOnlyAdaptersUsage returns the names of VMs using each host-only network interface
+func listHostOnlyAdaptersUsage() (map[string][]string, error) {
+	out, err := vboxManager.vbmQuery("list", "vms")
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string][]string{}
+	for _, line := range strings.Split(out, "\n") {
+		res := reVMLine.FindStringSubmatch(strings.TrimSpace(line))
+		if res == nil {
+			continue
+		}
+		vm := res[1]
+		info, err := vboxManager.vbmQuery("showvminfo", res[2], "--machinereadable")
+		if err != nil {
+			return nil, err
+		}
+		err = parseKeyValues(info, reMachineReadableLine, func(key, val string) error {
+			if reHostOnlyAdapterKey.MatchString(key) {
+				usage[val] = append(usage[val], vm)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return usage, nil
+}
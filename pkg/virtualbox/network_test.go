@@ -0,0 +1,446 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualbox
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gemalto/gokube/pkg/runner"
+)
+
+// hostOnlyIfs is the output of VBoxManage list hostonlyifs with two host-only networks
+const hostOnlyIfs = `Name:            vboxnet0
+GUID:            786f6276-656e-4074-8000-0a0027000000
+DHCP:            Disabled
+IPAddress:       192.168.99.1
+NetworkMask:     255.255.255.0
+IPV6Address:
+IPV6NetworkMaskPrefixLength: 0
+HardwareAddress: 0a:00:27:00:00:00
+MediumType:      Ethernet
+Wireless:        No
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet0
+
+Name:            vboxnet1
+GUID:            786f6276-656e-4174-8000-0a0027000001
+DHCP:            Disabled
+IPAddress:       192.168.56.1
+NetworkMask:     255.255.255.0
+IPV6Address:
+IPV6NetworkMaskPrefixLength: 0
+HardwareAddress: 0a:00:27:00:00:01
+MediumType:      Ethernet
+Wireless:        No
+Status:          Up
+VBoxNetworkName: HostInterfaceNetworking-vboxnet1
+`
+
+// routePrint is the output of Windows route print -4, with routes through host-only and corporate interfaces
+const routePrint = `===========================================================================
+Interface List
+ 12...0a 00 27 00 00 00 ......VirtualBox Host-Only Ethernet Adapter
+  1...........................Software Loopback Interface 1
+===========================================================================
+
+IPv4 Route Table
+===========================================================================
+Active Routes:
+Network Destination        Netmask          Gateway       Interface  Metric
+          0.0.0.0          0.0.0.0      10.20.0.254      10.20.0.17     25
+        10.20.0.0    255.255.255.0         On-link        10.20.0.17    281
+       172.16.0.0      255.240.0.0      10.20.0.254      10.20.0.17     26
+        127.0.0.0        255.0.0.0         On-link         127.0.0.1    331
+     192.168.99.0    255.255.255.0         On-link      192.168.99.1    281
+        224.0.0.0        240.0.0.0         On-link         127.0.0.1    331
+  255.255.255.255  255.255.255.255         On-link      192.168.99.1    281
+===========================================================================
+Persistent Routes:
+  None
+`
+
+// record replays the given VBoxManage and host command responses, VBoxManage being given by its base name
+func record(t *testing.T, responses map[string]*runner.Response) *runner.Recorder {
+	t.Helper()
+	recorder := &runner.Recorder{Responses: responses}
+	previous := runner.Default()
+	runner.SetDefault(recorder)
+	t.Cleanup(func() { runner.SetDefault(previous) })
+	return recorder
+}
+
+// commands returns the command lines recorded, executables being reduced to their base name without extension
+func commands(recorder *runner.Recorder) []string {
+	var lines []string
+	for _, record := range recorder.Records {
+		name := filepath.Base(strings.ReplaceAll(record.Command.Name, `\`, "/"))
+		c := &runner.Command{Name: strings.TrimSuffix(name, filepath.Ext(name)), Args: record.Command.Args}
+		lines = append(lines, c.String())
+	}
+	return lines
+}
+
+func stdout(out string) *runner.Response {
+	return &runner.Response{Result: &runner.Result{Stdout: out}}
+}
+
+func TestExpectedVMIP(t *testing.T) {
+	tests := []struct {
+		cidr    string
+		want    string
+		wantErr error
+	}{
+		{"192.168.99.1/24", "192.168.99.100", nil},
+		{"10.0.0.1/16", "10.0.0.100", nil},
+		{"192.168.99.1/25", "", ErrCidrTooSmall},
+		{"192.168.99.0/24", "", ErrNetworkAddrCidr},
+	}
+	for _, tt := range tests {
+		got, err := ExpectedVMIP(tt.cidr)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ExpectedVMIP(%s) error = %v, want %v", tt.cidr, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ExpectedVMIP(%s) = %s, want %s", tt.cidr, got, tt.want)
+		}
+	}
+	if _, err := ExpectedVMIP("192.168.99.1"); err == nil {
+		t.Errorf("ExpectedVMIP(192.168.99.1) error = nil, want invalid CIDR")
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		n1   string
+		n2   string
+		want bool
+	}{
+		{"192.168.99.0/24", "192.168.99.0/24", true},
+		{"192.168.99.0/24", "192.168.0.0/16", true},
+		{"192.168.0.0/16", "192.168.99.128/25", true},
+		{"192.168.99.0/24", "192.168.98.0/24", false},
+		{"10.0.0.0/8", "172.16.0.0/12", false},
+	}
+	for _, tt := range tests {
+		_, n1, _ := net.ParseCIDR(tt.n1)
+		_, n2, _ := net.ParseCIDR(tt.n2)
+		if got := overlaps(n1, n2); got != tt.want {
+			t.Errorf("overlaps(%s, %s) = %v, want %v", tt.n1, tt.n2, got, tt.want)
+		}
+	}
+}
+
+func TestListRoutes(t *testing.T) {
+	record(t, map[string]*runner.Response{"route print -4": stdout(routePrint)})
+	routes, err := listRoutes()
+	if err != nil {
+		t.Fatalf("listRoutes() error = %v", err)
+	}
+	var got []string
+	for _, r := range routes {
+		got = append(got, r.destination.String()+" "+r.iface)
+	}
+	// Default, loopback, multicast and broadcast routes are skipped
+	want := []string{"10.20.0.0/24 10.20.0.17", "172.16.0.0/12 10.20.0.17", "192.168.99.0/24 192.168.99.1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listRoutes() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateHostOnlyCIDR(t *testing.T) {
+	tests := []struct {
+		cidr    string
+		wantErr string
+	}{
+		// Routes through host-only interfaces are ignored
+		{"192.168.99.1/24", ""},
+		{"192.168.57.1/24", ""},
+		{"172.17.0.1/24", "overlaps with host route to 172.16.0.0/12"},
+		{"10.20.0.1/16", "overlaps with host route to 10.20.0.0/24"},
+		{"192.168.57.1/25", ErrCidrTooSmall.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			record(t, map[string]*runner.Response{
+				"VBoxManage list hostonlyifs": stdout(hostOnlyIfs),
+				"route print -4":              stdout(routePrint),
+			})
+			err := ValidateHostOnlyCIDR(tt.cidr)
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Errorf("ValidateHostOnlyCIDR() error = %v", err)
+			} else if len(tt.wantErr) > 0 && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ValidateHostOnlyCIDR() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// minikubeInfo is the output of VBoxManage showvminfo minikube --machinereadable, with a NAT NIC and a host-only one
+const minikubeInfo = `name="minikube"
+VMState="poweroff"
+nic1="nat"
+macaddress1="080027E3A1B2"
+nic2="hostonly"
+hostonlyadapter2="vboxnet0"
+macaddress2="0800274E5F60"
+nic3="none"
+`
+
+func TestFormatMAC(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"0800274E5F60", "08:00:27:4E:5F:60"},
+		{"08:00:27:4e:5f:60", "08:00:27:4e:5f:60"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := formatMAC(tt.mac); got != tt.want {
+			t.Errorf("formatMAC(%s) = %s, want %s", tt.mac, got, tt.want)
+		}
+	}
+}
+
+func TestReserveVMIP(t *testing.T) {
+	recorder := record(t, map[string]*runner.Response{
+		"VBoxManage list hostonlyifs":                      stdout(hostOnlyIfs),
+		"VBoxManage showvminfo minikube --machinereadable": stdout(minikubeInfo),
+		"VBoxManage dhcpserver modify --network=HostInterfaceNetworking-vboxnet0 --mac-address=08:00:27:4e:5f:60 --fixed-address=192.168.99.100": stdout(""),
+		"VBoxManage dhcpserver restart --network=HostInterfaceNetworking-vboxnet0":                                                               stdout(""),
+	})
+	mac, err := ReserveVMIP("192.168.99.1/24", "192.168.99.100")
+	if err != nil {
+		t.Fatalf("ReserveVMIP() error = %v", err)
+	}
+	if mac != "08:00:27:4e:5f:60" {
+		t.Errorf("ReserveVMIP() = %s, want 08:00:27:4e:5f:60", mac)
+	}
+	want := []string{
+		"VBoxManage list hostonlyifs",
+		"VBoxManage showvminfo minikube --machinereadable",
+		"VBoxManage dhcpserver modify --network=HostInterfaceNetworking-vboxnet0 --mac-address=08:00:27:4e:5f:60 --fixed-address=192.168.99.100",
+		"VBoxManage dhcpserver restart --network=HostInterfaceNetworking-vboxnet0",
+	}
+	if got := commands(recorder); !reflect.DeepEqual(got, want) {
+		t.Errorf("ReserveVMIP() commands = %v, want %v", got, want)
+	}
+}
+
+func TestReserveVMIPErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidr    string
+		info    string
+		wantErr string
+	}{
+		{"no host-only network", "192.168.57.1/24", minikubeInfo, "no host-only network interface matching 192.168.57.1/24"},
+		{"no NIC attached", "192.168.56.1/24", minikubeInfo, "no VM network interface attached to host-only network vboxnet1"},
+		{"invalid MAC", "192.168.99.1/24", strings.Replace(minikubeInfo, "0800274E5F60", "08002", 1), "not able to parse VM MAC address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record(t, map[string]*runner.Response{
+				"VBoxManage list hostonlyifs":                      stdout(hostOnlyIfs),
+				"VBoxManage showvminfo minikube --machinereadable": stdout(tt.info),
+			})
+			_, err := ReserveVMIP(tt.cidr, "192.168.99.100")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReserveVMIP() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReleaseVMIP(t *testing.T) {
+	release := "VBoxManage dhcpserver modify --network=HostInterfaceNetworking-vboxnet0 --mac-address=08:00:27:4e:5f:60 --remove-config"
+	recorder := record(t, map[string]*runner.Response{
+		"VBoxManage list hostonlyifs": stdout(hostOnlyIfs),
+		release:                       stdout(""),
+	})
+	err := ReleaseVMIP("192.168.99.1/24", "08:00:27:4e:5f:60")
+	if err != nil {
+		t.Fatalf("ReleaseVMIP() error = %v", err)
+	}
+	if got := commands(recorder); len(got) != 2 || got[1] != release {
+		t.Errorf("ReleaseVMIP() commands = %v, want %s", got, release)
+	}
+}
+
+// dhcpServers is the output of VBoxManage list dhcpservers, with an orphan host-only DHCP server and a NAT network one
+const dhcpServers = `NetworkName:    HostInterfaceNetworking-vboxnet0
+Dhcpd IP:       192.168.99.2
+LowerIPAddress: 192.168.99.100
+UpperIPAddress: 192.168.99.254
+NetworkMask:    255.255.255.0
+Enabled:        Yes
+Global Configuration:
+    minLeaseTime:     default
+    maxLeaseTime:     default
+    Forced options:   None
+        1/legacy: 255.255.255.0
+Groups:               None
+Individual Configs:   None
+
+NetworkName:    HostInterfaceNetworking-vboxnet5
+Dhcpd IP:       192.168.60.2
+LowerIPAddress: 192.168.60.100
+UpperIPAddress: 192.168.60.254
+NetworkMask:    255.255.255.0
+Enabled:        No
+
+NetworkName:    NatNetwork
+Dhcpd IP:       10.0.2.3
+LowerIPAddress: 10.0.2.4
+UpperIPAddress: 10.0.2.254
+NetworkMask:    255.255.255.0
+Enabled:        Yes
+`
+
+// networkResponses returns the responses of the commands listing host-only networks, DHCP servers and VMs,
+// minikube using vboxnet0 and VM other using vboxnet1
+func networkResponses() map[string]*runner.Response {
+	return map[string]*runner.Response{
+		"VBoxManage list hostonlyifs": stdout(hostOnlyIfs),
+		"VBoxManage list dhcpservers": stdout(dhcpServers),
+		"VBoxManage list vms": stdout(`"minikube" {6d3c4b2a-0000-0000-0000-000000000001}
+"other" {6d3c4b2a-0000-0000-0000-000000000002}
+"idle" {6d3c4b2a-0000-0000-0000-000000000003}
+`),
+		"VBoxManage showvminfo 6d3c4b2a-0000-0000-0000-000000000001 --machinereadable": stdout(minikubeInfo),
+		"VBoxManage showvminfo 6d3c4b2a-0000-0000-0000-000000000002 --machinereadable": stdout("nic1=\"hostonly\"\nhostonlyadapter1=\"vboxnet1\"\n"),
+		"VBoxManage showvminfo 6d3c4b2a-0000-0000-0000-000000000003 --machinereadable": stdout("nic1=\"nat\"\n"),
+		"VBoxManage showvminfo minikube --machinereadable":                             stdout(minikubeInfo),
+	}
+}
+
+func TestListDHCPServers(t *testing.T) {
+	record(t, networkResponses())
+	servers, err := listDHCPServers()
+	if err != nil {
+		t.Fatalf("listDHCPServers() error = %v", err)
+	}
+	if len(servers) != 3 {
+		t.Fatalf("listDHCPServers() = %d servers, want 3", len(servers))
+	}
+	dhcp := servers["HostInterfaceNetworking-vboxnet0"]
+	if dhcp == nil || dhcp.IP.String() != "192.168.99.2" || dhcp.LowerIP.String() != "192.168.99.100" ||
+		dhcp.UpperIP.String() != "192.168.99.254" || net.IP(dhcp.Mask).String() != "255.255.255.0" || !dhcp.Enabled {
+		t.Errorf("listDHCPServers() vboxnet0 server = %+v", dhcp)
+	}
+	if servers["HostInterfaceNetworking-vboxnet5"].Enabled {
+		t.Errorf("listDHCPServers() vboxnet5 server enabled, want disabled")
+	}
+}
+
+func TestListHostOnlyAdaptersUsage(t *testing.T) {
+	record(t, networkResponses())
+	usage, err := listHostOnlyAdaptersUsage()
+	if err != nil {
+		t.Fatalf("listHostOnlyAdaptersUsage() error = %v", err)
+	}
+	want := map[string][]string{"vboxnet0": {"minikube"}, "vboxnet1": {"other"}}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("listHostOnlyAdaptersUsage() = %v, want %v", usage, want)
+	}
+}
+
+func TestListHostOnlyNetworks(t *testing.T) {
+	record(t, networkResponses())
+	networks, orphans, err := ListHostOnlyNetworks()
+	if err != nil {
+		t.Fatalf("ListHostOnlyNetworks() error = %v", err)
+	}
+	if len(networks) != 2 || networks[0].Name != "vboxnet0" || networks[1].Name != "vboxnet1" {
+		t.Fatalf("ListHostOnlyNetworks() networks = %v, want vboxnet0 and vboxnet1", networks)
+	}
+	if networks[0].DHCPServer == nil || networks[1].DHCPServer != nil {
+		t.Errorf("ListHostOnlyNetworks() DHCP servers = %v, %v, want only vboxnet0 one", networks[0].DHCPServer, networks[1].DHCPServer)
+	}
+	// DHCP servers of NAT networks are not orphans
+	if len(orphans) != 1 || orphans[0].NetworkName != "HostInterfaceNetworking-vboxnet5" {
+		t.Errorf("ListHostOnlyNetworks() orphans = %v, want vboxnet5 DHCP server", orphans)
+	}
+	tests := []struct {
+		network int
+		cidr    string
+		want    bool
+	}{
+		{0, "192.168.99.1/24", true},
+		// Used by minikube VM whatever the CIDR
+		{0, "192.168.56.1/24", true},
+		{1, "192.168.56.1/24", true},
+		{1, "192.168.99.1/24", false},
+		{1, "192.168.56.1/16", false},
+	}
+	for _, tt := range tests {
+		if got := networks[tt.network].IsOwned(tt.cidr); got != tt.want {
+			t.Errorf("%s IsOwned(%s) = %v, want %v", networks[tt.network].Name, tt.cidr, got, tt.want)
+		}
+	}
+}
+
+func TestRecreateHostOnlyNetwork(t *testing.T) {
+	responses := networkResponses()
+	created := []string{
+		"VBoxManage dhcpserver remove --network=HostInterfaceNetworking-vboxnet0",
+		"VBoxManage hostonlyif remove vboxnet0",
+		"VBoxManage hostonlyif create",
+		"VBoxManage hostonlyif ipconfig vboxnet2 --ip 192.168.99.1 --netmask 255.255.255.0",
+		"VBoxManage dhcpserver add --network=HostInterfaceNetworking-vboxnet2 --server-ip=192.168.99.2 --netmask=255.255.255.0 " +
+			"--lower-ip=192.168.99.100 --upper-ip=192.168.99.254 --enable",
+		"VBoxManage modifyvm minikube --hostonlyadapter2 vboxnet2",
+	}
+	for _, c := range created {
+		responses[c] = stdout("")
+	}
+	responses["VBoxManage hostonlyif create"] = stdout("0%...10%...100%\nInterface 'vboxnet2' was successfully created\n")
+	recorder := record(t, responses)
+	name, err := RecreateHostOnlyNetwork("192.168.99.1/24")
+	if err != nil {
+		t.Fatalf("RecreateHostOnlyNetwork() error = %v", err)
+	}
+	if name != "vboxnet2" {
+		t.Errorf("RecreateHostOnlyNetwork() = %s, want vboxnet2", name)
+	}
+	var got []string
+	for _, c := range commands(recorder) {
+		if !strings.Contains(c, " list ") && !strings.Contains(c, " showvminfo ") {
+			got = append(got, c)
+		}
+	}
+	if !reflect.DeepEqual(got, created) {
+		t.Errorf("RecreateHostOnlyNetwork() commands = %v, want %v", got, created)
+	}
+}
+
+func TestRecreateHostOnlyNetworkSharedWithOtherVM(t *testing.T) {
+	recorder := record(t, networkResponses())
+	// vboxnet0, used by minikube, must not be removed as vboxnet1 cannot be
+	_, err := RecreateHostOnlyNetwork("192.168.56.1/24")
+	if err == nil || !strings.Contains(err.Error(), "vboxnet1 is also used by VM other") {
+		t.Fatalf("RecreateHostOnlyNetwork() error = %v, want vboxnet1 used by other", err)
+	}
+	for _, c := range commands(recorder) {
+		if strings.Contains(c, " remove ") {
+			t.Errorf("RecreateHostOnlyNetwork() ran %s, want nothing removed", c)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualbox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ListSharedFolders returns the host path of minikube VM permanent shared folders by name
+func ListSharedFolders() (map[string]string, error) {
+	return listSharedFolders("Machine")
+}
+
+// ListTransientSharedFolders returns the host path of minikube VM transient shared folders by name, which only exist while it is running
+func ListTransientSharedFolders() (map[string]string, error) {
+	return listSharedFolders("Transient")
+}
+
+func listSharedFolders(mapping string) (map[string]string, error) {
+	info, err := vboxManager.vbmQuery("showvminfo", "minikube", "--machinereadable")
+	if err != nil {
+		return nil, fmt.Errorf("not able to get VM info: %w", err)
+	}
+	names := map[string]string{}
+	paths := map[string]string{}
+	err = parseKeyValues(info, reMachineReadableLine, func(key, val string) error {
+		if index, ok := strings.CutPrefix(key, "SharedFolderName"+mapping+"Mapping"); ok {
+			names[index] = val
+		} else if index, ok := strings.CutPrefix(key, "SharedFolderPath"+mapping+"Mapping"); ok {
+			paths[index] = val
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	folders := map[string]string{}
+	for index, name := range names {
+		folders[name] = paths[index]
+	}
+	return folders, nil
+}
+
+// AddSharedFolder shares the given host directory with minikube VM, either permanently (VM must be stopped)
+// or transiently (VM must be running)
+func AddSharedFolder(name string, hostPath string, transient bool) error {
+	args := []string{"sharedfolder", "add", "minikube", "--name", name, "--hostpath", hostPath}
+	if transient {
+		args = append(args, "--transient")
+	}
+	err := vboxManager.vbm(args...)
+	if err != nil {
+		return fmt.Errorf("not able to add shared folder %s: %w", name, err)
+	}
+	return nil
+}
+
+// RemoveSharedFolder stops sharing the given permanent or transient folder with minikube VM
+func RemoveSharedFolder(name string, transient bool) error {
+	args := []string{"sharedfolder", "remove", "minikube", "--name", name}
+	if transient {
+		args = append(args, "--transient")
+	}
+	err := vboxManager.vbm(args...)
+	if err != nil {
+		return fmt.Errorf("not able to remove shared folder %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+(c) Copyright 2018, Gemalto. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualbox
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gemalto/gokube/pkg/runner"
+)
+
+// sharedFoldersInfo is an excerpt of VBoxManage showvminfo minikube --machinereadable with permanent and transient shared folders
+const sharedFoldersInfo = `name="minikube"
+VMState="running"
+SharedFolderNameMachineMapping1="hosthome"
+SharedFolderPathMachineMapping1="C:\Users\Jean O'Brien"
+SharedFolderNameMachineMapping2="projects"
+SharedFolderPathMachineMapping2="D:\My Projects\gokube"
+SharedFolderNameTransientMapping1="data"
+SharedFolderPathTransientMapping1="E:\data sets"
+`
+
+func TestListSharedFolders(t *testing.T) {
+	record(t, map[string]*runner.Response{
+		"VBoxManage showvminfo minikube --machinereadable": stdout(sharedFoldersInfo),
+	})
+	folders, err := ListSharedFolders()
+	if err != nil {
+		t.Fatalf("ListSharedFolders() error = %v", err)
+	}
+	want := map[string]string{
+		"hosthome": `C:\Users\Jean O'Brien`,
+		"projects": `D:\My Projects\gokube`,
+	}
+	if !reflect.DeepEqual(folders, want) {
+		t.Errorf("ListSharedFolders() = %v, want %v", folders, want)
+	}
+	folders, err = ListTransientSharedFolders()
+	if err != nil {
+		t.Fatalf("ListTransientSharedFolders() error = %v", err)
+	}
+	want = map[string]string{"data": `E:\data sets`}
+	if !reflect.DeepEqual(folders, want) {
+		t.Errorf("ListTransientSharedFolders() = %v, want %v", folders, want)
+	}
+}
+
+func TestListSharedFoldersNone(t *testing.T) {
+	record(t, map[string]*runner.Response{
+		"VBoxManage showvminfo minikube --machinereadable": stdout("name=\"minikube\"\nVMState=\"poweroff\"\n"),
+	})
+	folders, err := ListSharedFolders()
+	if err != nil {
+		t.Fatalf("ListSharedFolders() error = %v", err)
+	}
+	if len(folders) != 0 {
+		t.Errorf("ListSharedFolders() = %v, want no folder", folders)
+	}
+}
+
+func TestListSharedFoldersError(t *testing.T) {
+	errVM := errors.New("Could not find a registered machine named 'minikube'")
+	record(t, map[string]*runner.Response{
+		"VBoxManage showvminfo minikube --machinereadable": {Err: errVM},
+	})
+	_, err := ListSharedFolders()
+	if !errors.Is(err, errVM) {
+		t.Errorf("ListSharedFolders() error = %v, want %v", err, errVM)
+	}
+}
+
+func TestAddSharedFolder(t *testing.T) {
+	recorder := record(t, map[string]*runner.Response{
+		`VBoxManage sharedfolder add minikube --name projects --hostpath "D:\\My Projects\\gokube"`: stdout(""),
+		`VBoxManage sharedfolder add minikube --name data --hostpath "E:\\data sets" --transient`:   stdout(""),
+	})
+	if err := AddSharedFolder("projects", `D:\My Projects\gokube`, false); err != nil {
+		t.Fatalf("AddSharedFolder() error = %v", err)
+	}
+	if err := AddSharedFolder("data", `E:\data sets`, true); err != nil {
+		t.Fatalf("AddSharedFolder() error = %v", err)
+	}
+	if len(commands(recorder)) != 2 {
+		t.Errorf("AddSharedFolder() ran %v, want 2 commands", commands(recorder))
+	}
+}
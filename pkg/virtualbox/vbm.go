@@ -2,7 +2,7 @@ package virtualbox
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
@@ -11,24 +11,29 @@ import (
 
 	"strconv"
 
-	"time"
-	"os"
+	"github.com/gemalto/gokube/pkg/runner"
 	"github.com/gemalto/gokube/pkg/utils"
+	"os"
+	"path/filepath"
+	"time"
 )
 
 const (
 	retryCountOnObjectNotReadyError = 5
 	objectNotReady                  = "error: The object is not ready"
 	retryDelay                      = 100 * time.Millisecond
+	systemDiskPort                  = 1
+	swapDiskPort                    = 2
 )
 
 var (
-	reColonLine        = regexp.MustCompile(`(.+):\s+(.*)`)
-	reEqualLine        = regexp.MustCompile(`(.+)=(.*)`)
-	reEqualQuoteLine   = regexp.MustCompile(`"(.+)"="(.*)"`)
-	reMachineNotFound  = regexp.MustCompile(`Could not find a registered machine named '(.+)'`)
-	reSnapshotNotFound = regexp.MustCompile(`Could not find a snapshot named '(.+)'`)
-	reNoSnapshotFound  = regexp.MustCompile("This machine does not have any snapshots")
+	reColonLine           = regexp.MustCompile(`(.+):\s+(.*)`)
+	reEqualLine           = regexp.MustCompile(`(.+)=(.*)`)
+	reEqualQuoteLine      = regexp.MustCompile(`"(.+)"="(.*)"`)
+	reMachineReadableLine = regexp.MustCompile(`^"?([^"=]+)"?="?([^"]*)"?$`)
+	reMachineNotFound     = regexp.MustCompile(`Could not find a registered machine named '(.+)'`)
+	reSnapshotNotFound    = regexp.MustCompile(`Could not find a snapshot named '(.+)'`)
+	reNoSnapshotFound     = regexp.MustCompile("This machine does not have any snapshots")
 
 	ErrMachineNotExist  = errors.New("machine does not exist")
 	ErrSnapshotNotExist = errors.New("snapshot does not exist")
@@ -43,18 +48,25 @@ type VBoxManager interface {
 
 	vbmOut(args ...string) (string, error)
 
+	vbmQuery(args ...string) (string, error)
+
 	vbmOutErr(args ...string) (string, string, error)
 }
 
 // VBoxCmdManager communicates with VirtualBox through the commandline using `VBoxManage`.
 type VBoxCmdManager struct {
-	runCmd func(cmd *exec.Cmd) error
+	runner runner.Runner
 }
 
 // NewVBoxManager creates a VBoxManager instance.
 func NewVBoxManager() *VBoxCmdManager {
+	return NewVBoxManagerWithRunner(nil)
+}
+
+// NewVBoxManagerWithRunner creates a VBoxManager instance using the given runner (nil means the default one).
+func NewVBoxManagerWithRunner(r runner.Runner) *VBoxCmdManager {
 	return &VBoxCmdManager{
-		runCmd: func(cmd *exec.Cmd) error { return cmd.Run() },
+		runner: r,
 	}
 }
 
@@ -98,33 +110,44 @@ func (v *VBoxCmdManager) vbmOut(args ...string) (string, error) {
 }
 
 func (v *VBoxCmdManager) vbmOutErr(args ...string) (string, string, error) {
-	return v.vbmOutErrRetry(retryCountOnObjectNotReadyError, args...)
+	r := v.runner
+	if r == nil {
+		r = runner.Default()
+	}
+	return v.vbmOutErrRetry(r, retryCountOnObjectNotReadyError, args...)
+}
+
+// vbmQuery runs a read-only VBoxManage command, which is run even in dry-run mode
+func (v *VBoxCmdManager) vbmQuery(args ...string) (string, error) {
+	r := v.runner
+	if r == nil {
+		r = runner.Inspector()
+	}
+	stdout, _, err := v.vbmOutErrRetry(r, retryCountOnObjectNotReadyError, args...)
+	return stdout, err
 }
 
-func (v *VBoxCmdManager) vbmOutErrRetry(retry int, args ...string) (string, string, error) {
-	cmd := exec.Command(vboxManageCmd, args...)
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := v.runCmd(cmd)
-	stderrStr := stderr.String()
+func (v *VBoxCmdManager) vbmOutErrRetry(r runner.Runner, retry int, args ...string) (string, string, error) {
+	result, err := r.Run(context.Background(), &runner.Command{Name: vboxManageCmd, Args: args})
+	var stdoutStr, stderrStr string
+	if result != nil {
+		stdoutStr, stderrStr = result.Stdout, result.Stderr
+	}
 
-	if err != nil {
-		if ee, ok := err.(*exec.Error); ok && ee.Err == exec.ErrNotFound {
-			err = ErrVBMNotFound
-		}
+	if err != nil && runner.IsNotFound(err) {
+		err = ErrVBMNotFound
 	}
 
 	// Sometimes, we just need to retry...
 	if retry > 1 {
 		if strings.Contains(stderrStr, objectNotReady) {
 			time.Sleep(retryDelay)
-			return v.vbmOutErrRetry(retry-1, args...)
+			return v.vbmOutErrRetry(r, retry-1, args...)
 		}
 	}
 
-	if err == nil || strings.HasPrefix(err.Error(), "exit status ") {
+	var exitErr *exec.ExitError
+	if err == nil || errors.As(err, &exitErr) {
 		// VBoxManage will sometimes not set the return code, but has a fatal error
 		// such as VBoxManage.exe: error: VT-x is not available. (VERR_VMX_NO_VMX)
 		if strings.Contains(stderrStr, "error:") {
@@ -132,7 +155,7 @@ func (v *VBoxCmdManager) vbmOutErrRetry(retry int, args ...string) (string, stri
 		}
 	}
 
-	return stdout.String(), stderrStr, err
+	return stdoutStr, stderrStr, err
 }
 
 func checkVBoxManageVersion(version string) error {
@@ -187,21 +210,162 @@ func parseKeyValues(stdOut string, regexp *regexp.Regexp, callback func(key, val
 	return s.Err()
 }
 
+// DetachDisk detach the disk attached to the given port of the minikube VM
+func (v *VBoxCmdManager) DetachDisk(vmName string, port int, device int) error {
+	command := []string{
+		"storageattach", vmName,
+		"--storagectl", "SATA",
+		"--port", fmt.Sprintf("%d", port),
+		"--device", fmt.Sprintf("%d", device),
+		"--medium", "none",
+	}
+	return v.vbm(command...)
+}
+
+// DeleteDisk unregister the disk from VBox and delete its file
+func (v *VBoxCmdManager) DeleteDisk(filePath string) error {
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil
+	}
+	return v.vbm("closemedium", "disk", filePath, "--delete")
+}
+
+// GetAttachedDisk returns the path of the disk attached to the given port of the VM (empty if none)
+func (v *VBoxCmdManager) GetAttachedDisk(vmName string, port int, device int) (string, error) {
+	out, err := v.vbmQuery("showvminfo", vmName, "--machinereadable")
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("SATA-%d-%d", port, device)
+	disk := ""
+	err = parseKeyValues(out, reEqualQuoteLine, func(k, val string) error {
+		if k == key && val != "none" {
+			disk = val
+		}
+		return nil
+	})
+	return disk, err
+}
+
+// GetDiskCapacity returns the capacity in MB of the given disk
+func (v *VBoxCmdManager) GetDiskCapacity(filePath string) (int, error) {
+	out, err := v.vbmQuery("showmediuminfo", "disk", filePath)
+	if err != nil {
+		return 0, err
+	}
+	capacity := -1
+	err = parseKeyValues(out, reColonLine, func(key, val string) error {
+		if key == "Capacity" {
+			fields := strings.Fields(val)
+			if len(fields) != 2 || fields[1] != "MBytes" {
+				return fmt.Errorf("unexpected disk capacity format: %q", val)
+			}
+			capacity, err = strconv.Atoi(fields[0])
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if capacity < 0 {
+		return 0, fmt.Errorf("cannot find capacity of disk %s", filePath)
+	}
+	return capacity, nil
+}
+
+// GetSystemDisk returns the path of minikube VM system disk
+func (v *VBoxCmdManager) GetSystemDisk() (string, error) {
+	disk, err := v.GetAttachedDisk("minikube", systemDiskPort, 0)
+	if err != nil {
+		return "", err
+	}
+	if len(disk) == 0 {
+		return "", fmt.Errorf("cannot find minikube VM system disk")
+	}
+	return disk, nil
+}
+
+// ResizeSystemDisk grows minikube VM system disk, converting it to VDI first as VBox cannot resize VMDK disks
+func (v *VBoxCmdManager) ResizeSystemDisk(sizeInMB int) error {
+	disk, err := v.GetSystemDisk()
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(disk), ".vmdk") {
+		vdiDisk := strings.TrimSuffix(disk, filepath.Ext(disk)) + ".vdi"
+		err = v.vbm("clonemedium", "disk", disk, vdiDisk, "--format", "VDI")
+		if err != nil {
+			return fmt.Errorf("cannot convert system disk to VDI: %w", err)
+		}
+		err = v.AttachDisk("minikube", systemDiskPort, 0, vdiDisk)
+		if err != nil {
+			return fmt.Errorf("cannot attach converted system disk to VM: %w", err)
+		}
+		err = v.DeleteDisk(disk)
+		if err != nil {
+			fmt.Printf("Warning: cannot delete previous system disk %s: %s\n", disk, err)
+		}
+		disk = vdiDisk
+	}
+	err = v.vbm("modifymedium", "disk", disk, "--resize", strconv.Itoa(sizeInMB))
+	if err != nil {
+		return fmt.Errorf("cannot resize system disk: %w", err)
+	}
+	return nil
+}
+
+// SwapDiskPath returns the path of the swap disk of minikube VM
+func SwapDiskPath() string {
+	return utils.GetUserHome() + string(os.PathSeparator) + ".minikube/machines/minikube/swapdisk.vdi"
+}
+
+// GetSwapDisk returns the path of the swap disk attached to minikube VM (empty if none)
+func (v *VBoxCmdManager) GetSwapDisk() (string, error) {
+	return v.GetAttachedDisk("minikube", swapDiskPort, 0)
+}
+
 // AddSwapDisk prepare, create, and attach swap disk to minikube VM
 func (v *VBoxCmdManager) AddSwapDisk(swapsize int16) error {
 
 	// Create the disk
-	swapDiskPath := utils.GetUserHome() + string(os.PathSeparator) + ".minikube/machines/minikube/swapdisk.vdi"
+	swapDiskPath := SwapDiskPath()
 	err := v.CreateDisk(swapsize, swapDiskPath)
 	if err != nil {
 		return fmt.Errorf("cannot create swap disk: %w", err)
 	}
 
-	// Attach the disk to the VM
-	err = v.AttachDisk("minikube", 2, 0, swapDiskPath)
+	// Attach the disk to the VM, unless it is already there
+	attached, err := v.GetSwapDisk()
+	if err != nil {
+		return fmt.Errorf("cannot get VM disks: %w", err)
+	}
+	if len(attached) > 0 {
+		return nil
+	}
+	err = v.AttachDisk("minikube", swapDiskPort, 0, swapDiskPath)
 	if err != nil {
 		return fmt.Errorf("cannot attach swap disk to VM: %w", err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// RemoveSwapDisk detach and delete swap disk from minikube VM
+func (v *VBoxCmdManager) RemoveSwapDisk() error {
+	attached, err := v.GetSwapDisk()
+	if err != nil {
+		return fmt.Errorf("cannot get VM disks: %w", err)
+	}
+	if len(attached) > 0 {
+		err = v.DetachDisk("minikube", swapDiskPort, 0)
+		if err != nil {
+			return fmt.Errorf("cannot detach swap disk from VM: %w", err)
+		}
+	}
+	err = v.DeleteDisk(SwapDiskPath())
+	if err != nil {
+		return fmt.Errorf("cannot delete swap disk: %w", err)
+	}
+	return nil
+}
@@ -17,8 +17,6 @@ package virtualbox
 import (
 	"errors"
 	"fmt"
-	"github.com/gemalto/gokube/pkg/utils"
-	"golang.org/x/sys/windows/registry"
 	"net"
 	"os"
 	"os/exec"
@@ -50,7 +48,7 @@ var (
 )
 
 func IsRunning() (bool, error) {
-	info, err := vboxManager.vbmOut("showvminfo", "minikube")
+	info, err := vboxManager.vbmQuery("showvminfo", "minikube")
 	if err != nil {
 		return false, fmt.Errorf("not able to get VM info: %w", err)
 	}
@@ -116,54 +114,8 @@ func RestoreSnapshot(name string) error {
 	return nil
 }
 
-func ResetHostOnlyNetworkLeases(hostOnlyCIDR string, verbose bool) error {
-	nets, err := listHostOnlyAdapters(vboxManager)
-	if err != nil {
-		return fmt.Errorf("not able to list host-only network interfaces: %w", err)
-	}
-	if verbose {
-		for _, v := range nets {
-			fmt.Printf("\nResetHostOnlyNetworkLeases: listHostOnlyAdapters: %+v", v)
-		}
-	}
-	ip, network, err := parseAndValidateCIDR(hostOnlyCIDR)
-	if err != nil {
-		return fmt.Errorf("not able to parse CIDR to find host-only network interface: %w", err)
-	}
-	if verbose {
-		fmt.Printf("\nResetHostOnlyNetworkLeases: parseAndValidateCIDR: %s,%s", ip.String(), network.String())
-	}
-	hostOnlyNet := getHostOnlyAdapter(nets, ip, network.Mask)
-	if hostOnlyNet == nil {
-		if verbose {
-			fmt.Printf("\nResetHostOnlyNetworkLeases: getHostOnlyAdapter: no host-only network interface matching minikube CDR")
-		}
-		return nil
-	}
-	if verbose {
-		fmt.Printf("\nResetHostOnlyNetworkLeases: getHostOnlyAdapter: %s", hostOnlyNet.NetworkName)
-	}
-	filesPattern := utils.GetUserHome() + "/.VirtualBox/" + hostOnlyNet.NetworkName + "*"
-	files, err := filepath.Glob(filesPattern)
-	if err != nil {
-		return fmt.Errorf("not able to get host-only network interface DHCP leases files: %w", err)
-	}
-	for _, f := range files {
-		if verbose {
-			fmt.Printf("\nResetHostOnlyNetworkLeases: deleting lease file %s...", f)
-		}
-		if err := os.Remove(f); err != nil {
-			return fmt.Errorf("not able to delete lease file %s: %w", f, err)
-		}
-		if verbose {
-			fmt.Printf("\nResetHostOnlyNetworkLeases: deleted lease file %s", f)
-		}
-	}
-	return nil
-}
-
 func listHostOnlyAdapters(vbox VBoxManager) (map[string]*hostOnlyNetwork, error) {
-	out, err := vbox.vbmOut("list", "hostonlyifs")
+	out, err := vbox.vbmQuery("list", "hostonlyifs")
 	if err != nil {
 		return nil, err
 	}
@@ -286,24 +238,6 @@ func detectVBoxManageCmdInPath() string {
 	return cmd
 }
 
-func findVBoxInstallDirInRegistry() (string, error) {
-	registryKey, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Oracle\VirtualBox`, registry.QUERY_VALUE)
-	if err != nil {
-		errorMessage := fmt.Sprintf("Can't find VirtualBox registry entries, is VirtualBox really installed properly? %s", err)
-		return "", fmt.Errorf(errorMessage)
-	}
-
-	defer registryKey.Close()
-
-	installDir, _, err := registryKey.GetStringValue("InstallDir")
-	if err != nil {
-		errorMessage := fmt.Sprintf("Can't find InstallDir registry key within VirtualBox registries entries, is VirtualBox really installed properly? %s", err)
-		return "", fmt.Errorf(errorMessage)
-	}
-
-	return installDir, nil
-}
-
 func parseIPv4Mask(s string) net.IPMask {
 	mask := net.ParseIP(s)
 	if mask == nil {